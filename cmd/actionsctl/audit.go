@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	auditLogPath = flag.String("audit_log", "", "Append a JSON line to this file for every run that cancel or rerun acts on.")
+	auditIssue   = flag.String("audit_issue", "", "Also comment the runs that cancel or rerun acted on on this issue, as owner/repo#number.")
+)
+
+// auditEntry records an operation on a run.
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	Actor      string    `json:"actor"` // The login of GITHUB_TOKEN or the GitHub App.
+	Command    string    `json:"command"`
+	Repository string    `json:"repo"`
+	RunID      int64     `json:"run_id"`
+	Workflow   string    `json:"workflow"`
+	URL        string    `json:"url"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// auditLog appends entries to -audit_log as they happen, and comments them
+// on -audit_issue once done. A nil auditLog records nothing.
+type auditLog struct {
+	client  *github.Client
+	command string
+	actor   string
+	entries []auditEntry
+}
+
+// newAuditLog returns nil if neither -audit_log nor -audit_issue is set, or
+// on a dry run.
+func newAuditLog(ctx context.Context, client *github.Client, command string) (*auditLog, error) {
+	if (*auditLogPath == "" && *auditIssue == "") || *dryRun {
+		return nil, nil
+	}
+
+	if *auditIssue != "" {
+		if _, _, err := parseIssueRef(*auditIssue); err != nil {
+			return nil, err
+		}
+	}
+
+	actor, err := currentLogin(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("audit log: %w", err)
+	}
+
+	return &auditLog{client: client, command: command, actor: actor}, nil
+}
+
+// record logs an operation on a run, and whether it failed.
+func (a *auditLog) record(reponame string, w *github.WorkflowRun, opErr error) error {
+	if a == nil {
+		return nil
+	}
+
+	e := auditEntry{
+		Time:       time.Now().UTC(),
+		Actor:      a.actor,
+		Command:    a.command,
+		Repository: reponame,
+		RunID:      w.GetID(),
+		Workflow:   w.GetName(),
+		URL:        w.GetHTMLURL(),
+	}
+	if opErr != nil {
+		e.Error = opErr.Error()
+	}
+	a.entries = append(a.entries, e)
+
+	if *auditLogPath == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(*auditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// finish comments the recorded entries on -audit_issue.
+func (a *auditLog) finish(ctx context.Context) error {
+	if a == nil || *auditIssue == "" || len(a.entries) == 0 {
+		return nil
+	}
+
+	reponame, number, err := parseIssueRef(*auditIssue)
+	if err != nil {
+		return err
+	}
+
+	owner, name, err := ghclient.SplitRepo(reponame)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "`actionsctl %s` by @%s acted on %d runs:\n\n| Time | Repository | Run | Workflow | Error |\n|---|---|---|---|---|\n", a.command, a.actor, len(a.entries))
+	for _, e := range a.entries {
+		fmt.Fprintf(&b, "| %s | %s | [%d](%s) | %s | %s |\n", e.Time.Format(time.RFC3339), e.Repository, e.RunID, e.URL, e.Workflow, e.Error)
+	}
+
+	_, _, err = a.client.Issues.CreateComment(ctx, owner, name, number, &github.IssueComment{Body: github.String(b.String())})
+	return err
+}
+
+// parseIssueRef parses owner/repo#number.
+func parseIssueRef(ref string) (string, int, error) {
+	reponame, n, ok := strings.Cut(ref, "#")
+	number, err := strconv.Atoi(n)
+	if !ok || err != nil {
+		return "", 0, fmt.Errorf("bad issue %q: want owner/repo#number", ref)
+	}
+
+	return reponame, number, nil
+}
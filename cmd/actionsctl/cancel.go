@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	cancelFlags     = flag.NewFlagSet("cancel", flag.ExitOnError)
+	cancelWorkflow  = cancelFlags.String("workflow", "", "Only cancel runs of this workflow, by name or file name.")
+	cancelBranch    = cancelFlags.String("branch", "", "Only cancel runs on this branch.")
+	cancelEvent     = cancelFlags.String("event", "", "Only cancel runs triggered by this event, e.g. pull_request.")
+	cancelStatuses  = cancelFlags.String("status", "queued,in_progress", "Statuses of the runs to cancel, separated by commas.")
+	cancelOlderThan = cancelFlags.Duration("older_than", 0, "Only cancel runs created more than this long ago, e.g. 6h for runs stuck in the queue.")
+)
+
+func runCancel(ctx context.Context) error {
+	repoList, err := targetRepos()
+	if err != nil {
+		return err
+	}
+
+	if *cancelBranch == "" && *cancelWorkflow == "" && *cancelEvent == "" && *cancelOlderThan == 0 && !*dryRun {
+		return fmt.Errorf("refusing to cancel every run without a filter; pass -dry_run to preview them")
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	audit, err := newAuditLog(ctx, client, "cancel")
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-*cancelOlderThan)
+
+	matched, cancelled, failed := 0, 0, 0
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range strings.Split(*cancelStatuses, ",") {
+			runs, err := listRuns(ctx, client, owner, name, *cancelWorkflow, github.ListWorkflowRunsOptions{
+				Status: status,
+				Branch: *cancelBranch,
+				Event:  *cancelEvent,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, w := range runs {
+				if w.GetCreatedAt().After(cutoff) {
+					continue
+				}
+
+				matched++
+				log.Printf("%s: cancelling %s run of %s on %s (created %v ago): %s", reponame, w.GetStatus(), w.GetName(), w.GetHeadBranch(),
+					time.Since(w.GetCreatedAt().Time).Round(time.Minute), w.GetHTMLURL())
+				if *dryRun {
+					continue
+				}
+
+				_, cancelErr := client.Actions.CancelWorkflowRunByID(ctx, owner, name, w.GetID())
+				if cancelErr != nil {
+					// Runs may complete before they are cancelled; keep going.
+					log.Printf("%s: %d: failed to cancel: %v", reponame, w.GetID(), cancelErr)
+					failed++
+				} else {
+					cancelled++
+				}
+
+				if err := audit.record(reponame, w, cancelErr); err != nil {
+					return err
+				}
+
+				time.Sleep(*pace)
+			}
+		}
+	}
+
+	log.Printf("cancel: %d runs matched, %d cancelled, %d failed to cancel", matched, cancelled, failed)
+	if err := audit.finish(ctx); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d runs failed to cancel", failed, cancelled+failed)
+	}
+
+	return nil
+}
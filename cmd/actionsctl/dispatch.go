@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	dispatchFlags    = flag.NewFlagSet("dispatch", flag.ExitOnError)
+	dispatchWorkflow = dispatchFlags.String("workflow", "", "Workflow file name (e.g. maintenance.yml) or ID to dispatch.")
+	dispatchRef      = dispatchFlags.String("ref", "", "Go template of the branch or tag to run on. Defaults to each repository's default branch.")
+	dispatchInputs   stringList
+)
+
+func init() {
+	dispatchFlags.Var(&dispatchInputs, "input", "Workflow input as key=value, where value is a Go template (repeatable). "+
+		"Fields: Repository, Owner, Name, DefaultBranch.")
+}
+
+// dispatchTarget is the data input and ref templates are evaluated against.
+type dispatchTarget struct {
+	Repository    string
+	Owner         string
+	Name          string
+	DefaultBranch string
+}
+
+func runDispatch(ctx context.Context) error {
+	if *dispatchWorkflow == "" {
+		return fmt.Errorf("-workflow is required")
+	}
+
+	repoList, err := targetRepos()
+	if err != nil {
+		return err
+	}
+
+	ref, err := template.New("ref").Parse(*dispatchRef)
+	if err != nil {
+		return fmt.Errorf("bad -ref: %w", err)
+	}
+
+	inputs := map[string]*template.Template{}
+	for _, in := range dispatchInputs {
+		key, value, ok := strings.Cut(in, "=")
+		if !ok {
+			return fmt.Errorf("bad -input %q: expected key=value", in)
+		}
+
+		if inputs[key], err = template.New(key).Parse(value); err != nil {
+			return fmt.Errorf("bad -input %q: %w", key, err)
+		}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	// Dispatched runs are found by looking for runs we triggered.
+	var login string
+	if !*dryRun && *wait {
+		if login, err = currentLogin(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	var runs []*trackedRun
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		repo, _, err := client.Repositories.Get(ctx, owner, name)
+		if err != nil {
+			return err
+		}
+
+		target := dispatchTarget{Repository: reponame, Owner: owner, Name: name, DefaultBranch: repo.GetDefaultBranch()}
+
+		req := github.CreateWorkflowDispatchEventRequest{Inputs: map[string]any{}}
+		if req.Ref, err = render(ref, target); err != nil {
+			return err
+		}
+
+		if req.Ref == "" {
+			req.Ref = target.DefaultBranch
+		}
+
+		for key, tmpl := range inputs {
+			if req.Inputs[key], err = render(tmpl, target); err != nil {
+				return err
+			}
+		}
+
+		log.Printf("%s: dispatching %s on %s with inputs %v", reponame, *dispatchWorkflow, req.Ref, req.Inputs)
+		if *dryRun {
+			continue
+		}
+
+		// The run's creation time is second-granular, and may trail the request.
+		at := time.Now().Add(-5 * time.Second)
+		if id, perr := strconv.ParseInt(*dispatchWorkflow, 10, 64); perr == nil {
+			_, err = client.Actions.CreateWorkflowDispatchEventByID(ctx, owner, name, id, req)
+		} else {
+			_, err = client.Actions.CreateWorkflowDispatchEventByFileName(ctx, owner, name, *dispatchWorkflow, req)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		runs = append(runs, &trackedRun{
+			Repository: reponame,
+			owner:      owner,
+			name:       name,
+			find: func(ctx context.Context) (*github.WorkflowRun, error) {
+				return findDispatchedRun(ctx, client, owner, name, login, req.Ref, at)
+			},
+		})
+		time.Sleep(*pace)
+	}
+
+	if *dryRun || !*wait {
+		return nil
+	}
+
+	if err := waitForRuns(ctx, client, runs); err != nil {
+		return err
+	}
+
+	return summarizeRuns(runs)
+}
+
+// findDispatchedRun returns the run created by a dispatch at the given time,
+// if it was created yet.
+func findDispatchedRun(ctx context.Context, client *github.Client, owner, name, actor, ref string, at time.Time) (*github.WorkflowRun, error) {
+	opts := &github.ListWorkflowRunsOptions{
+		Actor:   actor,
+		Branch:  ref,
+		Event:   "workflow_dispatch",
+		Created: ">=" + at.UTC().Format(time.RFC3339),
+	}
+
+	var runs *github.WorkflowRuns
+	var err error
+	if id, perr := strconv.ParseInt(*dispatchWorkflow, 10, 64); perr == nil {
+		runs, _, err = client.Actions.ListWorkflowRunsByID(ctx, owner, name, id, opts)
+	} else {
+		runs, _, err = client.Actions.ListWorkflowRunsByFileName(ctx, owner, name, *dispatchWorkflow, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Runs are listed newest first; the oldest matching one is ours.
+	if n := len(runs.WorkflowRuns); n > 0 {
+		return runs.WorkflowRuns[n-1], nil
+	}
+
+	return nil, nil
+}
+
+func render(tmpl *template.Template, data any) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	environmentsFlags  = flag.NewFlagSet("environments", flag.ExitOnError)
+	environmentsFormat = environmentsFlags.String("format", "json", "Output format: json or text.")
+)
+
+type repoInventory struct {
+	Repository   string             `json:"repository"`
+	Secrets      []string           `json:"secrets"` // Repository-level secret names.
+	Environments []environmentEntry `json:"environments"`
+}
+
+type environmentEntry struct {
+	Name              string   `json:"name"`
+	WaitTimer         int      `json:"wait_timer_minutes,omitempty"`
+	Reviewers         []string `json:"reviewers,omitempty"` // Users and org/team slugs.
+	PreventSelfReview bool     `json:"prevent_self_review,omitempty"`
+	AdminsBypass      bool     `json:"admins_bypass"`
+	BranchPolicy      string   `json:"branch_policy"` // all, protected or custom.
+	Secrets           []string `json:"secrets"`
+}
+
+func runEnvironments(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetReposOrOrgs(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var inventory []repoInventory
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		inv, err := fetchInventory(ctx, client, owner, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		log.Printf("%s: %d secrets, %d environments", reponame, len(inv.Secrets), len(inv.Environments))
+		inventory = append(inventory, inv)
+	}
+
+	switch *environmentsFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(inventory)
+
+	case "text":
+		for _, inv := range inventory {
+			fmt.Printf("%s: secrets: %s\n", inv.Repository, strings.Join(inv.Secrets, ", "))
+			for _, env := range inv.Environments {
+				fmt.Printf("  environment %s: branches=%s reviewers=[%s] wait_timer=%dm admins_bypass=%t secrets: %s\n", env.Name,
+					env.BranchPolicy, strings.Join(env.Reviewers, ", "), env.WaitTimer, env.AdminsBypass, strings.Join(env.Secrets, ", "))
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported -format %q", *environmentsFormat)
+	}
+}
+
+func fetchInventory(ctx context.Context, client *github.Client, owner, name string) (repoInventory, error) {
+	inv := repoInventory{Repository: owner + "/" + name}
+
+	repo, _, err := client.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		return inv, err
+	}
+
+	if inv.Secrets, err = listSecretNames(func(opts *github.ListOptions) (*github.Secrets, *github.Response, error) {
+		return client.Actions.ListRepoSecrets(ctx, owner, name, opts)
+	}); err != nil {
+		return inv, err
+	}
+
+	opts := &github.EnvironmentListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		envs, r, err := client.Repositories.ListEnvironments(ctx, owner, name, opts)
+		if err != nil {
+			return inv, err
+		}
+
+		for _, env := range envs.Environments {
+			entry := environmentEntry{
+				Name:         env.GetName(),
+				AdminsBypass: env.GetCanAdminsBypass(),
+				BranchPolicy: "all",
+			}
+
+			switch p := env.DeploymentBranchPolicy; {
+			case p.GetProtectedBranches():
+				entry.BranchPolicy = "protected"
+			case p.GetCustomBranchPolicies():
+				entry.BranchPolicy = "custom"
+			}
+
+			for _, rule := range env.ProtectionRules {
+				switch rule.GetType() {
+				case "wait_timer":
+					entry.WaitTimer = rule.GetWaitTimer()
+
+				case "required_reviewers":
+					entry.PreventSelfReview = rule.GetPreventSelfReview()
+					for _, rr := range rule.Reviewers {
+						switch reviewer := rr.Reviewer.(type) {
+						case *github.User:
+							entry.Reviewers = append(entry.Reviewers, reviewer.GetLogin())
+						case *github.Team:
+							entry.Reviewers = append(entry.Reviewers, owner+"/"+reviewer.GetSlug())
+						}
+					}
+				}
+			}
+
+			if entry.Secrets, err = listSecretNames(func(opts *github.ListOptions) (*github.Secrets, *github.Response, error) {
+				return client.Actions.ListEnvSecrets(ctx, int(repo.GetID()), env.GetName(), opts)
+			}); err != nil {
+				return inv, err
+			}
+
+			inv.Environments = append(inv.Environments, entry)
+		}
+
+		if r.NextPage == 0 {
+			return inv, nil
+		}
+
+		opts.Page = r.NextPage
+	}
+}
+
+// listSecretNames pages through a secrets listing, returning only names.
+func listSecretNames(list func(*github.ListOptions) (*github.Secrets, *github.Response, error)) ([]string, error) {
+	var names []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		secrets, r, err := list(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range secrets.Secrets {
+			names = append(names, s.Name)
+		}
+
+		if r.NextPage == 0 {
+			return names, nil
+		}
+
+		opts.Page = r.NextPage
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/githubapp"
+)
+
+func init() {
+	ghclient.RegisterFlags(flag.CommandLine)
+}
+
+// app is set by newClient when authenticating as a GitHub App.
+var app *githubapp.Transport
+
+func newClient() (*github.Client, error) {
+	client, transport, err := ghclient.New()
+	app = transport
+	return client, err
+}
+
+// currentLogin returns the login that the client acts as.
+func currentLogin(ctx context.Context, client *github.Client) (string, error) {
+	if app != nil {
+		return app.Login(ctx)
+	}
+
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return "", err
+	}
+
+	return user.GetLogin(), nil
+}
+
+func targetRepos() ([]string, error) {
+	if *repos == "" {
+		return nil, errors.New("-repos is required")
+	}
+
+	return strings.Split(*repos, ","), nil
+}
+
+func targetOrgs() ([]string, error) {
+	if *orgs == "" {
+		return nil, errors.New("-orgs is required")
+	}
+
+	return strings.Split(*orgs, ","), nil
+}
+
+// targetReposOrOrgs returns -repos, plus the unarchived repositories of -orgs.
+func targetReposOrOrgs(ctx context.Context, client *github.Client) ([]string, error) {
+	if *repos == "" && *orgs == "" {
+		return nil, errors.New("-repos or -orgs is required")
+	}
+
+	var res []string
+	if *repos != "" {
+		res = strings.Split(*repos, ",")
+	}
+
+	if *orgs != "" {
+		for _, org := range strings.Split(*orgs, ",") {
+			orgRepos, err := ghclient.ListOrgRepos(ctx, client, org)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, r := range orgRepos {
+				if !r.GetArchived() {
+					res = append(res, r.GetFullName())
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// doJSON calls an API endpoint that the GitHub client doesn't wrap.
+func doJSON(ctx context.Context, client *github.Client, method, url string, body, v any) error {
+	req, err := client.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Do(ctx, req, v)
+	return err
+}
+
+// stringList is a flag that may be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
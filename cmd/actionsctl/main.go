@@ -0,0 +1,104 @@
+// actionsctl performs bulk GitHub Actions operations across repositories.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"namespacelabs.dev/githubtools/internal/cli"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	repos  = flag.String("repos", "", "List of repositories, separated by commas. E.g. namespacelabs/foundation")
+	orgs   = flag.String("orgs", "", "List of organizations, separated by commas, for commands that operate on organizations.")
+	dryRun = flag.Bool("dry_run", false, "Only print what would be done.")
+
+	wait         = flag.Bool("wait", true, "Track started runs until they complete.")
+	pollInterval = flag.Duration("poll_interval", 15*time.Second, "How often to check on started runs.")
+	timeout      = flag.Duration("timeout", 2*time.Hour, "Stop waiting for runs after this long.")
+	pace         = flag.Duration("pace", time.Second, "Delay between API calls that start or stop runs.")
+)
+
+type command struct {
+	flags *flag.FlagSet
+	run   func(context.Context) error
+}
+
+var commands = map[string]command{
+	"dispatch":           {dispatchFlags, runDispatch},
+	"rerun":              {rerunFlags, runRerun},
+	"cancel":             {cancelFlags, runCancel},
+	"runner-token":       {tokenFlags, runRunnerToken},
+	"runner-groups":      {runnerGroupFlags, runRunnerGroups},
+	"permissions":        {permissionsFlags, runPermissions},
+	"oidc":               {oidcFlags, runOIDC},
+	"environments":       {environmentsFlags, runEnvironments},
+	"workflow-audit":     {auditFlags, runWorkflowAudit},
+	"token-permissions":  {tokenPermissionsFlags, runTokenPermissions},
+	"required-workflows": {requiredFlags, runRequiredWorkflows},
+	"webhook":            {webhookFlags, runWebhook},
+	"policy":             {policyFlags, runPolicy},
+}
+
+func main() {
+	if handled, err := cli.Run(os.Stdout, os.Args[1:], describe); err != nil {
+		log.Fatal(err)
+	} else if handled {
+		return
+	}
+
+	flag.Usage = func() {
+		var names []string
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s <command> [flags]\n\ncommands: %v\n\nflags:\n", os.Args[0], names)
+		flag.PrintDefaults()
+	}
+
+	if len(os.Args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Subcommands accept all of the top-level flags in addition to their own.
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if cmd.flags.Lookup(f.Name) == nil {
+			cmd.flags.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+
+	_ = cmd.flags.Parse(os.Args[2:])
+
+	if err := ghclient.ConfigureHTTP(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cmd.run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// describe describes the subcommands and flags, for completion and -help-json.
+func describe() cli.Command {
+	subcommands := map[string]*flag.FlagSet{}
+	for name, cmd := range commands {
+		subcommands[name] = cmd.flags
+	}
+
+	return cli.Describe("actionsctl", flag.CommandLine, subcommands)
+}
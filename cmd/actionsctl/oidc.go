@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+var (
+	oidcFlags  = flag.NewFlagSet("oidc", flag.ExitOnError)
+	oidcFormat = oidcFlags.String("format", "text", "Output format: text or json.")
+)
+
+// oidcUsage is a workflow step that exchanges a GitHub OIDC token for cloud
+// credentials.
+type oidcUsage struct {
+	Repository string   `json:"repository"`
+	Workflow   string   `json:"workflow"` // Workflow file path.
+	Job        string   `json:"job"`
+	Action     string   `json:"action"`
+	Cloud      string   `json:"cloud"`
+	Principal  string   `json:"principal"` // Role, service account or client ID assumed.
+	Audience   string   `json:"audience"`
+	Subjects   []string `json:"subjects"` // Possible `sub` claims of the token.
+}
+
+// oidcActions maps cloud authentication actions to how their OIDC principal
+// and audience are determined from their inputs. They return false if the
+// step doesn't use OIDC (e.g. it's configured with static keys).
+var oidcActions = map[string]func(with map[string]string) (cloud, principal, audience string, ok bool){
+	"aws-actions/configure-aws-credentials": func(with map[string]string) (string, string, string, bool) {
+		if with["role-to-assume"] == "" || with["aws-access-key-id"] != "" {
+			return "", "", "", false
+		}
+		return "aws", with["role-to-assume"], or(with["audience"], "sts.amazonaws.com"), true
+	},
+	"google-github-actions/auth": func(with map[string]string) (string, string, string, bool) {
+		provider := with["workload_identity_provider"]
+		if provider == "" {
+			return "", "", "", false
+		}
+		return "gcp", or(with["service_account"], provider), or(with["audience"], "https://iam.googleapis.com/"+strings.TrimPrefix(provider, "/")), true
+	},
+	"azure/login": func(with map[string]string) (string, string, string, bool) {
+		if with["client-id"] == "" || with["creds"] != "" {
+			return "", "", "", false
+		}
+		return "azure", with["client-id"], or(with["audience"], "api://AzureADTokenExchange"), true
+	},
+}
+
+func runOIDC(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetReposOrOrgs(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var usages []oidcUsage
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		repo, _, err := client.Repositories.Get(ctx, owner, name)
+		if err != nil {
+			return err
+		}
+
+		// A custom subject template replaces the default subject format.
+		var custom []string
+		tmpl, _, err := client.Actions.GetRepoOIDCSubjectClaimCustomTemplate(ctx, owner, name)
+		if err != nil && !ghclient.IsNotFound(err) {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+		if tmpl != nil && !tmpl.GetUseDefault() {
+			custom = tmpl.IncludeClaimKeys
+		}
+
+		files, err := workflows.Fetch(ctx, client, owner, name, "")
+		if err != nil {
+			return err
+		}
+
+		for _, w := range files {
+			for _, id := range w.SortedJobs() {
+				job := w.Jobs[id]
+				for _, step := range job.Steps {
+					action := workflows.ActionName(step.Uses)
+					detect, ok := oidcActions[action]
+					if !ok {
+						continue
+					}
+
+					cloud, principal, audience, ok := detect(step.With)
+					if !ok {
+						continue
+					}
+
+					u := oidcUsage{
+						Repository: reponame,
+						Workflow:   w.Path,
+						Job:        id,
+						Action:     action,
+						Cloud:      cloud,
+						Principal:  principal,
+						Audience:   audience,
+					}
+
+					if custom != nil {
+						u.Subjects = []string{fmt.Sprintf("custom template with claims %s", strings.Join(custom, ","))}
+					} else {
+						u.Subjects = defaultSubjects(reponame, repo.GetDefaultBranch(), w, job)
+					}
+
+					usages = append(usages, u)
+				}
+			}
+		}
+
+		log.Printf("%s: scanned %d workflows", reponame, len(files))
+	}
+
+	switch *oidcFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(usages)
+
+	case "text":
+		for _, u := range usages {
+			fmt.Printf("%s: %s: %s: %s %s (audience %s)\n", u.Repository, u.Workflow, u.Job, u.Cloud, u.Principal, u.Audience)
+			for _, s := range u.Subjects {
+				fmt.Printf("  sub: %s\n", s)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported -format %q", *oidcFormat)
+	}
+}
+
+// defaultSubjects returns the `sub` claims a job's OIDC tokens may carry with
+// GitHub's default subject format, given the events that trigger it.
+func defaultSubjects(repo, defaultBranch string, w *workflows.File, job *workflows.Job) []string {
+	prefix := "repo:" + repo + ":"
+	if env := job.EnvironmentName(); env != "" {
+		return []string{prefix + "environment:" + env}
+	}
+
+	subjects := map[string]bool{}
+	refs := func(kind string, patterns []string) {
+		if len(patterns) == 0 {
+			patterns = []string{"*"}
+		}
+		for _, p := range patterns {
+			subjects[prefix+"ref:refs/"+kind+"/"+p] = true
+		}
+	}
+
+	for event, f := range w.Triggers() {
+		switch event {
+		case "pull_request":
+			subjects[prefix+"pull_request"] = true
+
+		case "push":
+			if len(f.Branches) == 0 && len(f.Tags) == 0 {
+				refs("heads", nil)
+				refs("tags", nil)
+			}
+			if len(f.Branches) > 0 {
+				refs("heads", f.Branches)
+			}
+			if len(f.Tags) > 0 {
+				refs("tags", f.Tags)
+			}
+
+		case "pull_request_target":
+			// Runs in the context of the base branch.
+			refs("heads", f.Branches)
+
+		case "schedule":
+			refs("heads", []string{defaultBranch})
+
+		default:
+			refs("heads", nil)
+		}
+	}
+
+	var res []string
+	for s := range subjects {
+		res = append(res, s)
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+func or(v, def string) string {
+	if v == "" {
+		return def
+	}
+
+	return v
+}
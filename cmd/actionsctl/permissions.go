@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	permissionsFlags     = flag.NewFlagSet("permissions", flag.ExitOnError)
+	permissionsPolicy    = permissionsFlags.String("policy", "", "JSON policy file with the intended Actions settings; without it, the settings are only reported.")
+	permissionsFormat    = permissionsFlags.String("format", "text", "Output format: text or json.")
+	permissionsRemediate = permissionsFlags.Bool("remediate", false, "Change the settings of repositories that differ from the policy (honors -dry_run).")
+)
+
+// actionsSettings are the Actions settings of a repository. In a policy,
+// unset fields aren't audited. E.g.:
+//
+//	{
+//	  "allowed_actions": "selected",
+//	  "github_owned_allowed": true,
+//	  "patterns_allowed": ["namespacelabs/*"],
+//	  "default_workflow_permissions": "read",
+//	  "can_approve_pull_request_reviews": false,
+//	  "fork_pr_approval_policy": "all_external_contributors"
+//	}
+type actionsSettings struct {
+	Enabled                      *bool    `json:"enabled,omitempty"`
+	AllowedActions               *string  `json:"allowed_actions,omitempty"` // all, local_only or selected.
+	GithubOwnedAllowed           *bool    `json:"github_owned_allowed,omitempty"`
+	VerifiedAllowed              *bool    `json:"verified_allowed,omitempty"`
+	PatternsAllowed              []string `json:"patterns_allowed,omitempty"`
+	DefaultWorkflowPermissions   *string  `json:"default_workflow_permissions,omitempty"` // read or write.
+	CanApprovePullRequestReviews *bool    `json:"can_approve_pull_request_reviews,omitempty"`
+	ForkPRApprovalPolicy         *string  `json:"fork_pr_approval_policy,omitempty"`
+}
+
+type repoActionsSettings struct {
+	Repository string          `json:"repository"`
+	Settings   actionsSettings `json:"settings"`
+	Diffs      []string        `json:"diffs,omitempty"`
+}
+
+// The GitHub client doesn't support these settings yet.
+
+type workflowPermissions struct {
+	DefaultWorkflowPermissions   *string `json:"default_workflow_permissions,omitempty"`
+	CanApprovePullRequestReviews *bool   `json:"can_approve_pull_request_reviews,omitempty"`
+}
+
+type forkPRApproval struct {
+	ApprovalPolicy *string `json:"approval_policy,omitempty"`
+}
+
+func runPermissions(ctx context.Context) error {
+	var policy *actionsSettings
+	if *permissionsPolicy != "" {
+		contents, err := os.ReadFile(*permissionsPolicy)
+		if err != nil {
+			return err
+		}
+
+		policy = &actionsSettings{}
+		if err := json.Unmarshal(contents, policy); err != nil {
+			return fmt.Errorf("%s: %w", *permissionsPolicy, err)
+		}
+	} else if *permissionsRemediate {
+		return fmt.Errorf("-remediate requires -policy")
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetReposOrOrgs(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var results []repoActionsSettings
+	differing := 0
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		settings, err := fetchActionsSettings(ctx, client, owner, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		res := repoActionsSettings{Repository: reponame, Settings: settings}
+		if policy != nil {
+			res.Diffs = diffActionsSettings(settings, *policy)
+		}
+
+		if len(res.Diffs) > 0 {
+			differing++
+
+			if *permissionsRemediate {
+				log.Printf("%s: remediating: %v", reponame, res.Diffs)
+				if !*dryRun {
+					if err := applyActionsSettings(ctx, client, owner, name, *policy); err != nil {
+						return fmt.Errorf("%s: %w", reponame, err)
+					}
+				}
+			}
+		}
+
+		results = append(results, res)
+	}
+
+	switch *permissionsFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+
+	case "text":
+		for _, r := range results {
+			s := r.Settings
+			fmt.Printf("%s: enabled=%s allowed_actions=%s default_token=%s can_approve_prs=%s fork_pr_approval=%s\n", r.Repository,
+				show(s.Enabled), show(s.AllowedActions), show(s.DefaultWorkflowPermissions), show(s.CanApprovePullRequestReviews), show(s.ForkPRApprovalPolicy))
+			for _, d := range r.Diffs {
+				fmt.Printf("  differs from policy: %s\n", d)
+			}
+		}
+
+	default:
+		return fmt.Errorf("unsupported -format %q", *permissionsFormat)
+	}
+
+	log.Printf("permissions: %d of %d repositories differ from the policy", differing, len(results))
+
+	if differing > 0 && !*permissionsRemediate {
+		return fmt.Errorf("%d repositories differ from the policy", differing)
+	}
+
+	return nil
+}
+
+func fetchActionsSettings(ctx context.Context, client *github.Client, owner, name string) (actionsSettings, error) {
+	var s actionsSettings
+
+	perms, _, err := client.Repositories.GetActionsPermissions(ctx, owner, name)
+	if err != nil {
+		return s, err
+	}
+
+	s.Enabled = perms.Enabled
+	s.AllowedActions = perms.AllowedActions
+
+	if perms.GetAllowedActions() == "selected" {
+		allowed, _, err := client.Repositories.GetActionsAllowed(ctx, owner, name)
+		if err != nil {
+			return s, err
+		}
+
+		s.GithubOwnedAllowed = allowed.GithubOwnedAllowed
+		s.VerifiedAllowed = allowed.VerifiedAllowed
+		s.PatternsAllowed = allowed.PatternsAllowed
+	}
+
+	var wp workflowPermissions
+	if err := doJSON(ctx, client, http.MethodGet, fmt.Sprintf("repos/%s/%s/actions/permissions/workflow", owner, name), nil, &wp); err != nil {
+		return s, err
+	}
+
+	s.DefaultWorkflowPermissions = wp.DefaultWorkflowPermissions
+	s.CanApprovePullRequestReviews = wp.CanApprovePullRequestReviews
+
+	// Only applies to public repositories.
+	var fork forkPRApproval
+	if err := doJSON(ctx, client, http.MethodGet, fmt.Sprintf("repos/%s/%s/actions/permissions/fork-pr-contributor-approval", owner, name), nil, &fork); err != nil && !ghclient.IsNotFound(err) {
+		return s, err
+	}
+
+	s.ForkPRApprovalPolicy = fork.ApprovalPolicy
+
+	return s, nil
+}
+
+func diffActionsSettings(got, want actionsSettings) []string {
+	var diffs []string
+	diffBool := func(name string, got, want *bool) {
+		if want != nil && (got == nil || *got != *want) {
+			diffs = append(diffs, fmt.Sprintf("%s is %s, want %t", name, show(got), *want))
+		}
+	}
+	diffString := func(name string, got, want *string) {
+		if want != nil && (got == nil || *got != *want) {
+			diffs = append(diffs, fmt.Sprintf("%s is %s, want %s", name, show(got), *want))
+		}
+	}
+
+	diffBool("enabled", got.Enabled, want.Enabled)
+	diffString("allowed_actions", got.AllowedActions, want.AllowedActions)
+	diffBool("github_owned_allowed", got.GithubOwnedAllowed, want.GithubOwnedAllowed)
+	diffBool("verified_allowed", got.VerifiedAllowed, want.VerifiedAllowed)
+	if want.PatternsAllowed != nil && !slices.Equal(got.PatternsAllowed, want.PatternsAllowed) {
+		diffs = append(diffs, fmt.Sprintf("patterns_allowed is %v, want %v", got.PatternsAllowed, want.PatternsAllowed))
+	}
+	diffString("default_workflow_permissions", got.DefaultWorkflowPermissions, want.DefaultWorkflowPermissions)
+	diffBool("can_approve_pull_request_reviews", got.CanApprovePullRequestReviews, want.CanApprovePullRequestReviews)
+	diffString("fork_pr_approval_policy", got.ForkPRApprovalPolicy, want.ForkPRApprovalPolicy)
+
+	return diffs
+}
+
+// applyActionsSettings sets the policy's settings on a repository.
+func applyActionsSettings(ctx context.Context, client *github.Client, owner, name string, policy actionsSettings) error {
+	if policy.Enabled != nil || policy.AllowedActions != nil {
+		perms := github.ActionsPermissionsRepository{Enabled: policy.Enabled, AllowedActions: policy.AllowedActions}
+		if perms.Enabled == nil {
+			// Enabled is required.
+			current, _, err := client.Repositories.GetActionsPermissions(ctx, owner, name)
+			if err != nil {
+				return err
+			}
+			perms.Enabled = current.Enabled
+		}
+
+		if _, _, err := client.Repositories.EditActionsPermissions(ctx, owner, name, perms); err != nil {
+			return err
+		}
+	}
+
+	if policy.GithubOwnedAllowed != nil || policy.VerifiedAllowed != nil || policy.PatternsAllowed != nil {
+		allowed := github.ActionsAllowed{
+			GithubOwnedAllowed: policy.GithubOwnedAllowed,
+			VerifiedAllowed:    policy.VerifiedAllowed,
+			PatternsAllowed:    policy.PatternsAllowed,
+		}
+
+		if _, _, err := client.Repositories.EditActionsAllowed(ctx, owner, name, allowed); err != nil {
+			return err
+		}
+	}
+
+	if policy.DefaultWorkflowPermissions != nil || policy.CanApprovePullRequestReviews != nil {
+		wp := workflowPermissions{
+			DefaultWorkflowPermissions:   policy.DefaultWorkflowPermissions,
+			CanApprovePullRequestReviews: policy.CanApprovePullRequestReviews,
+		}
+
+		if err := doJSON(ctx, client, http.MethodPut, fmt.Sprintf("repos/%s/%s/actions/permissions/workflow", owner, name), wp, nil); err != nil {
+			return err
+		}
+	}
+
+	if policy.ForkPRApprovalPolicy != nil {
+		fork := forkPRApproval{ApprovalPolicy: policy.ForkPRApprovalPolicy}
+		if err := doJSON(ctx, client, http.MethodPut, fmt.Sprintf("repos/%s/%s/actions/permissions/fork-pr-contributor-approval", owner, name), fork, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func show[T any](v *T) string {
+	if v == nil {
+		return "unset"
+	}
+
+	return fmt.Sprint(*v)
+}
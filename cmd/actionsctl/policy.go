@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"github.com/google/go-github/v58/github"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+var (
+	policyFlags  = flag.NewFlagSet("policy", flag.ExitOnError)
+	policyRules  = policyFlags.String("rules", "", "JSON file of the policy rules to evaluate against every workflow job; each rule is a CEL expression that is true for violating jobs, e.g. \"ubuntu-latest\" in runs_on.")
+	policyFormat = policyFlags.String("format", "text", "Output format: text, json, or sarif (see -sarif_dir).")
+	policyCheck  = policyFlags.Bool("check", false, "Also post an \"actionsctl policy\" check run on the default branch of each repository, failing if it has violations, "+
+		"so that it can be made a required check (honors -dry_run; needs a GitHub App, see -app-id).")
+	policyPRs = policyFlags.Bool("pull_requests", false, "Instead of the default branches, evaluate the workflow files that each open pull request changes, at its head, "+
+		"and post the result as an \"actionsctl policy\" check run on it (a passing one if it changes no workflows), so that violations block merging (honors -dry_run; needs a GitHub App, see -app-id).")
+)
+
+// policyRulesFile lists rules, each a CEL expression (https://cel.dev) that
+// is true for the jobs that violate it. E.g.:
+//
+//	{
+//	  "rules": [
+//	    {
+//	      "name": "no-ubuntu-latest-in-prod",
+//	      "description": "Production repositories pin their runner image.",
+//	      "violation": "\"prod\" in topics && \"ubuntu-latest\" in runs_on"
+//	    }
+//	  ]
+//	}
+//
+// A rule is evaluated once per job, with the job's fields as variables (see
+// policyVariables), and must be a bool. Besides CEL's standard functions and
+// macros (in, size, exists, all, matches, startsWith, ...), rules may use the
+// string extensions, e.g. job_name.lowerAscii().
+type policyRulesFile struct {
+	Rules []policyRule `json:"rules"`
+}
+
+type policyRule struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Violation   string `json:"violation"`
+
+	prg cel.Program
+}
+
+// policyJob is a workflow job, as policy rules see it.
+type policyJob struct {
+	Repository     string
+	Topics         []string
+	Visibility     string // public, private or internal.
+	DefaultBranch  string
+	Workflow       string // Workflow file path.
+	WorkflowName   string
+	Triggers       []string
+	Job            string
+	JobName        string
+	RunsOn         []string
+	Actions        []string // Actions the job uses, without versions; includes reusable workflows.
+	Unpinned       []string // Actions the job uses that aren't pinned to a commit SHA, with their versions.
+	Permissions    workflows.Permissions
+	Environment    string
+	TimeoutMinutes int       // Or 0 if it's an expression.
+	LastRun        policyRun // The workflow's latest run on the default branch; only fetched if a rule refers to it.
+
+	line int
+}
+
+// policyRun is a workflow run, or the zero value if there's none.
+type policyRun struct {
+	Conclusion string
+	Event      string
+	Actor      string
+	Created    time.Time
+}
+
+// policyViolation is a job that violates a rule.
+type policyViolation struct {
+	Repository  string `json:"repository"`
+	Workflow    string `json:"workflow"`
+	Job         string `json:"job"`
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	Line        int    `json:"line"`
+}
+
+// policyVariables declares a variable for each policyJob field, in snake_case.
+var policyVariables = []cel.EnvOption{
+	cel.Variable("repository", cel.StringType),
+	cel.Variable("topics", cel.ListType(cel.StringType)),
+	cel.Variable("visibility", cel.StringType),
+	cel.Variable("default_branch", cel.StringType),
+	cel.Variable("workflow", cel.StringType),
+	cel.Variable("workflow_name", cel.StringType),
+	cel.Variable("triggers", cel.ListType(cel.StringType)),
+	cel.Variable("job", cel.StringType),
+	cel.Variable("job_name", cel.StringType),
+	cel.Variable("runs_on", cel.ListType(cel.StringType)),
+	cel.Variable("actions", cel.ListType(cel.StringType)),
+	cel.Variable("unpinned", cel.ListType(cel.StringType)),
+	cel.Variable("permissions", cel.MapType(cel.StringType, cel.StringType)),
+	cel.Variable("environment", cel.StringType),
+	cel.Variable("timeout_minutes", cel.IntType),
+	cel.Variable("last_run", cel.MapType(cel.StringType, cel.DynType)), // conclusion, event, actor and created.
+}
+
+// vars returns the job as the variables of policyVariables.
+func (j policyJob) vars() map[string]any {
+	return map[string]any{
+		"repository":      j.Repository,
+		"topics":          j.Topics,
+		"visibility":      j.Visibility,
+		"default_branch":  j.DefaultBranch,
+		"workflow":        j.Workflow,
+		"workflow_name":   j.WorkflowName,
+		"triggers":        j.Triggers,
+		"job":             j.Job,
+		"job_name":        j.JobName,
+		"runs_on":         j.RunsOn,
+		"actions":         j.Actions,
+		"unpinned":        j.Unpinned,
+		"permissions":     map[string]string(j.Permissions),
+		"environment":     j.Environment,
+		"timeout_minutes": j.TimeoutMinutes,
+		"last_run": map[string]any{
+			"conclusion": j.LastRun.Conclusion,
+			"event":      j.LastRun.Event,
+			"actor":      j.LastRun.Actor,
+			"created":    j.LastRun.Created,
+		},
+	}
+}
+
+func runPolicy(ctx context.Context) error {
+	if *policyRules == "" {
+		return fmt.Errorf("-rules is required")
+	}
+
+	rules, err := loadPolicyRules(*policyRules)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetReposOrOrgs(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	fetchLastRun := false
+	for _, r := range rules {
+		fetchLastRun = fetchLastRun || strings.Contains(r.Violation, "last_run")
+	}
+
+	var violations []policyViolation
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		repo, _, err := client.Repositories.Get(ctx, owner, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		if *policyPRs {
+			found, err := checkPullRequests(ctx, client, reponame, repo, rules, fetchLastRun)
+			if err != nil {
+				return fmt.Errorf("%s: %w", reponame, err)
+			}
+
+			violations = append(violations, found...)
+			continue
+		}
+
+		files, err := workflows.Fetch(ctx, client, owner, name, "")
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		jobs, err := policyJobs(ctx, client, repo, files, fetchLastRun)
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		found, err := evaluatePolicy(reponame, rules, jobs)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("%s: %d policy violations across %d jobs", reponame, len(found), len(jobs))
+		violations = append(violations, found...)
+
+		if *policyCheck {
+			branch, _, err := client.Repositories.GetBranch(ctx, owner, name, repo.GetDefaultBranch(), 0)
+			if err != nil {
+				return fmt.Errorf("%s: %w", reponame, err)
+			}
+
+			if err := postPolicyCheck(ctx, client, repo, branch.GetCommit().GetSHA(), repo.GetDefaultBranch(), found); err != nil {
+				return fmt.Errorf("%s: %w", reponame, err)
+			}
+		}
+	}
+
+	switch *policyFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(violations); err != nil {
+			return err
+		}
+
+	case "text":
+		for _, v := range violations {
+			fmt.Printf("%s: %s / %s: %s: %s\n", v.Repository, v.Workflow, v.Job, v.Rule, v.Description)
+		}
+
+	case "sarif":
+		descriptions := map[string]string{}
+		for _, r := range rules {
+			descriptions[r.Name] = r.Description
+		}
+
+		var sf []sarifFinding
+		for _, v := range violations {
+			sf = append(sf, sarifFinding{
+				Repository: v.Repository,
+				Path:       v.Workflow,
+				Line:       v.Line,
+				Rule:       v.Rule,
+				Level:      "error",
+				Message:    fmt.Sprintf("Job %s: %s", v.Job, v.Description),
+			})
+		}
+
+		if err := writeSARIF("policy", repoList, descriptions, sf); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unsupported -format %q", *policyFormat)
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%d policy violations", len(violations))
+	}
+
+	return nil
+}
+
+func loadPolicyRules(file string) ([]policyRule, error) {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var f policyRulesFile
+	if err := json.Unmarshal(contents, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	env, err := cel.NewEnv(append(policyVariables, ext.Strings())...)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, r := range f.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("%s: rule %d has no name", file, k)
+		}
+
+		ast, iss := env.Compile(r.Violation)
+		if iss.Err() != nil {
+			return nil, fmt.Errorf("%s: rule %s: %w", file, r.Name, iss.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("%s: rule %s must be a bool, not %v", file, r.Name, ast.OutputType())
+		}
+
+		if f.Rules[k].prg, err = env.Program(ast); err != nil {
+			return nil, fmt.Errorf("%s: rule %s: %w", file, r.Name, err)
+		}
+	}
+
+	return f.Rules, nil
+}
+
+// evaluatePolicy returns the violations of rules by the jobs of reponame.
+func evaluatePolicy(reponame string, rules []policyRule, jobs []policyJob) ([]policyViolation, error) {
+	var res []policyViolation
+	for _, j := range jobs {
+		vars := j.vars()
+		for _, r := range rules {
+			out, _, err := r.prg.Eval(vars)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %s / %s: %w", r.Name, j.Workflow, j.Job, err)
+			}
+
+			violated, ok := out.Value().(bool)
+			if !ok {
+				return nil, fmt.Errorf("rule %s must evaluate to true or false, got %v", r.Name, out)
+			}
+
+			if violated {
+				res = append(res, policyViolation{Repository: reponame, Workflow: j.Workflow, Job: j.Job, Rule: r.Name, Description: r.Description, Line: j.line})
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// checkPullRequests evaluates the workflow files that the open pull requests
+// of reponame change, and posts the result as a check run on each.
+func checkPullRequests(ctx context.Context, client *github.Client, reponame string, repo *github.Repository, rules []policyRule, fetchLastRun bool) ([]policyViolation, error) {
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+
+	var violations []policyViolation
+	opts := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		prs, resp, err := client.PullRequests.List(ctx, owner, name, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pr := range prs {
+			changed, err := changedWorkflows(ctx, client, owner, name, pr.GetNumber())
+			if err != nil {
+				return nil, err
+			}
+
+			sha := pr.GetHead().GetSHA()
+			where := fmt.Sprintf("#%d", pr.GetNumber())
+			if len(changed) == 0 {
+				// Still post a check, so that it can be required of every pull request.
+				if err := createPolicyCheck(ctx, client, repo, sha, where, "success", "No workflow changes", "This pull request changes no workflow files."); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			var files []*workflows.File
+			for _, p := range changed {
+				w, err := workflows.FetchFile(ctx, client, owner, name, p, sha)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, w)
+			}
+
+			jobs, err := policyJobs(ctx, client, repo, files, fetchLastRun)
+			if err != nil {
+				return nil, err
+			}
+
+			found, err := evaluatePolicy(reponame, rules, jobs)
+			if err != nil {
+				return nil, err
+			}
+
+			log.Printf("%s#%d: %d policy violations across %d jobs of %d changed workflows", reponame, pr.GetNumber(), len(found), len(jobs), len(files))
+			violations = append(violations, found...)
+
+			if err := postPolicyCheck(ctx, client, repo, sha, where, found); err != nil {
+				return nil, err
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return violations, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// changedWorkflows returns the workflow files that a pull request adds or
+// modifies.
+func changedWorkflows(ctx context.Context, client *github.Client, owner, name string, number int) ([]string, error) {
+	var res []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		files, resp, err := client.PullRequests.ListFiles(ctx, owner, name, number, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			p := f.GetFilename()
+			if ext := path.Ext(p); path.Dir(p) == ".github/workflows" && (ext == ".yml" || ext == ".yaml") && f.GetStatus() != "removed" {
+				res = append(res, p)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return res, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// policyJobs returns the jobs of the workflow files of repo.
+func policyJobs(ctx context.Context, client *github.Client, repo *github.Repository, files []*workflows.File, fetchLastRun bool) ([]policyJob, error) {
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+
+	var res []policyJob
+	for _, w := range files {
+		var triggers []string
+		for t := range w.Triggers() {
+			triggers = append(triggers, t)
+		}
+		sort.Strings(triggers)
+
+		var lastRun policyRun
+		if fetchLastRun {
+			runs, _, err := client.Actions.ListWorkflowRunsByFileName(ctx, owner, name, path.Base(w.Path), &github.ListWorkflowRunsOptions{
+				Branch:      repo.GetDefaultBranch(),
+				ListOptions: github.ListOptions{PerPage: 1},
+			})
+			if err != nil && !ghclient.IsNotFound(err) {
+				return nil, err
+			}
+
+			if runs != nil && len(runs.WorkflowRuns) > 0 {
+				r := runs.WorkflowRuns[0]
+				lastRun = policyRun{Conclusion: r.GetConclusion(), Event: r.GetEvent(), Actor: r.GetActor().GetLogin(), Created: r.GetCreatedAt().Time}
+			}
+		}
+
+		workflowPerms := workflows.ParsePermissions(w.Permissions)
+		for _, id := range w.SortedJobs() {
+			job := w.Jobs[id]
+
+			var actions, unpinned []string
+			for _, step := range append([]workflows.Step{{Uses: job.Uses}}, job.Steps...) {
+				if step.Uses == "" {
+					continue
+				}
+
+				actions = append(actions, workflows.ActionName(step.Uses))
+				if _, ref, ok := strings.Cut(step.Uses, "@"); ok && !shaRef.MatchString(ref) && !strings.HasPrefix(step.Uses, "docker://") {
+					unpinned = append(unpinned, step.Uses)
+				}
+			}
+
+			perms := workflows.ParsePermissions(job.Permissions)
+			if perms == nil {
+				perms = workflowPerms
+			}
+
+			timeout, _ := job.Timeout()
+
+			res = append(res, policyJob{
+				Repository:     repo.GetFullName(),
+				Topics:         repo.Topics,
+				Visibility:     repo.GetVisibility(),
+				DefaultBranch:  repo.GetDefaultBranch(),
+				Workflow:       w.Path,
+				WorkflowName:   w.DisplayName(),
+				Triggers:       triggers,
+				Job:            id,
+				JobName:        job.DisplayName(id),
+				RunsOn:         job.RunnerLabels(),
+				Actions:        actions,
+				Unpinned:       unpinned,
+				Permissions:    perms,
+				Environment:    job.EnvironmentName(),
+				TimeoutMinutes: timeout,
+				LastRun:        lastRun,
+				line:           job.Line,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// postPolicyCheck posts violations as a check run on the commit sha, which
+// is described as where for logging.
+func postPolicyCheck(ctx context.Context, client *github.Client, repo *github.Repository, sha, where string, violations []policyViolation) error {
+	conclusion, title := "success", "No policy violations"
+	var summary strings.Builder
+	if len(violations) == 0 {
+		summary.WriteString("All workflow jobs comply with the policy.")
+	} else {
+		conclusion, title = "failure", fmt.Sprintf("%d policy violations", len(violations))
+		summary.WriteString("| Workflow | Job | Rule | Description |\n|---|---|---|---|\n")
+		for _, v := range violations {
+			fmt.Fprintf(&summary, "| %s | %s | %s | %s |\n", v.Workflow, v.Job, v.Rule, v.Description)
+		}
+	}
+
+	return createPolicyCheck(ctx, client, repo, sha, where, conclusion, title, summary.String())
+}
+
+func createPolicyCheck(ctx context.Context, client *github.Client, repo *github.Repository, sha, where, conclusion, title, summary string) error {
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+
+	log.Printf("%s: posting a %s policy check on %s", repo.GetFullName(), conclusion, where)
+	if *dryRun {
+		return nil
+	}
+
+	_, _, err := client.Checks.CreateCheckRun(ctx, owner, name, github.CreateCheckRunOptions{
+		Name:       "actionsctl policy",
+		HeadSHA:    sha,
+		Conclusion: github.String(conclusion),
+		Output: &github.CheckRunOutput{
+			Title:   github.String(title),
+			Summary: github.String(summary),
+		},
+	})
+	return err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+func TestEvaluatePolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(file, []byte(`{"rules": [
+		{"name": "no-ubuntu-latest-in-prod", "violation": "\"prod\" in topics && \"ubuntu-latest\" in runs_on"},
+		{"name": "release-timeout", "violation": "job_name.lowerAscii().startsWith(\"release\") && timeout_minutes == 0"},
+		{"name": "failing-writer", "violation": "permissions.exists(scope, permissions[scope] == \"write\") && last_run.conclusion == \"failure\""}
+	]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := loadPolicyRules(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := []policyJob{
+		{Workflow: "ci.yml", Job: "build", Topics: []string{"prod"}, RunsOn: []string{"ubuntu-latest"}, TimeoutMinutes: 30, line: 7},
+		{Workflow: "ci.yml", Job: "release", JobName: "Release", RunsOn: []string{"ubuntu-22.04"}, line: 20},
+		{Workflow: "ci.yml", Job: "test", RunsOn: []string{"ubuntu-latest"}, line: 12},
+		{Workflow: "deploy.yml", Job: "deploy", Permissions: workflows.Permissions{"contents": "write"}, LastRun: policyRun{Conclusion: "failure"}, line: 5},
+	}
+
+	got, err := evaluatePolicy("acme/app", rules, jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []policyViolation{
+		{Repository: "acme/app", Workflow: "ci.yml", Job: "build", Rule: "no-ubuntu-latest-in-prod", Line: 7},
+		{Repository: "acme/app", Workflow: "ci.yml", Job: "release", Rule: "release-timeout", Line: 20},
+		{Repository: "acme/app", Workflow: "deploy.yml", Job: "deploy", Rule: "failing-writer", Line: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	for _, violation := range []string{"job", "runs_on.contains(\"x\")"} {
+		if err := os.WriteFile(file, []byte(`{"rules": [{"name": "bad", "violation": "`+strings.ReplaceAll(violation, `"`, `\"`)+`"}]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := loadPolicyRules(file); err == nil {
+			t.Errorf("want an error for the rule %s", violation)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+var (
+	requiredFlags  = flag.NewFlagSet("required-workflows", flag.ExitOnError)
+	requiredPolicy = requiredFlags.String("policy", "", "JSON policy file listing the workflows repositories must have.")
+	requiredFormat = requiredFlags.String("format", "text", "Output format: text or json.")
+)
+
+// requiredWorkflowPolicy lists the standard workflows repositories must run.
+// E.g.:
+//
+//	{
+//	  "workflows": [
+//	    {"name": "codeql", "uses": ["github/codeql-action/analyze"]},
+//	    {"name": "lint", "files": ["lint.y*ml"], "repositories": ["*"], "exclude": ["legacy-*"]},
+//	    {"name": "shared ci", "uses": ["namespacelabs/.github/.github/workflows/ci.yaml"]}
+//	  ]
+//	}
+//
+// A repository has a required workflow if one of its workflow files matches
+// files (path.Match patterns of file names), or uses one of the listed actions
+// or reusable workflows. Repositories and exclude are path.Match patterns of
+// repository names; without repositories, every repository must have it.
+type requiredWorkflowPolicy struct {
+	Workflows []*requiredWorkflow `json:"workflows"`
+}
+
+type requiredWorkflow struct {
+	Name         string   `json:"name"`
+	Files        []string `json:"files"`
+	Uses         []string `json:"uses"`
+	Repositories []string `json:"repositories"`
+	Exclude      []string `json:"exclude"`
+}
+
+func (r *requiredWorkflow) appliesTo(repo string) bool {
+	matches := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, repo); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	return (len(r.Repositories) == 0 || matches(r.Repositories)) && !matches(r.Exclude)
+}
+
+func (r *requiredWorkflow) matches(w *workflows.File) bool {
+	for _, p := range r.Files {
+		if ok, _ := path.Match(p, path.Base(w.Path)); ok {
+			return true
+		}
+	}
+
+	for _, job := range w.Jobs {
+		uses := []string{job.Uses}
+		for _, step := range job.Steps {
+			uses = append(uses, step.Uses)
+		}
+
+		for _, u := range uses {
+			for _, want := range r.Uses {
+				if u != "" && workflows.ActionName(u) == strings.ToLower(want) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+type requiredCoverage struct {
+	Repository string `json:"repository"`
+	Workflow   string `json:"workflow"` // The required workflow's name.
+	Status     string `json:"status"`   // present, missing or disabled.
+	Path       string `json:"path,omitempty"`
+}
+
+func runRequiredWorkflows(ctx context.Context) error {
+	if *requiredPolicy == "" {
+		return fmt.Errorf("-policy is required")
+	}
+
+	contents, err := os.ReadFile(*requiredPolicy)
+	if err != nil {
+		return err
+	}
+
+	var policy requiredWorkflowPolicy
+	if err := json.Unmarshal(contents, &policy); err != nil {
+		return fmt.Errorf("%s: %w", *requiredPolicy, err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetReposOrOrgs(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var coverage []requiredCoverage
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		files, err := workflows.Fetch(ctx, client, owner, name, "")
+		if err != nil {
+			return err
+		}
+
+		states, err := workflowStates(ctx, client, owner, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		for _, req := range policy.Workflows {
+			if !req.appliesTo(name) {
+				continue
+			}
+
+			c := requiredCoverage{Repository: reponame, Workflow: req.Name, Status: "missing"}
+			for _, w := range files {
+				if !req.matches(w) {
+					continue
+				}
+
+				c.Path = w.Path
+				if state := states[w.Path]; state == "active" || state == "" {
+					c.Status = "present"
+					break
+				}
+				c.Status = "disabled"
+			}
+
+			coverage = append(coverage, c)
+		}
+	}
+
+	switch *requiredFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(coverage); err != nil {
+			return err
+		}
+
+	case "text":
+		for _, c := range coverage {
+			switch c.Status {
+			case "missing":
+				fmt.Printf("%s: %s is missing\n", c.Repository, c.Workflow)
+			case "disabled":
+				fmt.Printf("%s: %s is disabled (%s)\n", c.Repository, c.Workflow, c.Path)
+			}
+		}
+
+	default:
+		return fmt.Errorf("unsupported -format %q", *requiredFormat)
+	}
+
+	gaps := 0
+	for _, req := range policy.Workflows {
+		present, total := 0, 0
+		for _, c := range coverage {
+			if c.Workflow == req.Name {
+				total++
+				if c.Status == "present" {
+					present++
+				}
+			}
+		}
+
+		gaps += total - present
+		log.Printf("%s: rolled out to %d of %d repositories", req.Name, present, total)
+	}
+
+	if gaps > 0 {
+		return fmt.Errorf("%d required workflows are missing or disabled", gaps)
+	}
+
+	return nil
+}
+
+// workflowStates maps the paths of a repository's workflows to their state:
+// active, disabled_manually, disabled_inactivity, ...
+func workflowStates(ctx context.Context, client *github.Client, owner, name string) (map[string]string, error) {
+	states := map[string]string{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, r, err := client.Actions.ListWorkflows(ctx, owner, name, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range list.Workflows {
+			states[w.GetPath()] = w.GetState()
+		}
+
+		if r.NextPage == 0 {
+			return states, nil
+		}
+		opts.Page = r.NextPage
+	}
+}
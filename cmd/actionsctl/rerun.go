@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/timewindow"
+)
+
+var (
+	rerunFlags       = flag.NewFlagSet("rerun", flag.ExitOnError)
+	rerunWorkflow    = rerunFlags.String("workflow", "", "Only re-run runs of this workflow, by name or file name.")
+	rerunBranch      = rerunFlags.String("branch", "", "Only re-run runs on this branch.")
+	rerunSince       = rerunFlags.String("since", "-24h", "Only re-run runs created at or after this time: "+timewindow.Formats+".")
+	rerunPattern     = rerunFlags.String("pattern", "", "Only re-run runs with a failed job whose failed step name or error annotation matches this regular expression.")
+	rerunMaxAttempts = rerunFlags.Int("max_attempts", 3, "Skip runs that already had this many attempts.")
+	rerunLimit       = rerunFlags.Int("limit", 100, "Maximum number of runs to re-run per repository.")
+)
+
+func runRerun(ctx context.Context) error {
+	repoList, err := targetRepos()
+	if err != nil {
+		return err
+	}
+
+	since, err := timewindow.Parse(*rerunSince, time.Now())
+	if err != nil {
+		return fmt.Errorf("bad -since: %w", err)
+	}
+
+	var pattern *regexp.Regexp
+	if *rerunPattern != "" {
+		if pattern, err = regexp.Compile(*rerunPattern); err != nil {
+			return fmt.Errorf("bad -pattern: %w", err)
+		}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	audit, err := newAuditLog(ctx, client, "rerun")
+	if err != nil {
+		return err
+	}
+
+	var runs []*trackedRun
+	rerunFailed := 0
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		opts := github.ListWorkflowRunsOptions{Status: "failure", Branch: *rerunBranch}
+		if !since.IsZero() {
+			opts.Created = ">=" + since.UTC().Format(time.RFC3339)
+		}
+
+		failed, err := listRuns(ctx, client, owner, name, *rerunWorkflow, opts)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for _, w := range failed {
+			if count == *rerunLimit {
+				break
+			}
+
+			if w.GetRunAttempt() >= *rerunMaxAttempts {
+				continue
+			}
+
+			if pattern != nil {
+				ok, err := failureMatches(ctx, client, owner, name, w, pattern)
+				if err != nil {
+					return err
+				}
+
+				if !ok {
+					continue
+				}
+			}
+
+			count++
+			log.Printf("%s: re-running failed jobs of %s (attempt %d): %s", reponame, w.GetName(), w.GetRunAttempt(), w.GetHTMLURL())
+			if *dryRun {
+				continue
+			}
+
+			_, rerunErr := client.Actions.RerunFailedJobsByID(ctx, owner, name, w.GetID())
+			if err := audit.record(reponame, w, rerunErr); err != nil {
+				return err
+			}
+
+			if rerunErr != nil {
+				// Keep going, and report how many failed at the end.
+				log.Printf("%s: %d: failed to re-run: %v", reponame, w.GetID(), rerunErr)
+				rerunFailed++
+			} else {
+				runs = append(runs, &trackedRun{Repository: reponame, owner: owner, name: name, run: w, minAttempt: w.GetRunAttempt() + 1})
+			}
+
+			time.Sleep(*pace)
+		}
+	}
+
+	log.Printf("rerun: re-ran %d runs, %d failed to re-run", len(runs), rerunFailed)
+
+	if err := audit.finish(ctx); err != nil {
+		return err
+	}
+
+	var failedErr error
+	if rerunFailed > 0 {
+		failedErr = fmt.Errorf("%d of %d runs failed to re-run", rerunFailed, len(runs)+rerunFailed)
+	}
+
+	if *dryRun || !*wait || len(runs) == 0 {
+		return failedErr
+	}
+
+	if err := waitForRuns(ctx, client, runs); err != nil {
+		return err
+	}
+
+	return errors.Join(failedErr, summarizeRuns(runs))
+}
+
+// listRuns returns all runs of a repository matching opts, newest first.
+// If set, workflow is the name or file name of the workflow to list runs of.
+func listRuns(ctx context.Context, client *github.Client, owner, name, workflow string, opts github.ListWorkflowRunsOptions) ([]*github.WorkflowRun, error) {
+	isFile := strings.HasSuffix(workflow, ".yml") || strings.HasSuffix(workflow, ".yaml")
+
+	var ws []*github.WorkflowRun
+	for k := 1; ; k++ {
+		opts.ListOptions = github.ListOptions{PerPage: 100, Page: k}
+
+		var runs *github.WorkflowRuns
+		var r *github.Response
+		var err error
+		if isFile {
+			runs, r, err = client.Actions.ListWorkflowRunsByFileName(ctx, owner, name, workflow, &opts)
+		} else {
+			runs, r, err = client.Actions.ListRepositoryWorkflowRuns(ctx, owner, name, &opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range runs.WorkflowRuns {
+			if isFile || workflow == "" || w.GetName() == workflow {
+				ws = append(ws, w)
+			}
+		}
+
+		log.Printf("%s/%s: got %d runs (total: %d rate_limit: %d/%d)", owner, name, len(runs.WorkflowRuns), len(ws), r.Rate.Remaining, r.Rate.Limit)
+
+		if r.NextPage == 0 {
+			return ws, nil
+		}
+	}
+}
+
+// failureMatches returns true if a failed job of the run has a failed step,
+// or an error annotation, matching pattern.
+func failureMatches(ctx context.Context, client *github.Client, owner, name string, w *github.WorkflowRun, pattern *regexp.Regexp) (bool, error) {
+	jobs, _, err := client.Actions.ListWorkflowJobs(ctx, owner, name, w.GetID(), &github.ListWorkflowJobsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, job := range jobs.Jobs {
+		if job.GetConclusion() != "failure" {
+			continue
+		}
+
+		for _, step := range job.Steps {
+			if step.GetConclusion() == "failure" && pattern.MatchString(step.GetName()) {
+				return true, nil
+			}
+		}
+
+		// A job's ID is also the ID of its check run.
+		annotations, _, err := client.Checks.ListCheckRunAnnotations(ctx, owner, name, job.GetID(), &github.ListOptions{PerPage: 100})
+		if err != nil {
+			return false, err
+		}
+
+		for _, a := range annotations {
+			if a.GetAnnotationLevel() == "failure" && pattern.MatchString(a.GetMessage()) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
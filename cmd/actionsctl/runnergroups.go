@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	runnerGroupFlags  = flag.NewFlagSet("runner-groups", flag.ExitOnError)
+	runnerGroupPolicy = runnerGroupFlags.String("policy", "", "JSON policy file describing which repositories each runner group is intended for.")
+)
+
+// runnerGroupPolicyFile is the intended exposure of an organization's runner
+// groups. E.g.:
+//
+//	{
+//	  "default": {"repositories": ["*"]},
+//	  "groups": {
+//	    "gpu": {"repositories": ["ml-*"]},
+//	    "oss": {"allow_public": true, "repositories": ["*"]}
+//	  }
+//	}
+//
+// Repositories are path.Match patterns of repository names within the org.
+// Groups without an entry are audited against "default", if set.
+type runnerGroupPolicyFile struct {
+	Default *groupPolicy            `json:"default"`
+	Groups  map[string]*groupPolicy `json:"groups"`
+}
+
+type groupPolicy struct {
+	AllowPublic  bool     `json:"allow_public"`
+	Repositories []string `json:"repositories"`
+}
+
+func (p *groupPolicy) allows(repo string) bool {
+	for _, pattern := range p.Repositories {
+		if ok, _ := path.Match(pattern, repo); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func runRunnerGroups(ctx context.Context) error {
+	if *runnerGroupPolicy == "" {
+		return fmt.Errorf("-policy is required")
+	}
+
+	orgList, err := targetOrgs()
+	if err != nil {
+		return err
+	}
+
+	contents, err := os.ReadFile(*runnerGroupPolicy)
+	if err != nil {
+		return err
+	}
+
+	var policy runnerGroupPolicyFile
+	if err := json.Unmarshal(contents, &policy); err != nil {
+		return fmt.Errorf("%s: %w", *runnerGroupPolicy, err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	findings := 0
+	report := func(org string, g *github.RunnerGroup, format string, args ...any) {
+		findings++
+		fmt.Printf("%s: runner group %q: %s\n", org, g.GetName(), fmt.Sprintf(format, args...))
+	}
+
+	for _, org := range orgList {
+		groups, err := listRunnerGroups(ctx, client, org)
+		if err != nil {
+			return err
+		}
+
+		var orgRepos []*github.Repository
+		for _, g := range groups {
+			p := policy.Groups[g.GetName()]
+			if p == nil {
+				p = policy.Default
+			}
+
+			if p == nil {
+				report(org, g, "not covered by the policy")
+				continue
+			}
+
+			var exposed []*github.Repository
+			switch g.GetVisibility() {
+			case "selected":
+				if exposed, err = listRunnerGroupRepos(ctx, client, org, g.GetID()); err != nil {
+					return err
+				}
+
+			default: // "all", or "private" for all private repositories.
+				if orgRepos == nil {
+					if orgRepos, err = ghclient.ListOrgRepos(ctx, client, org); err != nil {
+						return err
+					}
+				}
+
+				for _, r := range orgRepos {
+					if g.GetVisibility() == "all" || r.GetPrivate() {
+						exposed = append(exposed, r)
+					}
+				}
+			}
+
+			for _, f := range auditRunnerGroup(g, p, exposed) {
+				report(org, g, "%s", f)
+			}
+
+			log.Printf("%s: audited runner group %q (visibility: %s, repositories: %d)", org, g.GetName(), g.GetVisibility(), len(exposed))
+		}
+	}
+
+	if findings > 0 {
+		return fmt.Errorf("%d runner group policy violations", findings)
+	}
+
+	return nil
+}
+
+// auditRunnerGroup returns how a group's exposure to repositories violates
+// its policy, reporting each exposed repository at most once.
+func auditRunnerGroup(g *github.RunnerGroup, p *groupPolicy, exposed []*github.Repository) []string {
+	publicDenied := g.GetAllowsPublicRepositories() && !p.AllowPublic
+
+	var findings []string
+	var publicExposed bool
+	for _, r := range exposed {
+		switch {
+		case !r.GetPrivate() && publicDenied:
+			findings = append(findings, fmt.Sprintf("exposed to public repository %s", r.GetName()))
+			publicExposed = true
+		case !p.allows(r.GetName()):
+			findings = append(findings, fmt.Sprintf("exposed to %s, outside of the intended repositories", r.GetName()))
+		}
+	}
+
+	// Without public repositories to name, the setting is still a violation.
+	if publicDenied && !publicExposed {
+		findings = append(findings, "allows public repositories")
+	}
+
+	return findings
+}
+
+func listRunnerGroups(ctx context.Context, client *github.Client, org string) ([]*github.RunnerGroup, error) {
+	var groups []*github.RunnerGroup
+	opts := &github.ListOrgRunnerGroupOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		res, r, err := client.Actions.ListOrganizationRunnerGroups(ctx, org, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, res.RunnerGroups...)
+		if r.NextPage == 0 {
+			return groups, nil
+		}
+
+		opts.Page = r.NextPage
+	}
+}
+
+func listRunnerGroupRepos(ctx context.Context, client *github.Client, org string, groupID int64) ([]*github.Repository, error) {
+	var all []*github.Repository
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		res, r, err := client.Actions.ListRepositoryAccessRunnerGroup(ctx, org, groupID, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, res.Repositories...)
+		if r.NextPage == 0 {
+			return all, nil
+		}
+
+		opts.Page = r.NextPage
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+)
+
+func TestAuditRunnerGroup(t *testing.T) {
+	repos := []*github.Repository{
+		{Name: github.String("app"), Private: github.Bool(true)},
+		{Name: github.String("site"), Private: github.Bool(false)},
+		{Name: github.String("ml-train"), Private: github.Bool(true)},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		public  bool
+		policy  groupPolicy
+		exposed []*github.Repository
+		want    []string
+	}{
+		{"allowed", false, groupPolicy{Repositories: []string{"*"}}, repos, nil},
+		{"public repository listed", true, groupPolicy{Repositories: []string{"*"}}, repos, []string{"exposed to public repository site"}},
+		{"public allowed", true, groupPolicy{AllowPublic: true, Repositories: []string{"*"}}, repos, nil},
+		{"no public repository", true, groupPolicy{Repositories: []string{"*"}}, repos[:1], []string{"allows public repositories"}},
+		{"outside intended", false, groupPolicy{Repositories: []string{"ml-*"}}, repos, []string{
+			"exposed to app, outside of the intended repositories",
+			"exposed to site, outside of the intended repositories",
+		}},
+		{"public and outside intended", true, groupPolicy{Repositories: []string{"ml-*"}}, repos, []string{
+			"exposed to app, outside of the intended repositories",
+			"exposed to public repository site",
+		}},
+	} {
+		g := &github.RunnerGroup{Name: github.String("default"), AllowsPublicRepositories: github.Bool(tc.public)}
+		if got := auditRunnerGroup(g, &tc.policy, tc.exposed); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
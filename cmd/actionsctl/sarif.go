@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var sarifDir = flag.String("sarif_dir", "sarif", "With -format=sarif, the directory to write a SARIF file per repository to, for upload to code scanning.")
+
+// sarifFinding is a finding at a line of a file of a repository.
+type sarifFinding struct {
+	Repository string
+	Path       string
+	Line       int
+	Rule       string
+	Level      string // error, warning or note.
+	Message    string
+}
+
+// writeSARIF writes a SARIF file for each repository of repoList to
+// -sarif_dir, including those without findings, so that code scanning closes
+// the alerts that were fixed. rules describes each rule by ID. category keeps
+// the alerts of different commands apart.
+func writeSARIF(category string, repoList []string, rules map[string]string, findings []sarifFinding) error {
+	if err := os.MkdirAll(*sarifDir, 0o755); err != nil {
+		return err
+	}
+
+	type message struct {
+		Text string `json:"text"`
+	}
+
+	type rule struct {
+		ID               string  `json:"id"`
+		ShortDescription message `json:"shortDescription"`
+	}
+
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine int `json:"startLine"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	}
+
+	type result struct {
+		RuleID    string     `json:"ruleId"`
+		Level     string     `json:"level"`
+		Message   message    `json:"message"`
+		Locations []location `json:"locations"`
+	}
+
+	var ruleIDs []string
+	for id := range rules {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+
+	var driverRules []rule
+	for _, id := range ruleIDs {
+		driverRules = append(driverRules, rule{ID: id, ShortDescription: message{rules[id]}})
+	}
+
+	byRepo := map[string][]result{}
+	for _, f := range findings {
+		var loc location
+		loc.PhysicalLocation.ArtifactLocation.URI = f.Path
+		loc.PhysicalLocation.Region.StartLine = max(f.Line, 1)
+
+		byRepo[f.Repository] = append(byRepo[f.Repository], result{RuleID: f.Rule, Level: f.Level, Message: message{f.Message}, Locations: []location{loc}})
+	}
+
+	for _, reponame := range repoList {
+		results := byRepo[reponame]
+		if results == nil {
+			results = []result{}
+		}
+
+		doc := map[string]any{
+			"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+			"version": "2.1.0",
+			"runs": []any{map[string]any{
+				"tool":              map[string]any{"driver": map[string]any{"name": "actionsctl", "rules": driverRules}},
+				"automationDetails": map[string]any{"id": "actionsctl/" + category + "/"},
+				"results":           results,
+			}},
+		}
+
+		contents, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		p := filepath.Join(*sarifDir, strings.ReplaceAll(reponame, "/", "_")+".sarif")
+		if err := os.WriteFile(p, append(contents, '\n'), 0o644); err != nil {
+			return err
+		}
+
+		log.Printf("%s: wrote %d findings to %s", reponame, len(results), p)
+	}
+
+	return nil
+}
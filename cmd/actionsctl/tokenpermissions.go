@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+var (
+	tokenPermissionsFlags  = flag.NewFlagSet("token-permissions", flag.ExitOnError)
+	tokenPermissionsFormat = tokenPermissionsFlags.String("format", "text", "Output format: text, json, or sarif (see -sarif_dir).")
+)
+
+// actionPermissions are the GITHUB_TOKEN scopes well-known actions need.
+// Actions not listed are assumed to need none beyond reading contents.
+var actionPermissions = map[string]workflows.Permissions{
+	"actions/checkout":                      {"contents": "read"},
+	"actions/deploy-pages":                  {"pages": "write", "id-token": "write"},
+	"actions/labeler":                       {"contents": "read", "pull-requests": "write"},
+	"actions/stale":                         {"issues": "write", "pull-requests": "write"},
+	"actions/dependency-review-action":      {"contents": "read"},
+	"actions/attest-build-provenance":       {"id-token": "write", "attestations": "write"},
+	"github/codeql-action/init":             {"security-events": "write", "actions": "read"},
+	"github/codeql-action/analyze":          {"security-events": "write", "actions": "read"},
+	"github/codeql-action/upload-sarif":     {"security-events": "write"},
+	"peter-evans/create-pull-request":       {"contents": "write", "pull-requests": "write"},
+	"softprops/action-gh-release":           {"contents": "write"},
+	"dependabot/fetch-metadata":             {"pull-requests": "read"},
+	"aws-actions/configure-aws-credentials": {"id-token": "write"},
+	"google-github-actions/auth":            {"id-token": "write"},
+	"azure/login":                           {"id-token": "write"},
+}
+
+// tokenFinding is a workflow job whose GITHUB_TOKEN is granted more than it
+// likely needs.
+type tokenFinding struct {
+	Repository string                `json:"repository"`
+	Workflow   string                `json:"workflow"`
+	Job        string                `json:"job"`
+	Granted    string                `json:"granted"`
+	Issue      string                `json:"issue"`
+	Suggested  workflows.Permissions `json:"suggested"`
+	Line       int                   `json:"line"` // Where the job starts in Workflow.
+}
+
+func runTokenPermissions(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetReposOrOrgs(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var findings []tokenFinding
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		var wp workflowPermissions
+		if err := doJSON(ctx, client, http.MethodGet, fmt.Sprintf("repos/%s/%s/actions/permissions/workflow", owner, name), nil, &wp); err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+		permissiveDefault := wp.DefaultWorkflowPermissions != nil && *wp.DefaultWorkflowPermissions == "write"
+
+		files, err := workflows.Fetch(ctx, client, owner, name, "")
+		if err != nil {
+			return err
+		}
+
+		before := len(findings)
+		for _, w := range files {
+			workflowPerms := workflows.ParsePermissions(w.Permissions)
+
+			for _, id := range w.SortedJobs() {
+				job := w.Jobs[id]
+				if job.Uses != "" {
+					// Reusable workflows are audited in their own repository.
+					continue
+				}
+
+				suggested := suggestPermissions(job)
+				granted, source := workflows.ParsePermissions(job.Permissions), "job"
+				if granted == nil {
+					granted, source = workflowPerms, "workflow"
+				}
+
+				f := tokenFinding{Repository: reponame, Workflow: w.Path, Job: id, Suggested: suggested, Line: job.Line}
+				switch {
+				case granted == nil && permissiveDefault:
+					f.Granted = "repository default (write)"
+					f.Issue = "no permissions block and the repository default grants write access"
+
+				case granted == nil:
+					continue
+
+				case granted["*"] == "write":
+					f.Granted = "write-all"
+					f.Issue = fmt.Sprintf("%s grants write-all", source)
+
+				default:
+					excess := excessPermissions(granted, suggested)
+					if len(excess) == 0 {
+						continue
+					}
+					f.Granted = formatPermissions(granted)
+					f.Issue = fmt.Sprintf("%s grants %s beyond what its actions need", source, strings.Join(excess, ", "))
+				}
+
+				findings = append(findings, f)
+			}
+		}
+
+		log.Printf("%s: %d jobs with excess token permissions", reponame, len(findings)-before)
+	}
+
+	switch *tokenPermissionsFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findings); err != nil {
+			return err
+		}
+
+	case "text":
+		for _, f := range findings {
+			fmt.Printf("%s: %s / %s: %s; suggested: %s\n", f.Repository, f.Workflow, f.Job, f.Issue, formatPermissions(f.Suggested))
+		}
+
+	case "sarif":
+		var sf []sarifFinding
+		for _, f := range findings {
+			sf = append(sf, sarifFinding{
+				Repository: f.Repository,
+				Path:       f.Workflow,
+				Line:       f.Line,
+				Rule:       "excess-token-permissions",
+				Level:      "warning",
+				Message:    fmt.Sprintf("Job %s: %s; suggested: %s.", f.Job, f.Issue, formatPermissions(f.Suggested)),
+			})
+		}
+
+		rules := map[string]string{"excess-token-permissions": "Job's GITHUB_TOKEN is granted more than its actions need."}
+		if err := writeSARIF("token-permissions", repoList, rules, sf); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unsupported -format %q", *tokenPermissionsFormat)
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("%d jobs have excess token permissions", len(findings))
+	}
+
+	return nil
+}
+
+// suggestPermissions returns the minimal scopes the job's actions need.
+// Steps that call the API directly (e.g. with gh) may need more.
+func suggestPermissions(job *workflows.Job) workflows.Permissions {
+	res := workflows.Permissions{"contents": "read"}
+	for _, step := range job.Steps {
+		action := workflows.ActionName(step.Uses)
+		needs := actionPermissions[action]
+		if action == "docker/login-action" && strings.HasPrefix(step.With["registry"], "ghcr.io") {
+			needs = workflows.Permissions{"packages": "write"}
+		}
+
+		for scope, level := range needs {
+			if res[scope] != "write" {
+				res[scope] = level
+			}
+		}
+	}
+
+	return res
+}
+
+// excessPermissions returns the write grants that aren't suggested.
+func excessPermissions(granted, suggested workflows.Permissions) []string {
+	var excess []string
+	for scope, level := range granted {
+		if level == "write" && suggested[scope] != "write" {
+			excess = append(excess, scope+": write")
+		}
+	}
+
+	sort.Strings(excess)
+	return excess
+}
+
+func formatPermissions(p workflows.Permissions) string {
+	if len(p) == 0 {
+		return "{}"
+	}
+
+	var scopes []string
+	for scope, level := range p {
+		scopes = append(scopes, scope+": "+level)
+	}
+
+	sort.Strings(scopes)
+	return strings.Join(scopes, ", ")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	tokenFlags       = flag.NewFlagSet("runner-token", flag.ExitOnError)
+	tokenKind        = tokenFlags.String("kind", "registration", "Kind of token: registration or remove.")
+	tokenFormat      = tokenFlags.String("format", "shell", "Output format: shell (config.sh invocations) or json.")
+	tokenLabels      = tokenFlags.String("labels", "", "Extra labels for registered runners, separated by commas.")
+	tokenRunnerGroup = tokenFlags.String("runner_group", "", "Runner group to register organization runners in.")
+	tokenName        = tokenFlags.String("name", "", "Name of the runner to register. Defaults to the hostname.")
+	tokenURL         = tokenFlags.String("github_url", "https://github.com", "Base URL runners register against.")
+	tokenOutDir      = tokenFlags.String("out_dir", "", "Write each target's config.sh invocation to its own script in this directory, readable only by the owner, instead of stdout.")
+)
+
+// runnerToken is a minted token, and how to use it.
+type runnerToken struct {
+	Target    string    `json:"target"` // "org" or "owner/repo".
+	Kind      string    `json:"kind"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Command   string    `json:"command"`
+}
+
+func runRunnerToken(ctx context.Context) error {
+	if *tokenKind != "registration" && *tokenKind != "remove" {
+		return fmt.Errorf("unsupported -kind %q", *tokenKind)
+	}
+
+	var orgList, repoList []string
+	if *orgs != "" {
+		orgList = strings.Split(*orgs, ",")
+	}
+	if *repos != "" {
+		repoList = strings.Split(*repos, ",")
+	}
+
+	if len(orgList) == 0 && len(repoList) == 0 {
+		return fmt.Errorf("-orgs or -repos is required")
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	var tokens []runnerToken
+	for _, org := range orgList {
+		var t *github.RegistrationToken
+		if *tokenKind == "registration" {
+			t, _, err = client.Actions.CreateOrganizationRegistrationToken(ctx, org)
+		} else {
+			var rt *github.RemoveToken
+			if rt, _, err = client.Actions.CreateOrganizationRemoveToken(ctx, org); rt != nil {
+				t = &github.RegistrationToken{Token: rt.Token, ExpiresAt: rt.ExpiresAt}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", org, err)
+		}
+
+		tokens = append(tokens, newRunnerToken(org, t, true))
+	}
+
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		var t *github.RegistrationToken
+		if *tokenKind == "registration" {
+			t, _, err = client.Actions.CreateRegistrationToken(ctx, owner, name)
+		} else {
+			var rt *github.RemoveToken
+			if rt, _, err = client.Actions.CreateRemoveToken(ctx, owner, name); rt != nil {
+				t = &github.RegistrationToken{Token: rt.Token, ExpiresAt: rt.ExpiresAt}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		tokens = append(tokens, newRunnerToken(reponame, t, false))
+	}
+
+	if *tokenOutDir != "" {
+		return writeTokenScripts(*tokenOutDir, tokens)
+	}
+
+	switch *tokenFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tokens)
+
+	case "shell":
+		for _, t := range tokens {
+			fmt.Printf("# %s %s token, expires %s\n%s\n", t.Target, t.Kind, t.ExpiresAt.Format(time.RFC3339), t.Command)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported -format %q", *tokenFormat)
+	}
+}
+
+func newRunnerToken(target string, t *github.RegistrationToken, org bool) runnerToken {
+	token := runnerToken{
+		Target:    target,
+		Kind:      *tokenKind,
+		Token:     t.GetToken(),
+		ExpiresAt: t.GetExpiresAt().Time,
+	}
+
+	if *tokenKind == "remove" {
+		token.Command = fmt.Sprintf("./config.sh remove --token %s", token.Token)
+		return token
+	}
+
+	args := []string{"./config.sh", "--unattended", "--url", strings.TrimSuffix(*tokenURL, "/") + "/" + target, "--token", token.Token}
+	if *tokenName != "" {
+		args = append(args, "--name", shellQuote(*tokenName))
+	}
+	if *tokenLabels != "" {
+		args = append(args, "--labels", shellQuote(*tokenLabels))
+	}
+	if org && *tokenRunnerGroup != "" {
+		args = append(args, "--runnergroup", shellQuote(*tokenRunnerGroup))
+	}
+
+	token.Command = strings.Join(args, " ")
+	return token
+}
+
+// writeTokenScripts writes one script per token, for distribution to the
+// machines that register runners.
+func writeTokenScripts(dir string, tokens []runnerToken) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	for _, t := range tokens {
+		name := filepath.Join(dir, strings.ReplaceAll(t.Target, "/", "_")+"-"+t.Kind+".sh")
+		script := fmt.Sprintf("#!/bin/sh\n# %s %s token, expires %s\n%s\n", t.Target, t.Kind, t.ExpiresAt.Format(time.RFC3339), t.Command)
+		if err := os.WriteFile(name, []byte(script), 0o700); err != nil {
+			return err
+		}
+
+		log.Printf("%s: wrote %s", t.Target, name)
+	}
+
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
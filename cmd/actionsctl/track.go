@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// trackedRun is a workflow run that was started (or restarted) by a command,
+// and that is followed until it completes.
+type trackedRun struct {
+	Repository  string
+	owner, name string
+
+	// Until the run is known, find is called to look for it.
+	find func(context.Context) (*github.WorkflowRun, error)
+	run  *github.WorkflowRun
+
+	// Statuses of attempts before this one are stale.
+	minAttempt int
+}
+
+func (t *trackedRun) done() bool {
+	return t.run.GetStatus() == "completed" && t.run.GetRunAttempt() >= t.minAttempt
+}
+
+func (t *trackedRun) result() string {
+	switch {
+	case t.run == nil:
+		return "not found"
+	case !t.done():
+		return "pending"
+	default:
+		return t.run.GetConclusion()
+	}
+}
+
+func (t *trackedRun) refresh(ctx context.Context, client *github.Client) error {
+	if t.run == nil {
+		run, err := t.find(ctx)
+		if err != nil {
+			return err
+		}
+
+		if run != nil {
+			log.Printf("%s: tracking run: %s", t.Repository, run.GetHTMLURL())
+		}
+
+		t.run = run
+		return nil
+	}
+
+	run, _, err := client.Actions.GetWorkflowRunByID(ctx, t.owner, t.name, t.run.GetID())
+	if err != nil {
+		return err
+	}
+
+	t.run = run
+	return nil
+}
+
+// waitForRuns follows runs until they all complete, or -timeout passes.
+func waitForRuns(ctx context.Context, client *github.Client, runs []*trackedRun) error {
+	deadline := time.Now().Add(*timeout)
+	for {
+		pending := 0
+		for _, t := range runs {
+			if t.done() {
+				continue
+			}
+
+			if err := t.refresh(ctx, client); err != nil {
+				return err
+			}
+
+			if !t.done() {
+				pending++
+			}
+		}
+
+		log.Printf("%d of %d runs still pending", pending, len(runs))
+
+		if pending == 0 || time.Now().After(deadline) {
+			return nil
+		}
+
+		time.Sleep(*pollInterval)
+	}
+}
+
+// summarizeRuns prints the outcome of each run, and fails unless all succeeded.
+func summarizeRuns(runs []*trackedRun) error {
+	counts := map[string]int{}
+	for _, t := range runs {
+		counts[t.result()]++
+		fmt.Printf("%s\t%s\t%s\n", t.Repository, t.result(), t.run.GetHTMLURL())
+	}
+
+	var results []string
+	for result, n := range counts {
+		results = append(results, fmt.Sprintf("%s: %d", result, n))
+	}
+	sort.Strings(results)
+
+	log.Printf("outcomes: %s", strings.Join(results, ", "))
+
+	if failed := len(runs) - counts["success"]; failed > 0 {
+		return fmt.Errorf("%d of %d runs did not succeed", failed, len(runs))
+	}
+
+	return nil
+}
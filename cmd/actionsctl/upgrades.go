@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	upgradeToLatest = auditFlags.Bool("upgrade", true, "With -open_prs, update actions to their latest release.")
+	upgradePin      = auditFlags.Bool("pin", false, "With -open_prs, replace tag references with the commit SHAs they point to.")
+	upgradeBranch   = auditFlags.String("pr_branch", "actions-upgrade", "Branch the pull requests are opened from.")
+	upgradeTitle    = auditFlags.String("pr_title", "Update GitHub Actions", "Title of the pull requests.")
+	upgradeBody     = auditFlags.String("pr_body_template", "", "Go template file for the pull request body, executed with the Repository and its Changes (File, Action, From, To).")
+	upgradeBatch    = auditFlags.Int("pr_batch", 10, "Maximum number of pull requests to open per invocation.")
+)
+
+// actionChange is the update of an action reference within a workflow file.
+type actionChange struct {
+	File   string
+	Action string
+	From   string // Current ref.
+	To     string // New ref.
+	Note   string // The version To corresponds to, when To is a commit SHA.
+}
+
+type upgradePR struct {
+	Repository string
+	Changes    []actionChange
+}
+
+var defaultUpgradeBody = template.Must(template.New("body").Parse(`This updates the following GitHub Actions references:
+
+| File | Action | From | To |
+|---|---|---|---|
+{{range .Changes}}| {{.File}} | {{.Action}} | {{.From}} | {{.To}}{{with .Note}} ({{.}}){{end}} |
+{{end}}`))
+
+// openUpgradePRs opens a pull request per repository, updating its workflows'
+// references to the audited actions.
+func openUpgradePRs(ctx context.Context, client *github.Client, pins []*actionPin) error {
+	if !*upgradeToLatest && !*upgradePin {
+		return fmt.Errorf("-open_prs requires -upgrade or -pin")
+	}
+
+	body := defaultUpgradeBody
+	if *upgradeBody != "" {
+		contents, err := os.ReadFile(*upgradeBody)
+		if err != nil {
+			return err
+		}
+
+		if body, err = template.New("body").Parse(string(contents)); err != nil {
+			return fmt.Errorf("%s: %w", *upgradeBody, err)
+		}
+	}
+
+	prs := map[string]*upgradePR{}
+	for _, p := range pins {
+		to, note, err := targetRef(ctx, client, p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Action, err)
+		}
+
+		if to == "" || to == p.Ref {
+			continue
+		}
+
+		for _, wf := range p.Workflows {
+			reponame, file, _ := strings.Cut(wf, ": ")
+			if prs[reponame] == nil {
+				prs[reponame] = &upgradePR{Repository: reponame}
+			}
+
+			pr := prs[reponame]
+			pr.Changes = append(pr.Changes, actionChange{File: file, Action: p.Action, From: p.Ref, To: to, Note: note})
+		}
+	}
+
+	var repoList []string
+	for reponame := range prs {
+		repoList = append(repoList, reponame)
+	}
+	sort.Strings(repoList)
+
+	opened, exists := 0, 0
+	for k, reponame := range repoList {
+		if opened == *upgradeBatch {
+			log.Printf("opened %d pull requests; %d repositories left for the next batch", opened, len(repoList)-k)
+			break
+		}
+
+		pr := prs[reponame]
+		sort.Slice(pr.Changes, func(i, j int) bool {
+			return pr.Changes[i].File+pr.Changes[i].Action < pr.Changes[j].File+pr.Changes[j].Action
+		})
+
+		var b strings.Builder
+		if err := body.Execute(&b, pr); err != nil {
+			return err
+		}
+
+		log.Printf("%s: opening pull request with %d changes", reponame, len(pr.Changes))
+		if *dryRun {
+			fmt.Printf("%s:\n%s\n", reponame, b.String())
+			continue
+		}
+
+		url, err := openUpgradePR(ctx, client, pr, b.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		if url == "" {
+			exists++
+			continue
+		}
+
+		log.Printf("%s: opened %s", reponame, url)
+		opened++
+		time.Sleep(*pace)
+	}
+
+	if exists > 0 {
+		log.Printf("skipped %d repositories where branch %s already exists", exists, *upgradeBranch)
+	}
+
+	return nil
+}
+
+// targetRef returns the ref an action should be updated to, and the version
+// it corresponds to if it's a commit SHA.
+func targetRef(ctx context.Context, client *github.Client, p *actionPin) (string, string, error) {
+	version := p.Ref
+	if *upgradeToLatest && strings.HasSuffix(p.Behind, " behind") {
+		version = p.Latest
+	}
+
+	if !*upgradePin {
+		if shaRef.MatchString(p.Ref) && version != p.Ref {
+			// Keep pinned references pinned.
+			return p.release.latestSHA, version, nil
+		}
+		return version, "", nil
+	}
+
+	if shaRef.MatchString(version) {
+		return version, "", nil
+	}
+
+	owner, repo, _ := strings.Cut(p.Action, "/")
+	repo, _, _ = strings.Cut(repo, "/")
+
+	sha, _, err := client.Repositories.GetCommitSHA1(ctx, owner, repo, version, "")
+	if err != nil {
+		return "", "", err
+	}
+
+	return sha, version, nil
+}
+
+// openUpgradePR pushes the changes to -pr_branch and opens a pull request from
+// it, returning its URL, or "" if the branch already exists. The branch is
+// deleted again if the pull request can't be opened, so that the next
+// invocation retries rather than skips the repository.
+func openUpgradePR(ctx context.Context, client *github.Client, pr *upgradePR, body string) (url string, err error) {
+	owner, name, err := ghclient.SplitRepo(pr.Repository)
+	if err != nil {
+		return "", err
+	}
+
+	repo, _, err := client.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		return "", err
+	}
+
+	base, _, err := client.Git.GetRef(ctx, owner, name, "heads/"+repo.GetDefaultBranch())
+	if err != nil {
+		return "", err
+	}
+
+	if _, _, err := client.Git.GetRef(ctx, owner, name, "heads/"+*upgradeBranch); err == nil {
+		log.Printf("%s: branch %s already exists, skipping", pr.Repository, *upgradeBranch)
+		return "", nil
+	} else if !ghclient.IsNotFound(err) {
+		return "", err
+	}
+
+	if _, _, err := client.Git.CreateRef(ctx, owner, name, &github.Reference{
+		Ref:    github.String("refs/heads/" + *upgradeBranch),
+		Object: &github.GitObject{SHA: base.Object.SHA},
+	}); err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if _, derr := client.Git.DeleteRef(context.WithoutCancel(ctx), owner, name, "heads/"+*upgradeBranch); derr != nil {
+			log.Printf("%s: failed to delete branch %s: %v", pr.Repository, *upgradeBranch, derr)
+		}
+	}()
+
+	byFile := map[string][]actionChange{}
+	for _, c := range pr.Changes {
+		byFile[c.File] = append(byFile[c.File], c)
+	}
+
+	for file, changes := range byFile {
+		f, _, _, err := client.Repositories.GetContents(ctx, owner, name, file, &github.RepositoryContentGetOptions{Ref: *upgradeBranch})
+		if err != nil {
+			return "", err
+		}
+
+		contents, err := f.GetContent()
+		if err != nil {
+			return "", err
+		}
+
+		updated := contents
+		for _, c := range changes {
+			updated = replaceActionRef(updated, c)
+		}
+
+		if updated == contents {
+			continue
+		}
+
+		if _, _, err := client.Repositories.UpdateFile(ctx, owner, name, file, &github.RepositoryContentFileOptions{
+			Message: github.String("Update GitHub Actions in " + file),
+			Content: []byte(updated),
+			SHA:     f.SHA,
+			Branch:  upgradeBranch,
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	created, _, err := client.PullRequests.Create(ctx, owner, name, &github.NewPullRequest{
+		Title: upgradeTitle,
+		Head:  upgradeBranch,
+		Base:  repo.DefaultBranch,
+		Body:  &body,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return created.GetHTMLURL(), nil
+}
+
+// replaceActionRef rewrites `uses:` lines referencing c.Action at c.From,
+// replacing any trailing comment with the version a SHA corresponds to.
+func replaceActionRef(contents string, c actionChange) string {
+	re := regexp.MustCompile(`(?m)(uses:\s*["']?)` + regexp.QuoteMeta(c.Action+"@"+c.From) + `(["']?)([ \t]*#.*)?$`)
+
+	replacement := "${1}" + c.Action + "@" + c.To + "${2}"
+	if c.Note != "" {
+		replacement += " # " + c.Note
+	}
+
+	return re.ReplaceAllString(contents, replacement)
+}
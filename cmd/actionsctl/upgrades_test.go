@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+)
+
+func TestOpenUpgradePRDeletesBranchOnFailure(t *testing.T) {
+	workflow := base64.StdEncoding.EncodeToString([]byte("steps:\n  - uses: actions/checkout@v3\n"))
+
+	var deleted bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/acme/app":
+			fmt.Fprint(w, `{"default_branch":"main"}`)
+		case "GET /repos/acme/app/git/ref/heads/main":
+			fmt.Fprint(w, `{"ref":"refs/heads/main","object":{"sha":"abc"}}`)
+		case "GET /repos/acme/app/git/ref/heads/actions-upgrade":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+		case "POST /repos/acme/app/git/refs":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"ref":"refs/heads/actions-upgrade","object":{"sha":"abc"}}`)
+		case "GET /repos/acme/app/contents/.github/workflows/ci.yml":
+			fmt.Fprintf(w, `{"type":"file","encoding":"base64","content":%q,"sha":"def"}`, workflow)
+		case "PUT /repos/acme/app/contents/.github/workflows/ci.yml":
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"message":"conflict"}`)
+		case "DELETE /repos/acme/app/git/refs/heads/actions-upgrade":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	pr := &upgradePR{
+		Repository: "acme/app",
+		Changes:    []actionChange{{File: ".github/workflows/ci.yml", Action: "actions/checkout", From: "v3", To: "v4"}},
+	}
+
+	if _, err := openUpgradePR(context.Background(), client, pr, ""); err == nil {
+		t.Fatal("want an error when the file can't be updated")
+	}
+
+	if !deleted {
+		t.Error("the branch wasn't deleted")
+	}
+}
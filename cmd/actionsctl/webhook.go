@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+var (
+	webhookFlags  = flag.NewFlagSet("webhook", flag.ExitOnError)
+	webhookURL    = webhookFlags.String("url", "", "URL of the webhook receiver to deliver events of -orgs to.")
+	webhookEvents = webhookFlags.String("events", "workflow_run,workflow_job", "Events to deliver, separated by commas.")
+	webhookVerify = webhookFlags.Duration("verify_timeout", time.Minute, "How long to wait for the ping to be delivered; 0 skips verifying it.")
+)
+
+// runWebhook creates the webhook of each of -orgs that delivers to -url, or
+// updates it if it exists, and pings it. The secret is read from
+// WEBHOOK_SECRET, so that it doesn't show up in process listings.
+func runWebhook(ctx context.Context) error {
+	if *webhookURL == "" {
+		return errors.New("-url is required")
+	}
+
+	secret := os.Getenv("WEBHOOK_SECRET")
+	if secret == "" {
+		return errors.New("WEBHOOK_SECRET is required")
+	}
+
+	orgList, err := targetOrgs()
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	hook := &github.Hook{
+		Config: map[string]any{
+			"url":          *webhookURL,
+			"content_type": "json",
+			"secret":       secret,
+			"insecure_ssl": "0",
+		},
+		Events: strings.Split(*webhookEvents, ","),
+		Active: github.Bool(true),
+	}
+
+	for _, org := range orgList {
+		existing, err := findOrgHook(ctx, client, org, *webhookURL)
+		if err != nil {
+			return fmt.Errorf("%s: %w", org, err)
+		}
+
+		if *dryRun {
+			if existing != nil {
+				log.Printf("%s: would update webhook %d (events %v)", org, existing.GetID(), hook.Events)
+			} else {
+				log.Printf("%s: would create webhook for %s (events %v)", org, *webhookURL, hook.Events)
+			}
+			continue
+		}
+
+		var h *github.Hook
+		if existing != nil {
+			h, _, err = client.Organizations.EditHook(ctx, org, existing.GetID(), hook)
+			log.Printf("%s: updating webhook %d", org, existing.GetID())
+		} else {
+			h, _, err = client.Organizations.CreateHook(ctx, org, hook)
+			log.Printf("%s: creating webhook for %s", org, *webhookURL)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", org, err)
+		}
+
+		if err := pingOrgHook(ctx, client, org, h.GetID()); err != nil {
+			return fmt.Errorf("%s: webhook %d: %w", org, h.GetID(), err)
+		}
+	}
+
+	return nil
+}
+
+// findOrgHook returns the webhook of org that delivers to url, if any.
+func findOrgHook(ctx context.Context, client *github.Client, org, url string) (*github.Hook, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		hooks, resp, err := client.Organizations.ListHooks(ctx, org, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, h := range hooks {
+			if u, _ := h.Config["url"].(string); u == url {
+				return h, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// pingOrgHook pings a webhook and, unless -verify_timeout is 0, waits for the
+// ping to be delivered and checks that the receiver accepted it.
+func pingOrgHook(ctx context.Context, client *github.Client, org string, id int64) error {
+	pinged := time.Now().Add(-time.Minute) // Allow for clock skew.
+	if _, err := client.Organizations.PingHook(ctx, org, id); err != nil {
+		return err
+	}
+
+	if *webhookVerify == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(*webhookVerify)
+	for time.Now().Before(deadline) {
+		time.Sleep(2 * time.Second)
+
+		deliveries, _, err := client.Organizations.ListHookDeliveries(ctx, org, id, &github.ListCursorOptions{PerPage: 10})
+		if err != nil {
+			return err
+		}
+
+		for _, d := range deliveries {
+			if d.GetEvent() != "ping" || d.GetDeliveredAt().Before(pinged) {
+				continue
+			}
+
+			if code := d.GetStatusCode(); code < 200 || code >= 300 {
+				return fmt.Errorf("ping delivery %d failed: %d %s", d.GetID(), code, d.GetStatus())
+			}
+
+			log.Printf("%s: webhook %d received the ping", org, id)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("ping wasn't delivered within %v", *webhookVerify)
+}
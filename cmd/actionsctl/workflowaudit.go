@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+var (
+	auditFlags   = flag.NewFlagSet("workflow-audit", flag.ExitOnError)
+	auditFormat  = auditFlags.String("format", "text", "Output format: text, json, or sarif (see -sarif_dir).")
+	auditOpenPRs = auditFlags.Bool("open_prs", false, "Open pull requests that update the audited action references (honors -dry_run).")
+)
+
+// actionPin is a version of a third-party action that workflows reference.
+type actionPin struct {
+	Action     string   `json:"action"` // owner/repo, plus a path for actions in subdirectories.
+	Ref        string   `json:"ref"`
+	Version    string   `json:"version"` // Ref, or the tag it resolves to if it's a commit SHA.
+	Latest     string   `json:"latest"`
+	Behind     string   `json:"behind"`
+	References int      `json:"references"`
+	Workflows  []string `json:"workflows"` // "owner/repo: path".
+	Using      string   `json:"using"`     // Runtime of the action, e.g. node20 or composite.
+
+	release *actionRelease
+	uses    []actionUse
+}
+
+// actionUse is where a workflow references an action.
+type actionUse struct {
+	repository, path string
+	line             int
+}
+
+var shaRef = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// deprecatedRuntimes are the runtimes GitHub has deprecated for actions.
+var deprecatedRuntimes = map[string]bool{"node12": true, "node16": true, "node20": true}
+
+var auditRules = map[string]string{
+	"unpinned-action":    "Action isn't pinned to a commit SHA, so the code it runs can change without review.",
+	"outdated-action":    "Action is a major version or more behind its latest release.",
+	"deprecated-runtime": "Action runs on a Node.js runtime that GitHub has deprecated.",
+}
+
+func runWorkflowAudit(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetReposOrOrgs(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	pins, err := collectActionPins(ctx, client, repoList)
+	if err != nil {
+		return err
+	}
+
+	if *auditOpenPRs {
+		return openUpgradePRs(ctx, client, pins)
+	}
+
+	switch *auditFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(pins)
+
+	case "text":
+		for _, p := range pins {
+			runtime := ""
+			if deprecatedRuntimes[p.Using] {
+				runtime = fmt.Sprintf(", runs on deprecated %s", p.Using)
+			}
+			fmt.Printf("%s@%s: %s (latest %s)%s, %d references\n", p.Action, p.Ref, p.Behind, p.Latest, runtime, p.References)
+		}
+		return nil
+
+	case "sarif":
+		return writeSARIF("workflow-audit", repoList, auditRules, auditFindings(pins))
+
+	default:
+		return fmt.Errorf("unsupported -format %q", *auditFormat)
+	}
+}
+
+// collectActionPins returns the third-party action versions used across the
+// workflows of repoList, compared with each action's latest release. The most
+// referenced come first.
+func collectActionPins(ctx context.Context, client *github.Client, repoList []string) ([]*actionPin, error) {
+	owners := map[string]bool{}
+	for _, reponame := range repoList {
+		owner, _, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return nil, err
+		}
+		owners[strings.ToLower(owner)] = true
+	}
+
+	pins := map[string]*actionPin{}
+	for _, reponame := range repoList {
+		owner, name, _ := ghclient.SplitRepo(reponame)
+
+		files, err := workflows.Fetch(ctx, client, owner, name, "")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range files {
+			for _, id := range w.SortedJobs() {
+				job := w.Jobs[id]
+				uses := append([]workflows.Step{{Uses: job.Uses, Line: job.Line}}, job.Steps...)
+
+				for _, step := range uses {
+					u := step.Uses
+					action, ref, ok := strings.Cut(u, "@")
+					if !ok || strings.HasPrefix(u, "./") || strings.HasPrefix(u, "docker://") {
+						continue
+					}
+
+					actionOwner, _, _ := strings.Cut(action, "/")
+					if owners[strings.ToLower(actionOwner)] {
+						continue
+					}
+
+					p := pins[u]
+					if p == nil {
+						p = &actionPin{Action: action, Ref: ref, Version: ref}
+						pins[u] = p
+					}
+
+					p.References++
+					p.uses = append(p.uses, actionUse{repository: reponame, path: w.Path, line: step.Line})
+					if wf := reponame + ": " + w.Path; !slices.Contains(p.Workflows, wf) {
+						p.Workflows = append(p.Workflows, wf)
+					}
+				}
+			}
+		}
+
+		log.Printf("%s: scanned %d workflows", reponame, len(files))
+	}
+
+	latest := map[string]*actionRelease{}
+	var res []*actionPin
+	for _, p := range pins {
+		actionOwner, actionRepo, _ := strings.Cut(p.Action, "/")
+		actionRepo, _, _ = strings.Cut(actionRepo, "/")
+
+		key := actionOwner + "/" + actionRepo
+		rel := latest[key]
+		if rel == nil {
+			var err error
+			if rel, err = fetchActionRelease(ctx, client, actionOwner, actionRepo); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			latest[key] = rel
+		}
+
+		if shaRef.MatchString(p.Ref) {
+			if tag := rel.tagsBySHA[p.Ref]; tag != "" {
+				p.Version = tag
+			}
+		}
+
+		if ext := path.Ext(p.Action); ext != ".yml" && ext != ".yaml" {
+			dir := strings.TrimPrefix(strings.TrimPrefix(p.Action, key), "/")
+			def, err := workflows.FetchAction(ctx, client, actionOwner, actionRepo, dir, p.Ref)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p.Action, err)
+			}
+			if def != nil {
+				p.Using = def.Runs.Using
+			}
+		}
+
+		p.release = rel
+		p.Latest = rel.latest
+		p.Behind = versionsBehind(p.Version, rel.latest)
+		res = append(res, p)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].References != res[j].References {
+			return res[i].References > res[j].References
+		}
+		return res[i].Action+"@"+res[i].Ref < res[j].Action+"@"+res[j].Ref
+	})
+
+	return res, nil
+}
+
+// auditFindings returns a finding for each reference to an action that isn't
+// pinned to a commit, is a major version behind, or runs on a deprecated
+// runtime.
+func auditFindings(pins []*actionPin) []sarifFinding {
+	var res []sarifFinding
+	for _, p := range pins {
+		for _, u := range p.uses {
+			add := func(rule, level, message string) {
+				res = append(res, sarifFinding{Repository: u.repository, Path: u.path, Line: u.line, Rule: rule, Level: level, Message: message})
+			}
+
+			if !shaRef.MatchString(p.Ref) {
+				add("unpinned-action", "warning", fmt.Sprintf("%s@%s isn't pinned to a commit SHA.", p.Action, p.Ref))
+			}
+			if strings.Contains(p.Behind, "major") {
+				add("outdated-action", "note", fmt.Sprintf("%s@%s is %s (latest %s).", p.Action, p.Ref, p.Behind, p.Latest))
+			}
+			if deprecatedRuntimes[p.Using] {
+				add("deprecated-runtime", "warning", fmt.Sprintf("%s@%s runs on %s, which GitHub has deprecated.", p.Action, p.Ref, p.Using))
+			}
+		}
+	}
+
+	return res
+}
+
+type actionRelease struct {
+	latest    string            // Tag of the latest release.
+	latestSHA string            // Commit the latest release's tag points to.
+	tagsBySHA map[string]string // Most recent tags, by commit.
+}
+
+func fetchActionRelease(ctx context.Context, client *github.Client, owner, name string) (*actionRelease, error) {
+	rel := &actionRelease{tagsBySHA: map[string]string{}}
+
+	tags, _, err := client.Repositories.ListTags(ctx, owner, name, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tags {
+		sha := t.GetCommit().GetSHA()
+		// Prefer the most specific tag (v1.2.3 over v1) for a commit.
+		if current := rel.tagsBySHA[sha]; current == "" || len(t.GetName()) > len(current) {
+			rel.tagsBySHA[sha] = t.GetName()
+		}
+	}
+
+	r, _, err := client.Repositories.GetLatestRelease(ctx, owner, name)
+	switch {
+	case err == nil:
+		rel.latest = r.GetTagName()
+
+	case ghclient.IsNotFound(err):
+		// No releases; fall back to the highest version tag.
+		for _, t := range tags {
+			if rel.latest == "" || compareVersions(t.GetName(), rel.latest) > 0 {
+				rel.latest = t.GetName()
+			}
+		}
+
+	default:
+		return nil, err
+	}
+
+	for _, t := range tags {
+		if t.GetName() == rel.latest {
+			rel.latestSHA = t.GetCommit().GetSHA()
+		}
+	}
+
+	return rel, nil
+}
+
+// parseVersion returns the numeric components of a version like v1.2.3.
+func parseVersion(v string) []int {
+	var res []int
+	for _, part := range strings.Split(strings.TrimPrefix(v, "v"), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return res
+		}
+		res = append(res, n)
+	}
+
+	return res
+}
+
+func compareVersions(a, b string) int {
+	va, vb := parseVersion(a), parseVersion(b)
+	for k := 0; k < len(va) && k < len(vb); k++ {
+		if va[k] != vb[k] {
+			return va[k] - vb[k]
+		}
+	}
+
+	return len(va) - len(vb)
+}
+
+// versionsBehind describes how far version is behind latest. A floating tag
+// like v4 is current as long as latest is a v4 release.
+func versionsBehind(version, latest string) string {
+	v, l := parseVersion(version), parseVersion(latest)
+	if len(v) == 0 || len(l) == 0 {
+		return "unknown"
+	}
+
+	for k, name := range []string{"major", "minor", "patch"} {
+		if k >= len(v) || k >= len(l) {
+			return "current"
+		}
+
+		if v[k] == l[k]-1 {
+			return fmt.Sprintf("1 %s version behind", name)
+		}
+		if v[k] < l[k] {
+			return fmt.Sprintf("%d %s versions behind", l[k]-v[k], name)
+		}
+
+		if v[k] > l[k] {
+			return "ahead of the latest release"
+		}
+	}
+
+	return "current"
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int // Its sign.
+	}{
+		{"v4", "v4", 0},
+		{"v4.1.0", "v4.1.0", 0},
+		{"v3", "v4", -1},
+		{"v4.10.0", "v4.9.2", 1},
+		{"v4", "v4.1.0", -1},
+		{"1.2.3", "v1.2.3", 0},
+	} {
+		got := compareVersions(tc.a, tc.b)
+		if (got < 0) != (tc.want < 0) || (got > 0) != (tc.want > 0) {
+			t.Errorf("compareVersions(%q, %q) = %d, want the sign of %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestVersionsBehind(t *testing.T) {
+	for _, tc := range []struct {
+		version, latest, want string
+	}{
+		{"v4", "v4.2.1", "current"},
+		{"v4.2.1", "v4.2.1", "current"},
+		{"v3", "v4.2.1", "1 major version behind"},
+		{"v4.0", "v4.2.1", "2 minor versions behind"},
+		{"v4.2.0", "v4.2.1", "1 patch version behind"},
+		{"v5", "v4.2.1", "ahead of the latest release"},
+		{"main", "v4.2.1", "unknown"},
+		{"v4", "", "unknown"},
+	} {
+		if got := versionsBehind(tc.version, tc.latest); got != tc.want {
+			t.Errorf("versionsBehind(%q, %q) = %q, want %q", tc.version, tc.latest, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+var (
+	approvalsFlags        = flag.NewFlagSet("approvals", flag.ExitOnError)
+	approvalsMinWait      = approvalsFlags.Duration("min_wait", time.Minute, "Fork pull request runs that started at least this long after being created count as having waited for approval.")
+	approvalsAbandonAfter = approvalsFlags.Duration("abandon_after", 7*24*time.Hour, "Runs awaiting approval for longer than this count as never approved.")
+)
+
+// approvalStats describes how long runs of first-time contributors' pull
+// requests wait for a maintainer to approve them. GitHub doesn't record when
+// a run was approved, but an approved run only starts then, so the wait is
+// the time between the run's creation and its start.
+type approvalStats struct {
+	Repository string
+	ForkRuns   int             // First attempts of pull_request runs from forks.
+	Approved   []time.Duration // Waits of runs that were approved.
+	Awaiting   int             // Runs currently awaiting approval.
+	Abandoned  int             // Of those, runs awaiting for longer than -abandon_after.
+	Oldest     time.Duration   // Age of the oldest run awaiting approval.
+}
+
+func (s approvalStats) Median() time.Duration {
+	return percentile(s.Approved, 0.5)
+}
+
+func (s approvalStats) P90() time.Duration {
+	return percentile(s.Approved, 0.9)
+}
+
+// percentile returns the p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+func runApprovals(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var stats []approvalStats
+	for _, reponame := range repoList {
+		s := approvalStats{Repository: reponame}
+
+		runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{Event: "pull_request"}, *runCount)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range runs {
+			if !isForkRun(w) || w.GetRunAttempt() > 1 || w.GetStatus() == "action_required" {
+				continue
+			}
+
+			s.ForkRuns++
+			if wait := w.GetRunStartedAt().Sub(w.GetCreatedAt().Time); wait >= *approvalsMinWait {
+				s.Approved = append(s.Approved, wait)
+			}
+		}
+
+		awaiting, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{Status: "action_required"}, *runCount)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range awaiting {
+			age := time.Since(w.GetCreatedAt().Time)
+
+			s.Awaiting++
+			s.Oldest = max(s.Oldest, age)
+			if age > *approvalsAbandonAfter {
+				s.Abandoned++
+			}
+		}
+
+		sort.Slice(s.Approved, func(i, j int) bool { return s.Approved[i] < s.Approved[j] })
+
+		log.Printf("%s: %d fork runs, %d waited for approval, %d awaiting approval", reponame, s.ForkRuns, len(s.Approved), s.Awaiting)
+		stats = append(stats, s)
+	}
+
+	return approvalsMarkdown.Execute(os.Stdout, stats)
+}
+
+func isForkRun(w *github.WorkflowRun) bool {
+	return w.GetHeadRepository().GetFullName() != w.GetRepository().GetFullName()
+}
+
+var approvalsMarkdown = template.Must(template.New("approvals").Funcs(template.FuncMap{
+	"round": func(d time.Duration) time.Duration { return d.Round(time.Minute) },
+}).Parse(`# Run approval latency
+
+| Repository | Fork PR runs | Waited for approval | Median wait | p90 wait | Awaiting approval | Never approved | Oldest awaiting |
+|---|---:|---:|---:|---:|---:|---:|---:|
+{{range .}}| {{.Repository}} | {{.ForkRuns}} | {{len .Approved}} | {{round .Median}} | {{round .P90}} | {{.Awaiting}} | {{.Abandoned}} | {{round .Oldest}} |
+{{end}}`))
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	arcManifests = flag.String("arc_manifests", "", "Glob of Kubernetes manifests with ARC AutoscalingRunnerSets whose minRunners and maxRunners "+
+		"are set from the demand observed for their scale set name: the median and -capacity_percentile concurrency.")
+	arcOut = flag.String("arc_out", "", "Directory the patched -arc_manifests are written to.")
+)
+
+// patchARCManifests writes each -arc_manifests file to -arc_out, with the
+// runner counts of its AutoscalingRunnerSets set from the observed demand.
+func patchARCManifests(records []jobRecord) error {
+	if *arcOut == "" {
+		return errors.New("-arc_out is required with -arc_manifests")
+	}
+
+	files, err := filepath.Glob(*arcManifests)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*arcOut, 0755); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		var docs []*yaml.Node
+		dec := yaml.NewDecoder(bytes.NewReader(contents))
+		for {
+			var doc yaml.Node
+			if err := dec.Decode(&doc); err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			docs = append(docs, &doc)
+		}
+
+		for _, doc := range docs {
+			if len(doc.Content) == 0 {
+				continue
+			}
+
+			root := doc.Content[0]
+			spec := mappingValue(root, "spec")
+			if mappingValue(root, "kind").Value != "AutoscalingRunnerSet" || spec.Kind != yaml.MappingNode {
+				continue
+			}
+
+			scaleSet := mappingValue(spec, "runnerScaleSetName").Value
+			if scaleSet == "" {
+				scaleSet = mappingValue(mappingValue(root, "metadata"), "name").Value
+			}
+
+			// Jobs target a scale set by its name alone.
+			capacity := recommendCapacity(records, func(r jobRecord) bool { return r.Label() == scaleSet })
+			if len(capacity) == 0 {
+				log.Printf("%s: %s: no jobs ran on it, left unchanged", file, scaleSet)
+				continue
+			}
+
+			c := capacity[0]
+			minRunners, maxRunners := c.Median, max(c.Recommended, 1)
+			setMappingValue(spec, "minRunners", minRunners)
+			setMappingValue(spec, "maxRunners", maxRunners)
+			log.Printf("%s: %s: %d jobs, peak %d: minRunners %d, maxRunners %d", file, scaleSet, c.Jobs, c.Peak, minRunners, maxRunners)
+		}
+
+		var b bytes.Buffer
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		for _, doc := range docs {
+			if err := enc.Encode(doc); err != nil {
+				return err
+			}
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(*arcOut, filepath.Base(file)), b.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mappingValue returns the value of key in a mapping node, or an empty node.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind == yaml.MappingNode {
+		for k := 0; k+1 < len(n.Content); k += 2 {
+			if n.Content[k].Value == key {
+				return n.Content[k+1]
+			}
+		}
+	}
+
+	return &yaml.Node{}
+}
+
+func setMappingValue(n *yaml.Node, key string, value int) {
+	v := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(value)}
+	for k := 0; k+1 < len(n.Content); k += 2 {
+		if n.Content[k].Value == key {
+			n.Content[k+1] = v
+			return
+		}
+	}
+
+	n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v58/github"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+var (
+	artifactsFlags    = flag.NewFlagSet("artifacts", flag.ExitOnError)
+	artifactsMinShare = artifactsFlags.Float64("min_share", 0.2, "Flag workflows spending at least this share of their job time uploading and downloading artifacts.")
+)
+
+// artifactActions are the step actions whose time counts as artifact transfer.
+var artifactActions = map[string]string{
+	"actions/upload-artifact":   "upload",
+	"actions/download-artifact": "download",
+}
+
+// artifactUsage is the time a workflow's jobs spend moving artifacts around,
+// and how much data they move.
+type artifactUsage struct {
+	Repository      string
+	Workflow        string
+	Runs            int
+	JobMinutes      float64 // Wall-clock minutes of all of the runs' jobs.
+	UploadMinutes   float64
+	DownloadMinutes float64
+	Uploads         int
+	Downloads       int
+	Bytes           int64 // Size of the artifacts the runs produced.
+}
+
+func (a artifactUsage) Share() float64 {
+	if a.JobMinutes == 0 {
+		return 0
+	}
+
+	return (a.UploadMinutes + a.DownloadMinutes) / a.JobMinutes
+}
+
+func (a artifactUsage) SharePercent() float64 {
+	return 100 * a.Share()
+}
+
+func (a artifactUsage) Dominates() bool {
+	return a.Share() >= *artifactsMinShare
+}
+
+func (a artifactUsage) MBPerRun() float64 {
+	if a.Runs == 0 {
+		return 0
+	}
+
+	return float64(a.Bytes) / float64(a.Runs) / (1 << 20)
+}
+
+func runArtifacts(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var res []*artifactUsage
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		files, err := workflows.Fetch(ctx, client, owner, name, "")
+		if err != nil {
+			return err
+		}
+
+		// Steps are matched to runs by workflow and step name.
+		kinds := map[string]string{}
+		for _, w := range files {
+			for _, id := range w.SortedJobs() {
+				for _, step := range w.Jobs[id].Steps {
+					if kind := artifactActions[workflows.ActionName(step.Uses)]; kind != "" {
+						kinds[w.DisplayName()+"|"+step.StepName()] = kind
+					}
+				}
+			}
+		}
+
+		runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{Status: "completed"}, *runCount)
+		if err != nil {
+			return err
+		}
+
+		byWorkflow := map[string]*artifactUsage{}
+		for _, w := range runs {
+			jobs, _, err := fetchJobs(ctx, client, w, *maxJobs)
+			if err != nil {
+				return err
+			}
+
+			a := byWorkflow[w.GetName()]
+			if a == nil {
+				a = &artifactUsage{Repository: reponame, Workflow: w.GetName()}
+				byWorkflow[w.GetName()] = a
+			}
+
+			transfers := 0
+			for _, job := range jobs {
+				if job.StartedAt == nil || job.CompletedAt == nil {
+					continue
+				}
+
+				a.JobMinutes += job.CompletedAt.Sub(job.StartedAt.Time).Minutes()
+				for _, s := range newStepRecords(job.Steps) {
+					switch artifactStepKind(kinds, w.GetName(), s.Name) {
+					case "upload":
+						a.Uploads++
+						a.UploadMinutes += s.End.Sub(s.Start).Minutes()
+						transfers++
+					case "download":
+						a.Downloads++
+						a.DownloadMinutes += s.End.Sub(s.Start).Minutes()
+						transfers++
+					}
+				}
+			}
+
+			a.Runs++
+			if transfers == 0 {
+				continue
+			}
+
+			opts := &github.ListOptions{PerPage: 100}
+			for {
+				artifacts, r, err := client.Actions.ListWorkflowRunArtifacts(ctx, owner, name, w.GetID(), opts)
+				if err != nil {
+					return err
+				}
+
+				for _, artifact := range artifacts.Artifacts {
+					a.Bytes += artifact.GetSizeInBytes()
+				}
+
+				if r.NextPage == 0 {
+					break
+				}
+				opts.Page = r.NextPage
+			}
+		}
+
+		for _, a := range byWorkflow {
+			if a.Uploads+a.Downloads > 0 {
+				log.Printf("%s: %s: %.0f%% of job time spent on %d uploads and %d downloads", reponame, a.Workflow, a.SharePercent(), a.Uploads, a.Downloads)
+				res = append(res, a)
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Share() != res[j].Share() {
+			return res[i].Share() > res[j].Share()
+		}
+		return res[i].Repository+res[i].Workflow < res[j].Repository+res[j].Workflow
+	})
+
+	return artifactsMarkdown.Execute(os.Stdout, res)
+}
+
+// artifactStepKind returns whether a step uploads or downloads artifacts,
+// either because the workflow says so or from the name GitHub gives unnamed
+// steps.
+func artifactStepKind(kinds map[string]string, workflow, step string) string {
+	if kind := kinds[workflow+"|"+step]; kind != "" {
+		return kind
+	}
+
+	if uses, ok := strings.CutPrefix(step, "Run "); ok {
+		return artifactActions[workflows.ActionName(uses)]
+	}
+
+	return ""
+}
+
+var artifactsMarkdown = template.Must(template.New("artifacts").Funcs(digestFuncs).Parse(`# Artifact transfer time
+
+| Repository | Workflow | Runs | Uploads | Upload minutes | Downloads | Download minutes | Share of job time | MB per run | |
+|---|---|---:|---:|---:|---:|---:|---:|---:|---|
+{{range .}}| {{.Repository}} | {{.Workflow}} | {{.Runs}} | {{.Uploads}} | {{minutes .UploadMinutes}} | {{.Downloads}} | {{minutes .DownloadMinutes}} | {{printf "%.1f%%" .SharePercent}} | {{printf "%.1f" .MBPerRun}} | {{if .Dominates}}artifact transfers dominate{{end}} |
+{{end}}`))
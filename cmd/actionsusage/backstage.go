@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"gopkg.in/yaml.v3"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	backstageCatalog = flag.String("backstage_catalog", "", "Path of the Backstage catalog file in each repository, e.g. catalog-info.yaml; "+
+		"usage is then attributed to the Component, System and owner it declares.")
+	backstageMetrics = flag.String("backstage_metrics", "", "With -backstage_catalog, write a JSON metrics payload per Backstage component to this file.")
+)
+
+// catalogEntity is the Backstage entity a repository's usage is attributed to.
+type catalogEntity struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Owner  string `yaml:"owner"`
+		System string `yaml:"system"`
+	} `yaml:"spec"`
+}
+
+// Ref is the entity's reference, e.g. component:default/foo.
+func (e catalogEntity) Ref() string {
+	namespace := e.Metadata.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	return fmt.Sprintf("%s:%s/%s", strings.ToLower(e.Kind), namespace, e.Metadata.Name)
+}
+
+// fetchCatalogEntities maps repositories to the first Component declared in
+// their -backstage_catalog file. Repositories without one are left out.
+func fetchCatalogEntities(ctx context.Context, client *github.Client, repoList []string) (map[string]catalogEntity, error) {
+	res := map[string]catalogEntity{}
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return nil, err
+		}
+
+		file, _, _, err := client.Repositories.GetContents(ctx, owner, name, *backstageCatalog, nil)
+		if err != nil {
+			if ghclient.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		contents, err := file.GetContent()
+		if err != nil {
+			return nil, err
+		}
+
+		dec := yaml.NewDecoder(strings.NewReader(contents))
+		for {
+			var e catalogEntity
+			if err := dec.Decode(&e); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", reponame, *backstageCatalog, err)
+			}
+
+			if e.Kind == "Component" {
+				res[strings.ToLower(reponame)] = e
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// componentMetrics is the CI usage of a Backstage component.
+type componentMetrics struct {
+	EntityRef string  `json:"entityRef"`
+	Owner     string  `json:"owner,omitempty"`
+	System    string  `json:"system,omitempty"`
+	Jobs      int     `json:"ciJobs"`
+	Minutes   float64 `json:"ciMinutes"`
+	Cost      float64 `json:"ciCost"`
+	Failures  int     `json:"ciFailures"`
+}
+
+// catalogMetrics returns the usage of each component in records.
+func catalogMetrics(records []jobRecord, entities map[string]catalogEntity) []*componentMetrics {
+	var res []*componentMetrics
+	byRef := map[string]*componentMetrics{}
+	for _, r := range records {
+		e, ok := entities[strings.ToLower(r.Repository)]
+		if !ok {
+			continue
+		}
+
+		m := byRef[e.Ref()]
+		if m == nil {
+			m = &componentMetrics{EntityRef: e.Ref(), Owner: e.Spec.Owner, System: e.Spec.System}
+			byRef[e.Ref()] = m
+			res = append(res, m)
+		}
+
+		m.Jobs++
+		m.Minutes += r.Minutes
+		m.Cost += jobCost(r)
+		if r.Conclusion == "failure" {
+			m.Failures++
+		}
+	}
+
+	return res
+}
+
+// orNone returns s, or "(none)" for repositories without a catalog entity.
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+
+	return s
+}
+
+func writeCatalogMetrics(p string, metrics []*componentMetrics) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+
+	if err := encodeJSON(f, metrics); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
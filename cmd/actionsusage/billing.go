@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// GitHub-hosted runner minute multipliers, relative to Linux. Self-hosted
+// runners, whatever their OS, aren't billed.
+var osMultipliers = map[string]float64{
+	"Linux":       1,
+	"Windows":     2,
+	"macOS":       10,
+	"Self-hosted": 0,
+}
+
+var osOrder = []string{"Linux", "Windows", "macOS", "Self-hosted"}
+
+// runnerOS guesses the operating system of a job's runner from its labels;
+// anything that doesn't look like Windows or macOS is billed as Linux.
+func runnerOS(labels []string) string {
+	for _, l := range labels {
+		l = strings.ToLower(l)
+		switch {
+		case strings.HasPrefix(l, "windows"):
+			return "Windows"
+		case strings.HasPrefix(l, "macos"):
+			return "macOS"
+		}
+	}
+
+	return "Linux"
+}
+
+// billedOS is runnerOS, except for jobs that asked for a self-hosted runner,
+// which are billed as Self-hosted.
+func billedOS(labels []string) string {
+	if slices.Contains(labels, "self-hosted") {
+		return "Self-hosted"
+	}
+
+	return runnerOS(labels)
+}
+
+type osMinutes struct {
+	OS        string  `json:"os"`
+	Minutes   float64 `json:"minutes"`
+	Weighted  float64 `json:"weighted_minutes"` // Minutes times the OS multiplier.
+	CostShare float64 `json:"cost_share"`       // Fraction of total weighted minutes.
+}
+
+func (m osMinutes) String() string {
+	return fmt.Sprintf("%s: %s minutes (x%g, %.1f%% of cost)", m.OS, formatMinutes(m.Minutes), osMultipliers[m.OS], 100*m.CostShare)
+}
+
+// splitByOS returns the minutes per runner OS, in a fixed order, with those of
+// self-hosted runners, which aren't billed, in their own row.
+func splitByOS(records []jobRecord) []osMinutes {
+	minutes := map[string]float64{}
+	var weighted float64
+	for _, r := range records {
+		os := billedOS(r.Labels)
+		minutes[os] += r.Minutes
+		weighted += r.Minutes * osMultipliers[os]
+	}
+
+	var res []osMinutes
+	for _, os := range osOrder {
+		m := osMinutes{OS: os, Minutes: minutes[os], Weighted: minutes[os] * osMultipliers[os]}
+		if weighted > 0 {
+			m.CostShare = m.Weighted / weighted
+		}
+		res = append(res, m)
+	}
+
+	return res
+}
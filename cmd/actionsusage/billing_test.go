@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitByOS(t *testing.T) {
+	records := []jobRecord{
+		{Labels: []string{"ubuntu-latest"}, Minutes: 20},
+		{Labels: []string{"windows-2022"}, Minutes: 5},
+		{Labels: []string{"macos-14"}, Minutes: 1},
+		{Labels: []string{"self-hosted", "linux", "x64"}, Minutes: 100},
+		{Labels: []string{"self-hosted", "macOS"}, Minutes: 7},
+	}
+
+	want := []osMinutes{
+		{OS: "Linux", Minutes: 20, Weighted: 20, CostShare: 0.5},
+		{OS: "Windows", Minutes: 5, Weighted: 10, CostShare: 0.25},
+		{OS: "macOS", Minutes: 1, Weighted: 10, CostShare: 0.25},
+		{OS: "Self-hosted", Minutes: 107},
+	}
+	if got := splitByOS(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	for _, r := range records[3:] {
+		if c := jobCost(r); c != 0 {
+			t.Errorf("self-hosted job costs %v", c)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	bisectFlags         = flag.NewFlagSet("bisect", flag.ExitOnError)
+	bisectMaxCandidates = bisectFlags.Int("max_candidates", 50, "Maximum number of candidate commits to list, and to fetch the changed files of.")
+)
+
+// bisectCommit is a commit that may have caused a slowdown.
+type bisectCommit struct {
+	SHA             string
+	Subject         string
+	Author          string
+	URL             string
+	TouchesWorkflow bool // Changes the workflow file, or another file under .github.
+}
+
+type bisectResult struct {
+	Repository string
+	Workflow   string
+	Baseline   time.Duration // Median duration before the regression.
+	Slow       time.Duration // Median duration from the first slow run on.
+	LastGood   *github.WorkflowRun
+	FirstBad   *github.WorkflowRun
+	Candidates []bisectCommit
+	Truncated  bool // There were more than -max_candidates commits.
+	CompareURL string
+}
+
+func runBisect(ctx context.Context) error {
+	if len(workflowFilter) != 1 {
+		return errors.New("bisect needs a single -workflow")
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if len(repoList) != 1 {
+		return errors.New("bisect needs a single repository")
+	}
+
+	reponame := repoList[0]
+	owner, name, err := ghclient.SplitRepo(reponame)
+	if err != nil {
+		return err
+	}
+
+	runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{Status: "success"}, *runCount)
+	if err != nil {
+		return err
+	}
+
+	var started []*github.WorkflowRun
+	for _, w := range runs {
+		if w.RunStartedAt != nil && w.UpdatedAt != nil {
+			started = append(started, w)
+		}
+	}
+	sort.Slice(started, func(i, j int) bool { return started[i].RunStartedAt.Before(started[j].RunStartedAt.Time) })
+
+	var samples []runSample
+	for _, w := range started {
+		samples = append(samples, runSample{start: w.RunStartedAt.Time, duration: w.UpdatedAt.Sub(w.RunStartedAt.Time), sha: w.GetHeadSHA()})
+	}
+
+	reg, ok := findRegression(samples)
+	if !ok {
+		return fmt.Errorf("%s: no regression found among %d successful runs of %s", reponame, len(samples), workflowFilter[0])
+	}
+
+	lastGood, firstBad := bisectRuns(samples, reg)
+	res := bisectResult{
+		Repository: reponame,
+		Workflow:   workflowFilter[0],
+		Baseline:   reg.Before,
+		Slow:       median(samples[firstBad:]),
+		LastGood:   started[lastGood],
+		FirstBad:   started[firstBad],
+		CompareURL: fmt.Sprintf("https://github.com/%s/compare/%s...%s", reponame, samples[lastGood].sha, samples[firstBad].sha),
+	}
+
+	log.Printf("%s: %s slowed down from %v to %v between %s and %s", reponame, res.Workflow, res.Baseline.Round(time.Second), res.Slow.Round(time.Second),
+		samples[lastGood].sha, samples[firstBad].sha)
+
+	cmp, _, err := client.Repositories.CompareCommits(ctx, owner, name, samples[lastGood].sha, samples[firstBad].sha, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return err
+	}
+
+	commits := cmp.Commits
+	if len(commits) > *bisectMaxCandidates {
+		commits, res.Truncated = commits[len(commits)-*bisectMaxCandidates:], true
+	}
+
+	paths, err := fetchWorkflowPaths(ctx, client, reponame)
+	if err != nil {
+		return err
+	}
+
+	workflowPath := paths[res.FirstBad.GetWorkflowID()]
+	for _, c := range commits {
+		full, _, err := client.Repositories.GetCommit(ctx, owner, name, c.GetSHA(), nil)
+		if err != nil {
+			return err
+		}
+
+		subject, _, _ := strings.Cut(c.GetCommit().GetMessage(), "\n")
+		candidate := bisectCommit{SHA: c.GetSHA(), Subject: subject, Author: c.GetCommit().GetAuthor().GetName(), URL: c.GetHTMLURL()}
+		for _, f := range full.Files {
+			if f.GetFilename() == workflowPath || strings.HasPrefix(f.GetFilename(), ".github/") {
+				candidate.TouchesWorkflow = true
+			}
+		}
+
+		res.Candidates = append(res.Candidates, candidate)
+	}
+
+	// Newest first, as the last commit before the first slow run is the
+	// likeliest culprit.
+	for i, j := 0, len(res.Candidates)-1; i < j; i, j = i+1, j-1 {
+		res.Candidates[i], res.Candidates[j] = res.Candidates[j], res.Candidates[i]
+	}
+
+	return bisectMarkdown.Execute(os.Stdout, res)
+}
+
+// bisectRuns narrows a regression down to the first slow run after which
+// runs stay slow, and the last run before it that wasn't slow, where slow is
+// closer to the median after the regression than before. It returns their
+// indexes in samples.
+func bisectRuns(samples []runSample, reg durationRegression) (int, int) {
+	threshold := (reg.Before + reg.After) / 2
+
+	firstBad := len(samples) - 1
+	for k, s := range samples {
+		if s.duration >= threshold && median(samples[k:]) >= threshold {
+			firstBad = k
+			break
+		}
+	}
+
+	lastGood := 0
+	for k := firstBad - 1; k >= 0; k-- {
+		if samples[k].duration < threshold {
+			lastGood = k
+			break
+		}
+	}
+
+	return lastGood, firstBad
+}
+
+var bisectMarkdown = template.Must(template.New("bisect").Funcs(digestFuncs).Parse(`# {{.Repository}}: {{.Workflow}} slowdown
+
+Runs went from a median of {{round .Baseline}} to {{round .Slow}}.
+
+- Last good run: [{{.LastGood.GetID}}]({{.LastGood.GetHTMLURL}}) at {{.LastGood.GetHeadSHA}}
+- First slow run: [{{.FirstBad.GetID}}]({{.FirstBad.GetHTMLURL}}) at {{.FirstBad.GetHeadSHA}}
+
+## Candidate commits
+
+[Compare]({{.CompareURL}}){{if .Truncated}}; only the newest are listed{{end}}. Commits marked ⚙ change the workflow or other files under .github.
+
+| Commit | Author | Subject | |
+|---|---|---|---|
+{{range .Candidates}}| [{{printf "%.7s" .SHA}}]({{.URL}}) | {{.Author}} | {{.Subject}} | {{if .TouchesWorkflow}}⚙{{end}} |
+{{end}}`))
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	botActors   = flag.String("bot_actors", "dependabot[bot],renovate[bot]", "Dependency update bots whose runs are attributed separately, separated by commas.")
+	excludeBots = flag.Bool("exclude-bots", false, "Leave out runs triggered by -bot_actors, or any other [bot] app, from the minutes and concurrency.")
+)
+
+// botUsage is the share of a repository's minutes spent on runs triggered by
+// dependency update bots.
+type botUsage struct {
+	Repository   string      `json:"repo"`
+	Bot          string      `json:"bot"`
+	Minutes      float64     `json:"minutes"`
+	Jobs         int         `json:"jobs"`
+	TotalMinutes float64     `json:"total_minutes"` // All minutes of the repository.
+	Share        float64     `json:"share"`
+	Weekly       []botWeekly `json:"weekly"`
+}
+
+type botWeekly struct {
+	Week         time.Time `json:"week"` // Monday.
+	Minutes      float64   `json:"minutes"`
+	TotalMinutes float64   `json:"total_minutes"`
+}
+
+func (b botUsage) String() string {
+	return fmt.Sprintf("%s: %s: %s of %s minutes (%.1f%%)", b.Repository, b.Bot, formatMinutes(b.Minutes), formatMinutes(b.TotalMinutes), 100*b.Share)
+}
+
+func isBot(actor string) bool {
+	for _, b := range strings.Split(*botActors, ",") {
+		if strings.EqualFold(actor, b) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// excludedBot returns whether -exclude-bots leaves out a run by actor.
+func excludedBot(actor string) bool {
+	return *excludeBots && (isBot(actor) || strings.HasSuffix(actor, "[bot]"))
+}
+
+func weekOf(t time.Time) time.Time {
+	day := t.UTC().Truncate(24 * time.Hour)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// attributeBotUsage returns, per repository and bot, the minutes of the runs
+// the bot triggered, with their weekly trend; the largest first.
+func attributeBotUsage(records []jobRecord) []botUsage {
+	repoTotals := map[string]float64{}
+	weekTotals := map[string]map[time.Time]float64{}
+	for _, r := range records {
+		repoTotals[r.Repository] += r.Minutes
+		if weekTotals[r.Repository] == nil {
+			weekTotals[r.Repository] = map[time.Time]float64{}
+		}
+		weekTotals[r.Repository][weekOf(r.Start)] += r.Minutes
+	}
+
+	type key struct{ repo, bot string }
+	usage := map[key]*botUsage{}
+	weekly := map[key]map[time.Time]float64{}
+	for _, r := range records {
+		if !isBot(r.Actor) {
+			continue
+		}
+
+		k := key{r.Repository, r.Actor}
+		if usage[k] == nil {
+			usage[k] = &botUsage{Repository: r.Repository, Bot: r.Actor}
+			weekly[k] = map[time.Time]float64{}
+		}
+
+		usage[k].Minutes += r.Minutes
+		usage[k].Jobs++
+		weekly[k][weekOf(r.Start)] += r.Minutes
+	}
+
+	var res []botUsage
+	for k, u := range usage {
+		u.TotalMinutes = repoTotals[k.repo]
+		if u.TotalMinutes > 0 {
+			u.Share = u.Minutes / u.TotalMinutes
+		}
+
+		for week, total := range weekTotals[k.repo] {
+			u.Weekly = append(u.Weekly, botWeekly{Week: week, Minutes: weekly[k][week], TotalMinutes: total})
+		}
+		sort.Slice(u.Weekly, func(i, j int) bool { return u.Weekly[i].Week.Before(u.Weekly[j].Week) })
+
+		res = append(res, *u)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Minutes != res[j].Minutes {
+			return res[i].Minutes > res[j].Minutes
+		}
+		return res[i].Repository+res[i].Bot < res[j].Repository+res[j].Bot
+	})
+
+	return res
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// supersededRuns returns the IDs of cancelled runs that were replaced by a
+// newer run of the same workflow on the same branch before they finished,
+// which is what a concurrency group with cancel-in-progress does.
+func supersededRuns(ws []*github.WorkflowRun) map[int64]bool {
+	type key struct {
+		repo       string
+		workflowID int64
+		branch     string
+	}
+
+	byKey := map[key][]*github.WorkflowRun{}
+	for _, w := range ws {
+		k := key{w.GetRepository().GetFullName(), w.GetWorkflowID(), w.GetHeadBranch()}
+		byKey[k] = append(byKey[k], w)
+	}
+
+	superseded := map[int64]bool{}
+	for _, runs := range byKey {
+		sort.Slice(runs, func(i, j int) bool { return runs[i].GetCreatedAt().Before(runs[j].GetCreatedAt().Time) })
+
+		for i, w := range runs {
+			if w.GetConclusion() != "cancelled" || i+1 == len(runs) {
+				continue
+			}
+
+			// The run was last updated when it was cancelled.
+			if next := runs[i+1]; !next.GetCreatedAt().After(w.GetUpdatedAt().Time) {
+				superseded[w.GetID()] = true
+			}
+		}
+	}
+
+	return superseded
+}
+
+type wasteSummary struct {
+	CancelledJobs     int     `json:"cancelled_jobs"`
+	CancelledMinutes  float64 `json:"cancelled_minutes"`
+	CancelledRounding float64 `json:"cancelled_rounding_minutes"` // Of CancelledMinutes, billed beyond the jobs' actual runtime.
+	SkippedJobs       int     `json:"skipped_jobs"`
+	SupersededRuns    int     `json:"superseded_runs"`
+	SupersededMinutes float64 `json:"superseded_minutes"`
+}
+
+func (w wasteSummary) String() string {
+	return fmt.Sprintf("cancelled jobs: %d (%s minutes, %s of them rounding up); skipped jobs: %d; superseded runs: %d (%s minutes)",
+		w.CancelledJobs, formatMinutes(w.CancelledMinutes), formatMinutes(w.CancelledRounding), w.SkippedJobs, w.SupersededRuns, formatMinutes(w.SupersededMinutes))
+}
+
+func summarizeWaste(records []jobRecord) wasteSummary {
+	var w wasteSummary
+	runs := map[int64]bool{}
+	for _, r := range records {
+		switch r.Conclusion {
+		case "cancelled":
+			w.CancelledJobs++
+			w.CancelledMinutes += r.Minutes
+			w.CancelledRounding += r.Minutes - r.Duration().Minutes()
+		case "skipped":
+			w.SkippedJobs++
+		}
+
+		if r.Superseded {
+			w.SupersededMinutes += r.Minutes
+			runs[r.RunID] = true
+		}
+	}
+
+	w.SupersededRuns = len(runs)
+	return w
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"time"
+)
+
+var (
+	capacityPercentile = flag.Float64("capacity_percentile", 95, "Self-hosted runner pools are recommended to cover the concurrency observed this percent of the time.")
+	capacityTFVars     = flag.String("capacity_tfvars", "", "Write the recommended self-hosted runner pool sizes per label to this Terraform variables file, "+
+		`e.g. runners.auto.tfvars.json, as {"runner_pools": {"<labels>": {"min_runners": n, "max_runners": n}}}.`)
+)
+
+// runnerCapacity is the observed demand for a self-hosted runner label.
+type runnerCapacity struct {
+	Label       string
+	Jobs        int
+	Peak        int // Most jobs running at once.
+	Median      int // Jobs running at once half of the time.
+	Recommended int // Jobs running at once -capacity_percentile of the time.
+}
+
+// recommendCapacity returns the demand for each runner label of the included
+// jobs, as the number of concurrently running jobs over the whole period
+// covered by records, idle time included.
+func recommendCapacity(records []jobRecord, include func(jobRecord) bool) []runnerCapacity {
+	type event struct {
+		at    time.Time
+		delta int
+	}
+
+	var first, last time.Time
+	byLabel := map[string][]event{}
+	jobs := map[string]int{}
+	for _, r := range records {
+		if first.IsZero() || r.Start.Before(first) {
+			first = r.Start
+		}
+		if r.End.After(last) {
+			last = r.End
+		}
+
+		if !include(r) {
+			continue
+		}
+
+		byLabel[r.Label()] = append(byLabel[r.Label()], event{r.Start, 1}, event{r.End, -1})
+		jobs[r.Label()]++
+	}
+
+	total := last.Sub(first)
+	if total <= 0 {
+		return nil
+	}
+
+	var res []runnerCapacity
+	for label, events := range byLabel {
+		sort.Slice(events, func(i, j int) bool {
+			if events[i].at.Equal(events[j].at) {
+				return events[i].delta < events[j].delta
+			}
+			return events[i].at.Before(events[j].at)
+		})
+
+		c := runnerCapacity{Label: label, Jobs: jobs[label]}
+
+		// How long each number of jobs was running.
+		at := map[int]time.Duration{0: events[0].at.Sub(first) + last.Sub(events[len(events)-1].at)}
+		running := 0
+		for k, e := range events {
+			running += e.delta
+			c.Peak = max(c.Peak, running)
+			if k+1 < len(events) {
+				at[running] += events[k+1].at.Sub(e.at)
+			}
+		}
+
+		c.Median = concurrencyPercentile(at, total, 0.5)
+		c.Recommended = concurrencyPercentile(at, total, *capacityPercentile/100)
+		res = append(res, c)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Recommended > res[j].Recommended })
+	return res
+}
+
+func selfHosted(r jobRecord) bool {
+	return slices.Contains(r.Labels, "self-hosted")
+}
+
+// concurrencyPercentile returns the least number of running jobs that covered
+// at least the fraction p of total.
+func concurrencyPercentile(at map[int]time.Duration, total time.Duration, p float64) int {
+	var levels []int
+	for n := range at {
+		levels = append(levels, n)
+	}
+	sort.Ints(levels)
+
+	var covered time.Duration
+	for _, n := range levels {
+		covered += at[n]
+		if float64(covered) >= math.Floor(p*float64(total)) {
+			return n
+		}
+	}
+
+	return levels[len(levels)-1]
+}
+
+func writeCapacityTFVars(p string, capacity []runnerCapacity) error {
+	type pool struct {
+		MinRunners int `json:"min_runners"`
+		MaxRunners int `json:"max_runners"`
+	}
+
+	pools := map[string]pool{}
+	for _, c := range capacity {
+		pools[c.Label] = pool{MinRunners: c.Median, MaxRunners: c.Recommended}
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+
+	if err := encodeJSON(f, map[string]any{"runner_pools": pools}); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,767 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/timewindow"
+)
+
+func init() {
+	ghclient.RegisterFlags(flag.CommandLine)
+}
+
+func newClient() (*github.Client, error) {
+	client, _, err := ghclient.New()
+	return client, err
+}
+
+// targetOrgs returns -org and the organizations of -enterprise.
+func targetOrgs(ctx context.Context, client *github.Client) ([]string, error) {
+	var orgs []string
+	if *org != "" {
+		orgs = append(orgs, *org)
+	}
+
+	if *enterprise != "" {
+		members, err := enterpriseOrgs(ctx, client, *enterprise)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("%s: %d organizations", *enterprise, len(members))
+		for _, o := range members {
+			if !slices.Contains(orgs, o) {
+				orgs = append(orgs, o)
+			}
+		}
+	}
+
+	return orgs, nil
+}
+
+// targetRepos returns -repos and the repositories of -repos-file, plus the
+// repositories of -org and of the organizations of -enterprise with one of
+// -topics, leaving out forks and archived ones unless asked to include them.
+func targetRepos(ctx context.Context, client *github.Client) ([]string, error) {
+	if *repos == "" && *reposFile == "" && *org == "" && *enterprise == "" {
+		return nil, errors.New("-repos, -repos-file, -org or -enterprise is required")
+	}
+
+	var res []string
+	if *repos != "" {
+		res = strings.Split(*repos, ",")
+	}
+
+	if *reposFile != "" {
+		fromFile, err := readRepoList(*reposFile)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, fromFile...)
+	}
+
+	if *topics != "" && *org == "" && *enterprise == "" {
+		return nil, errors.New("-topics requires -org or -enterprise")
+	}
+
+	orgs, err := targetOrgs(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range orgs {
+		listed := len(res)
+		orgRepos, err := ghclient.ListOrgRepos(ctx, client, o)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range orgRepos {
+			if (repo.GetArchived() && !*includeArchived) || (repo.GetFork() && !*includeForks) {
+				continue
+			}
+
+			if hasTopic(repo) && !slices.Contains(res, repo.GetFullName()) {
+				res = append(res, repo.GetFullName())
+			}
+		}
+
+		log.Printf("%s: %d more repositories", o, len(res)-listed)
+	}
+
+	if *excludeRepos != "" {
+		var kept []string
+		for _, reponame := range res {
+			excluded, err := excludedRepo(reponame)
+			if err != nil {
+				return nil, err
+			}
+
+			if excluded {
+				log.Printf("%s: excluded", reponame)
+				continue
+			}
+			kept = append(kept, reponame)
+		}
+		res = kept
+	}
+
+	return preflightRepos(ctx, client, res)
+}
+
+// hasTopic returns whether a repository has one of -topics, if set.
+func hasTopic(repo *github.Repository) bool {
+	if *topics == "" {
+		return true
+	}
+
+	for _, t := range strings.Split(*topics, ",") {
+		if slices.Contains(repo.Topics, strings.ToLower(t)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseRunIDs parses -run-ids into the repositories the runs belong to, in
+// order, and the IDs of the runs of each.
+func parseRunIDs(v string) ([]string, map[string][]int64, error) {
+	var repoList []string
+	ids := map[string][]int64{}
+	for _, entry := range strings.Split(v, ",") {
+		reponame, id, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		n, err := strconv.ParseInt(id, 10, 64)
+		if !ok || err != nil {
+			return nil, nil, fmt.Errorf("bad -run-ids entry %q: want owner/repo:id", entry)
+		}
+
+		if _, ok := ids[reponame]; !ok {
+			repoList = append(repoList, reponame)
+		}
+		ids[reponame] = append(ids[reponame], n)
+	}
+
+	return repoList, ids, nil
+}
+
+// fetchRunsByID fetches the given runs of a repository.
+func fetchRunsByID(ctx context.Context, client *github.Client, reponame string, ids []int64) ([]*github.WorkflowRun, error) {
+	owner, name, err := ghclient.SplitRepo(reponame)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*github.WorkflowRun
+	for _, id := range ids {
+		w, _, err := client.Actions.GetWorkflowRunByID(ctx, owner, name, id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: run %d: %w", reponame, id, err)
+		}
+
+		res = append(res, w)
+	}
+
+	return res, nil
+}
+
+// excludedRepo returns whether a repository matches one of -exclude-repos.
+// Patterns without a slash match the repository name within any owner.
+func excludedRepo(reponame string) (bool, error) {
+	for _, pattern := range strings.Split(*excludeRepos, ",") {
+		name := reponame
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(reponame)
+		}
+
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("bad -exclude-repos pattern %q: %w", pattern, err)
+		}
+
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// skippedRepo is a repository whose Actions data the token can't read.
+type skippedRepo struct {
+	Repo   string `json:"repository"`
+	Reason string `json:"reason"`
+}
+
+// preflightRepos lists a single run of each repository with -preflight, so
+// that a lack of access shows before a long collection rather than midway
+// through it. It returns the repositories whose runs the token can read, and
+// records the others in the result, which makes it partial.
+func preflightRepos(ctx context.Context, client *github.Client, repoList []string) ([]string, error) {
+	if !*preflight {
+		return repoList, nil
+	}
+
+	var ok []string
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return nil, err
+		}
+
+		_, _, err = client.Actions.ListRepositoryWorkflowRuns(ctx, owner, name, &github.ListWorkflowRunsOptions{ListOptions: github.ListOptions{PerPage: 1}})
+		var e *github.ErrorResponse
+		var reason string
+		switch {
+		case err == nil:
+			ok = append(ok, reponame)
+			continue
+		case ghclient.IsNotFound(err):
+			reason = "not found, or not visible to the token"
+		case ghclient.IsForbidden(err) && errors.As(err, &e):
+			reason = "forbidden: " + e.Message
+			if accepted := e.Response.Header.Get("X-Accepted-GitHub-Permissions"); accepted != "" {
+				reason += fmt.Sprintf(" (requires %s)", accepted)
+			}
+		default:
+			return nil, fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		log.Printf("%s: skipped, %s", reponame, reason)
+		result.Skipped = append(result.Skipped, skippedRepo{reponame, reason})
+	}
+
+	if len(ok) == 0 && len(repoList) > 0 {
+		return nil, errors.New("the token can't read the runs of any repository")
+	}
+
+	if len(ok) < len(repoList) {
+		log.Printf("Skipping %d of %d repositories whose runs the token can't read", len(repoList)-len(ok), len(repoList))
+	}
+
+	return ok, nil
+}
+
+// compileJobFilters compiles -job-filter and -job-exclude, returning nil for
+// either if it isn't set.
+func compileJobFilters() (*regexp.Regexp, *regexp.Regexp, error) {
+	var include, exclude *regexp.Regexp
+	var err error
+	if *jobFilter != "" {
+		if include, err = regexp.Compile(*jobFilter); err != nil {
+			return nil, nil, fmt.Errorf("bad -job-filter: %w", err)
+		}
+	}
+
+	if *jobExclude != "" {
+		if exclude, err = regexp.Compile(*jobExclude); err != nil {
+			return nil, nil, fmt.Errorf("bad -job-exclude: %w", err)
+		}
+	}
+
+	return include, exclude, nil
+}
+
+// matchesRunnerLabel returns whether any of a job's labels matches one of
+// the -runner-label glob patterns.
+func matchesRunnerLabel(labels []string) (bool, error) {
+	for _, pattern := range strings.Split(*runnerLabel, ",") {
+		for _, label := range labels {
+			ok, err := path.Match(pattern, label)
+			if err != nil {
+				return false, fmt.Errorf("bad -runner-label pattern %q: %w", pattern, err)
+			}
+
+			if ok {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// readRepoList reads one repository per line from a file, or stdin for -.
+// Blank lines and # comments are ignored.
+func readRepoList(p string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if p != "-" {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var res []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			res = append(res, line)
+		}
+	}
+
+	return res, scanner.Err()
+}
+
+var (
+	runsSince = flag.String("since", "", "Only consider runs created at or after this time: "+timewindow.Formats+". "+
+		"Runs are then listed until they fall outside the window, rather than up to -run_count.")
+	runsUntil = flag.String("until", "", "Only consider runs created before this time; same formats as -since.")
+
+	runConclusion = flag.String("conclusion", "", "Only consider runs with this conclusion, e.g. failure to measure the minutes spent on failing runs. "+
+		"One of success, failure, cancelled, skipped, timed_out, action_required, neutral, stale or startup_failure.")
+
+	workflowFilter stringList
+
+	// The window parsed from -since and -until; zero if unset.
+	windowStart, windowEnd time.Time
+)
+
+func init() {
+	flag.Var(&workflowFilter, "workflow", "Only consider the runs of this workflow, given by file name (e.g. ci.yml) or by name (repeatable).")
+}
+
+// stringList is a flag that may be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// parseWindow parses -since and -until.
+func parseWindow(now time.Time) error {
+	var err error
+	if windowStart, err = timewindow.Parse(*runsSince, now); err != nil {
+		return fmt.Errorf("bad -since: %w", err)
+	}
+
+	if windowEnd, err = timewindow.Parse(*runsUntil, now); err != nil {
+		return fmt.Errorf("bad -until: %w", err)
+	}
+
+	if !windowStart.IsZero() && !windowEnd.IsZero() && !windowStart.Before(windowEnd) {
+		return fmt.Errorf("-since must be before -until")
+	}
+
+	return nil
+}
+
+// fetchRuns returns up to limit workflow runs of a repository, newest first.
+// Only runs of the -workflow workflows are returned, if set, and only those
+// matching -branch, -event, -actor and -conclusion where opts doesn't already
+// filter on them. -actor and -conclusion are passed to the API when at most
+// 1000 runs are listed, and otherwise checked as runs are listed, which costs
+// requests for the runs that don't match but isn't cut at 1000 runs.
+//
+// Unless opts filters by creation time itself, only runs within -since and
+// -until are returned; with -since, all of them regardless of limit.
+//
+// A warning is logged if the API stops at its cap of 1000 runs before limit.
+func fetchRuns(ctx context.Context, client *github.Client, reponame string, opts github.ListWorkflowRunsOptions, limit int) ([]*github.WorkflowRun, error) {
+	ws, capped, err := listWorkflowRuns(ctx, client, reponame, opts, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if !capped.IsZero() {
+		log.Printf("%s: the API lists at most %d runs when filtering, so runs created before %v are missing",
+			reponame, apiRunCap, capped.Format(time.RFC3339))
+	}
+
+	return ws, nil
+}
+
+// fetchRunsCreated returns all workflow runs of a repository created from
+// from until to, newest first, filtered as fetchRuns does. As the API lists
+// at most 1000 runs per query, each query continues from the oldest run of
+// the last one that hit the cap.
+func fetchRunsCreated(ctx context.Context, client *github.Client, reponame string, from, to time.Time) ([]*github.WorkflowRun, error) {
+	var ws []*github.WorkflowRun
+	seen := map[int64]bool{}
+	last := to.Add(-time.Second) // The created filter includes both ends.
+	for {
+		runs, capped, err := listWorkflowRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{
+			Created: from.UTC().Format(time.RFC3339) + ".." + last.UTC().Format(time.RFC3339),
+		}, math.MaxInt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range runs {
+			if !seen[w.GetID()] {
+				seen[w.GetID()] = true
+				ws = append(ws, w)
+			}
+		}
+
+		if capped.IsZero() {
+			break
+		}
+		if !capped.Before(last) {
+			log.Printf("%s: more than %d runs created at %v, some are missing",
+				reponame, apiRunCap, capped.Format(time.RFC3339))
+			break
+		}
+		last = capped
+	}
+
+	sort.SliceStable(ws, func(i, j int) bool { return ws[i].GetCreatedAt().After(ws[j].GetCreatedAt().Time) })
+	return ws, nil
+}
+
+// apiRunCap is the most runs the API lists when filtering them.
+const apiRunCap = 1000
+
+// listWorkflowRuns is fetchRuns, also returning when the oldest run was
+// created if the API stopped listing at its cap: runs created from then on
+// are all listed, older ones may be missing.
+func listWorkflowRuns(ctx context.Context, client *github.Client, reponame string, opts github.ListWorkflowRunsOptions, limit int) ([]*github.WorkflowRun, time.Time, error) {
+	owner, name, err := ghclient.SplitRepo(reponame)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// The API's filters cap its listings at 1000 runs, which only matters if
+	// more could be listed: with -since, or a -run_count above 1000. Within
+	// the cap, the API filters -actor and -conclusion itself rather than
+	// listing runs only to drop them.
+	bounded := opts.Created != "" || (windowStart.IsZero() && limit <= 1000)
+
+	var filter runFilter
+	if opts.Branch == "" {
+		filter.branch = *branch
+	}
+	if opts.Event == "" {
+		filter.event = *event
+	}
+	if opts.Actor == "" && bounded {
+		opts.Actor = *actor
+	} else if opts.Actor == "" {
+		filter.actor = *actor
+	}
+	if opts.Status == "" && bounded && *runConclusion != "startup_failure" { // Which the API's status filter doesn't take.
+		opts.Status = *runConclusion
+	} else if opts.Status == "" {
+		filter.conclusion = *runConclusion
+	}
+
+	if len(workflowFilter) == 0 {
+		return listRuns(reponame, opts, filter, limit, func(opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+			return client.Actions.ListRepositoryWorkflowRuns(ctx, owner, name, opts)
+		})
+	}
+
+	var ws []*github.WorkflowRun
+	var capped time.Time // The latest of the workflows' caps.
+	for _, w := range workflowFilter {
+		var runs []*github.WorkflowRun
+		var wfCapped time.Time
+		if ext := path.Ext(w); ext == ".yml" || ext == ".yaml" {
+			runs, wfCapped, err = listRuns(reponame, opts, filter, limit, func(opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+				return client.Actions.ListWorkflowRunsByFileName(ctx, owner, name, path.Base(w), opts)
+			})
+			if ghclient.IsNotFound(err) {
+				log.Printf("%s: no workflow %s", reponame, w)
+				continue
+			}
+		} else {
+			var id int64
+			if id, err = workflowIDByName(ctx, client, owner, name, w); err != nil {
+				return nil, time.Time{}, err
+			}
+			if id == 0 {
+				log.Printf("%s: no workflow named %q", reponame, w)
+				continue
+			}
+
+			runs, wfCapped, err = listRuns(reponame, opts, filter, limit, func(opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+				return client.Actions.ListWorkflowRunsByID(ctx, owner, name, id, opts)
+			})
+		}
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		ws = append(ws, runs...)
+		if wfCapped.After(capped) {
+			capped = wfCapped
+		}
+	}
+
+	sort.Slice(ws, func(i, j int) bool { return ws[i].GetCreatedAt().After(ws[j].GetCreatedAt().Time) })
+	if (opts.Created != "" || windowStart.IsZero()) && len(ws) > limit {
+		ws = ws[:limit]
+	}
+
+	return ws, capped, nil
+}
+
+// workflowIDByName returns the ID of a repository's workflow with the given
+// name, or 0 if it has none.
+func workflowIDByName(ctx context.Context, client *github.Client, owner, name, workflow string) (int64, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		wfs, r, err := client.Actions.ListWorkflows(ctx, owner, name, opts)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, wf := range wfs.Workflows {
+			if wf.GetName() == workflow {
+				return wf.GetID(), nil
+			}
+		}
+
+		if r.NextPage == 0 {
+			return 0, nil
+		}
+		opts.Page = r.NextPage
+	}
+}
+
+// runFilter selects runs after they're listed, for the filters that aren't
+// passed to the API because it then returns at most 1000 runs.
+type runFilter struct {
+	branch     string
+	event      string
+	actor      string
+	conclusion string
+}
+
+func (f runFilter) match(w *github.WorkflowRun) bool {
+	return (f.branch == "" || w.GetHeadBranch() == f.branch) &&
+		(f.event == "" || w.GetEvent() == f.event) &&
+		(f.actor == "" || strings.EqualFold(w.GetActor().GetLogin(), f.actor)) &&
+		(f.conclusion == "" || w.GetConclusion() == f.conclusion)
+}
+
+// listRuns pages through the runs returned by list, newest first, keeping
+// those that filter matches. If the API stopped at its cap, it also returns
+// when the oldest run it listed was created.
+func listRuns(reponame string, opts github.ListWorkflowRunsOptions, filter runFilter, limit int, list func(*github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)) ([]*github.WorkflowRun, time.Time, error) {
+	filtered := opts.Created != "" || opts.Actor != "" || opts.Branch != "" || opts.Event != "" ||
+		opts.Status != "" || opts.HeadSHA != "" || opts.CheckSuiteID != 0
+
+	windowed := opts.Created == "" && (!windowStart.IsZero() || !windowEnd.IsZero())
+	if windowed && !windowStart.IsZero() {
+		limit = math.MaxInt
+	}
+
+	var ws []*github.WorkflowRun
+	var listed int
+	var oldest time.Time
+	for k := 1; ; k++ {
+		opts.ListOptions = github.ListOptions{
+			PerPage: min(100, limit),
+			Page:    k,
+		}
+
+		runs, r, err := list(&opts)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		if len(runs.WorkflowRuns) == 0 {
+			if filtered && listed >= apiRunCap {
+				return ws, oldest, nil
+			}
+			break
+		}
+
+		listed += len(runs.WorkflowRuns)
+		oldest = runs.WorkflowRuns[len(runs.WorkflowRuns)-1].GetCreatedAt().Time
+
+		// Runs are listed newest first, so the first one created before -since
+		// ends the window. The created filter isn't used, as the API then
+		// returns at most 1000 runs.
+		done := false
+		for _, w := range runs.WorkflowRuns {
+			if windowed && !windowEnd.IsZero() && !w.GetCreatedAt().Before(windowEnd) {
+				continue
+			}
+			if windowed && !windowStart.IsZero() && w.GetCreatedAt().Before(windowStart) {
+				done = true
+				break
+			}
+			if !filter.match(w) {
+				continue
+			}
+			ws = append(ws, w)
+		}
+
+		if len(ws) > 0 {
+			log.Printf("%s: got %d runs (total: %d rate_limit: %d/%d from: %v to %v)",
+				reponame, len(runs.WorkflowRuns), len(ws), r.Rate.Remaining, r.Rate.Limit,
+				ws[0].CreatedAt.Time.Format(time.RFC3339), ws[len(ws)-1].CreatedAt.Time.Format(time.RFC3339),
+			)
+		}
+		if done || len(ws) >= limit {
+			break
+		}
+	}
+
+	return ws, time.Time{}, nil
+}
+
+// fetchJobs returns up to (roughly) maxJobs jobs of the latest attempt of a
+// workflow run, along with the last API response for rate limit reporting.
+func fetchJobs(ctx context.Context, client *github.Client, w *github.WorkflowRun, maxJobs int) ([]*github.WorkflowJob, *github.Response, error) {
+	return listJobs(ctx, client, w, maxJobs, "latest")
+}
+
+// listJobs is fetchJobs with the API's filter: latest, or all to also return
+// the jobs of earlier attempts.
+func listJobs(ctx context.Context, client *github.Client, w *github.WorkflowRun, maxJobs int, filter string) ([]*github.WorkflowJob, *github.Response, error) {
+	var jobs []*github.WorkflowJob
+	var last *github.Response
+	for k := 1; len(jobs) < maxJobs; k++ {
+		j, r, err := client.Actions.ListWorkflowJobs(ctx, *w.Repository.Owner.Login, *w.Repository.Name, *w.ID, &github.ListWorkflowJobsOptions{
+			Filter: filter,
+			ListOptions: github.ListOptions{
+				Page:    k,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		last = r
+
+		if len(j.Jobs) == 0 {
+			break
+		}
+
+		jobs = append(jobs, j.Jobs...)
+		if j.TotalCount != nil && len(jobs) == *j.TotalCount {
+			break
+		}
+	}
+
+	return jobs, last, nil
+}
+
+// jobMinutes returns the minutes a completed job counts for. With
+// -rounding=job, each job is rounded up to the next whole minute, as GitHub
+// bills hosted runners; otherwise it's exact, and runMinutes does any rounding.
+func jobMinutes(job *github.WorkflowJob) float64 {
+	m := job.CompletedAt.Time.Sub(job.StartedAt.Time).Minutes()
+	if *rounding == "job" {
+		return math.Ceil(m)
+	}
+
+	return m
+}
+
+// runMinutes returns the minutes the completed jobs of a run count for.
+func runMinutes(jobs []*github.WorkflowJob) float64 {
+	var total float64
+	for _, job := range jobs {
+		if job.CompletedAt != nil && job.StartedAt != nil {
+			total += jobMinutes(job)
+		}
+	}
+
+	if *rounding == "run" {
+		return math.Ceil(total)
+	}
+
+	return total
+}
+
+// roundRun rounds the total of a run's job records up to a whole minute with
+// -rounding=run, attributing the difference to its longest job. It returns
+// the minutes added.
+func roundRun(records []jobRecord) float64 {
+	if *rounding != "run" || len(records) == 0 {
+		return 0
+	}
+
+	var total float64
+	longest := 0
+	for k, r := range records {
+		total += r.Minutes
+		if r.Minutes > records[longest].Minutes {
+			longest = k
+		}
+	}
+
+	extra := math.Ceil(total) - total
+	records[longest].Minutes += extra
+	return extra
+}
+
+// groupRun rounds a run's job records with roundRun and only then adds them
+// to groups, if set, so that the groups add up to the total. It returns the
+// minutes that rounding added.
+func groupRun(records []jobRecord, groups *grouper) (float64, error) {
+	extra := roundRun(records)
+	if groups == nil {
+		return extra, nil
+	}
+
+	for _, r := range records {
+		if err := groups.add(r); err != nil {
+			return 0, err
+		}
+	}
+
+	return extra, nil
+}
+
+// formatMinutes formats minutes as a whole number if they are, and with one
+// decimal otherwise.
+func formatMinutes(m float64) string {
+	return strconv.FormatFloat(math.Round(m*10)/10, 'f', -1, 64)
+}
+
+// fetchWorkflowPaths maps the IDs of a repository's workflows to their file paths.
+func fetchWorkflowPaths(ctx context.Context, client *github.Client, reponame string) (map[int64]string, error) {
+	owner, name, err := ghclient.SplitRepo(reponame)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[int64]string{}
+	for k := 1; ; k++ {
+		wfs, _, err := client.Actions.ListWorkflows(ctx, owner, name, &github.ListOptions{Page: k, PerPage: 100})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, wf := range wfs.Workflows {
+			paths[wf.GetID()] = wf.GetPath()
+		}
+
+		if len(wfs.Workflows) == 0 || len(paths) >= wfs.GetTotalCount() {
+			break
+		}
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// testJob returns a completed job of the given duration.
+func testJob(id int64, name string, d time.Duration) *github.WorkflowJob {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &github.WorkflowJob{
+		ID:          github.Int64(id),
+		Name:        github.String(name),
+		Conclusion:  github.String("success"),
+		CreatedAt:   &github.Timestamp{Time: start},
+		StartedAt:   &github.Timestamp{Time: start},
+		CompletedAt: &github.Timestamp{Time: start.Add(d)},
+	}
+}
+
+func setFlag(t *testing.T, p *string, v string) {
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+func TestRoundRunGroupsAddUp(t *testing.T) {
+	runs := [][]*github.WorkflowJob{
+		{testJob(1, "build", 90*time.Second), testJob(2, "test", 150*time.Second), testJob(3, "lint", 10*time.Second)},
+		{testJob(4, "build", 30*time.Second), testJob(5, "lint", 20*time.Second)},
+	}
+
+	for _, tc := range []struct {
+		rounding string
+		total    float64
+	}{
+		{"job", 2 + 3 + 1 + 1 + 1},
+		{"run", 5 + 1},
+		{"exact", (90 + 150 + 10 + 30 + 20) / 60.0},
+	} {
+		t.Run(tc.rounding, func(t *testing.T) {
+			setFlag(t, rounding, tc.rounding)
+
+			groups, err := newGrouper("{{.Job}}")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var total float64
+			for k, jobs := range runs {
+				w := &github.WorkflowRun{ID: github.Int64(int64(k + 1)), Name: github.String("ci")}
+
+				var records []jobRecord
+				for _, job := range jobs {
+					r := newJobRecord("acme/app", w, job)
+					total += r.Minutes
+					records = append(records, r)
+				}
+
+				extra, err := groupRun(records, groups)
+				if err != nil {
+					t.Fatal(err)
+				}
+				total += extra
+			}
+
+			if math.Abs(total-tc.total) > 1e-9 {
+				t.Errorf("total = %v, want %v", total, tc.total)
+			}
+
+			var grouped float64
+			for _, g := range groups.sorted() {
+				grouped += g.Minutes
+			}
+			if math.Abs(grouped-total) > 1e-9 {
+				t.Errorf("groups add up to %v, want %v", grouped, total)
+			}
+		})
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+	w := &github.WorkflowRun{
+		HeadBranch: github.String("main"),
+		Event:      github.String("push"),
+		Actor:      &github.User{Login: github.String("Dependabot[bot]")},
+		Conclusion: github.String("startup_failure"),
+	}
+
+	for _, tc := range []struct {
+		filter runFilter
+		want   bool
+	}{
+		{runFilter{}, true},
+		{runFilter{branch: "main", event: "push"}, true},
+		{runFilter{branch: "release"}, false},
+		{runFilter{event: "pull_request"}, false},
+		{runFilter{actor: "dependabot[bot]"}, true},
+		{runFilter{actor: "octocat"}, false},
+		{runFilter{conclusion: "startup_failure"}, true},
+		{runFilter{conclusion: "failure"}, false},
+	} {
+		if got := tc.filter.match(w); got != tc.want {
+			t.Errorf("%+v.match() = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestPreflightRepos(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/app/actions/runs":
+			fmt.Fprint(w, `{"total_count":0,"workflow_runs":[]}`)
+		case "/repos/acme/secret/actions/runs":
+			w.Header().Set("X-Accepted-GitHub-Permissions", "actions=read")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"Resource not accessible by personal access token"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+		}
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	result = runResult{}
+	ok, err := preflightRepos(context.Background(), client, []string{"acme/app", "acme/secret", "acme/gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"acme/app"}; !reflect.DeepEqual(ok, want) {
+		t.Errorf("got %v, want %v", ok, want)
+	}
+
+	want := []skippedRepo{
+		{"acme/secret", "forbidden: Resource not accessible by personal access token (requires actions=read)"},
+		{"acme/gone", "not found, or not visible to the token"},
+	}
+	if !reflect.DeepEqual(result.Skipped, want) {
+		t.Errorf("skipped %v, want %v", result.Skipped, want)
+	}
+
+	if code := finish(nil); code != exitPartial {
+		t.Errorf("exit code %d, want %d", code, exitPartial)
+	}
+
+	if _, err := preflightRepos(context.Background(), client, []string{"acme/gone"}); err == nil {
+		t.Error("want an error when no repository can be read")
+	}
+}
+
+func TestFetchRunsAPIFilters(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		if query.Get("page") != "1" {
+			fmt.Fprint(w, `{"total_count":2,"workflow_runs":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"total_count":2,"workflow_runs":[
+			{"id":2,"created_at":"2024-03-02T00:00:00Z","actor":{"login":"dependabot[bot]"},"conclusion":"success"},
+			{"id":1,"created_at":"2024-03-01T00:00:00Z","actor":{"login":"octocat"},"conclusion":"failure"}]}`)
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	setFlag(t, actor, "dependabot[bot]")
+	setFlag(t, runConclusion, "success")
+
+	for _, tc := range []struct {
+		name   string
+		since  time.Time
+		limit  int
+		actor  string
+		status string
+		wantID []int64
+	}{
+		// The API would have filtered the runs: all that are listed are kept.
+		{"bounded", time.Time{}, 1000, "dependabot[bot]", "success", []int64{2, 1}},
+		{"since", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 1000, "", "", []int64{2}},
+		{"more than the cap", time.Time{}, 5000, "", "", []int64{2}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			old := windowStart
+			windowStart = tc.since
+			defer func() { windowStart = old }()
+
+			ws, err := fetchRuns(context.Background(), client, "acme/app", github.ListWorkflowRunsOptions{}, tc.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := query.Get("actor"); got != tc.actor {
+				t.Errorf("actor=%q, want %q", got, tc.actor)
+			}
+			if got := query.Get("status"); got != tc.status {
+				t.Errorf("status=%q, want %q", got, tc.status)
+			}
+
+			var ids []int64
+			for _, w := range ws {
+				ids = append(ids, w.GetID())
+			}
+			if !reflect.DeepEqual(ids, tc.wantID) {
+				t.Errorf("got runs %v, want %v", ids, tc.wantID)
+			}
+		})
+	}
+}
+
+func TestFetchRunsCreatedPastCap(t *testing.T) {
+	// 1500 runs, one a minute, listed as the API does: at most 1000 of them.
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		from, to, _ := strings.Cut(q.Get("created"), "..")
+		fromT, _ := time.Parse(time.RFC3339, from)
+		toT, _ := time.Parse(time.RFC3339, to)
+
+		var runs []string
+		for k := 1499; k >= 0 && len(runs) < apiRunCap; k-- {
+			if at := start.Add(time.Duration(k) * time.Minute); !at.Before(fromT) && !at.After(toT) {
+				runs = append(runs, fmt.Sprintf(`{"id":%d,"created_at":%q}`, k+1, at.Format(time.RFC3339)))
+			}
+		}
+
+		page, _ := strconv.Atoi(q.Get("page"))
+		perPage, _ := strconv.Atoi(q.Get("per_page"))
+		runs = runs[min((page-1)*perPage, len(runs)):min(page*perPage, len(runs))]
+		fmt.Fprintf(w, `{"total_count":%d,"workflow_runs":[%s]}`, len(runs), strings.Join(runs, ","))
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	ws, err := fetchRunsCreated(context.Background(), client, "acme/app", start, start.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ws) != 1500 {
+		t.Fatalf("got %d runs, want 1500", len(ws))
+	}
+	for k, w := range ws {
+		if want := int64(1500 - k); w.GetID() != want {
+			t.Fatalf("run %d has ID %d, want %d", k, w.GetID(), want)
+		}
+	}
+}
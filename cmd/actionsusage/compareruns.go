@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	compareFlags    = flag.NewFlagSet("compare-runs", flag.ExitOnError)
+	compareMinDelta = compareFlags.Duration("min_delta", 5*time.Second, "Only list steps whose duration changed by at least this much.")
+)
+
+// durationDelta compares how long a job or step took in two runs. A zero
+// duration means it didn't run.
+type durationDelta struct {
+	Name string
+	A, B time.Duration
+}
+
+func (d durationDelta) Delta() time.Duration {
+	return d.B - d.A
+}
+
+// jobComparison compares a job, and its steps, across two runs.
+type jobComparison struct {
+	durationDelta
+	Steps []durationDelta // Those changed by at least -min_delta.
+}
+
+type runComparison struct {
+	Repository string
+	Workflow   string
+	A, B       *github.WorkflowRun
+	Duration   durationDelta // Of the runs, from the first job's start to the last job's end.
+	Jobs       []jobComparison
+	Culprit    *durationDelta // The step that slowed down the most, if any did.
+	CulpritJob string
+}
+
+func runCompareRuns(ctx context.Context) error {
+	if compareFlags.NArg() != 2 {
+		return errors.New("usage: actionsusage compare-runs -repos owner/repo run_id_a run_id_b")
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if len(repoList) != 1 {
+		return errors.New("compare-runs needs a single repository")
+	}
+
+	owner, name, err := ghclient.SplitRepo(repoList[0])
+	if err != nil {
+		return err
+	}
+
+	var runs [2]*github.WorkflowRun
+	var records [2][]jobRecord
+	for k, arg := range compareFlags.Args() {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad run ID %q", arg)
+		}
+
+		if runs[k], _, err = client.Actions.GetWorkflowRunByID(ctx, owner, name, id); err != nil {
+			return err
+		}
+
+		jobs, _, err := fetchJobs(ctx, client, runs[k], *maxJobs)
+		if err != nil {
+			return err
+		}
+
+		for _, job := range jobs {
+			if job.StartedAt != nil && job.CompletedAt != nil {
+				records[k] = append(records[k], newJobRecord(repoList[0], runs[k], job))
+			}
+		}
+
+		log.Printf("%s: %d: %d jobs", repoList[0], id, len(records[k]))
+	}
+
+	if runs[0].GetWorkflowID() != runs[1].GetWorkflowID() {
+		return fmt.Errorf("runs are of different workflows: %q and %q", runs[0].GetName(), runs[1].GetName())
+	}
+
+	c := compareRuns(records[0], records[1])
+	c.Repository, c.Workflow, c.A, c.B = repoList[0], runs[0].GetName(), runs[0], runs[1]
+
+	return compareMarkdown.Execute(os.Stdout, c)
+}
+
+// compareRuns matches the jobs of two runs by name, and their steps by name
+// and occurrence.
+func compareRuns(a, b []jobRecord) runComparison {
+	var c runComparison
+	c.Duration = durationDelta{Name: "run", A: runSpan(a), B: runSpan(b)}
+
+	byName := map[string]*jobRecord{}
+	for k := range a {
+		byName[a[k].Job] = &a[k]
+	}
+
+	seen := map[string]bool{}
+	for _, rb := range b {
+		seen[rb.Job] = true
+		jc := jobComparison{durationDelta: durationDelta{Name: rb.Job, B: rb.Duration()}}
+
+		var stepsA []stepRecord
+		if ra := byName[rb.Job]; ra != nil {
+			jc.A = ra.Duration()
+			stepsA = ra.Steps
+		}
+
+		for _, d := range compareSteps(stepsA, rb.Steps) {
+			if d.Delta() >= *compareMinDelta || -d.Delta() >= *compareMinDelta {
+				jc.Steps = append(jc.Steps, d)
+			}
+
+			if d.Delta() > 0 && (c.Culprit == nil || d.Delta() > c.Culprit.Delta()) {
+				culprit := d
+				c.Culprit, c.CulpritJob = &culprit, rb.Job
+			}
+		}
+
+		c.Jobs = append(c.Jobs, jc)
+	}
+
+	for _, ra := range a {
+		if !seen[ra.Job] {
+			c.Jobs = append(c.Jobs, jobComparison{durationDelta: durationDelta{Name: ra.Job, A: ra.Duration()}})
+		}
+	}
+
+	return c
+}
+
+func compareSteps(a, b []stepRecord) []durationDelta {
+	key := func(steps []stepRecord, k int) string {
+		n := 0
+		for _, s := range steps[:k] {
+			if s.Name == steps[k].Name {
+				n++
+			}
+		}
+		return fmt.Sprintf("%s#%d", steps[k].Name, n)
+	}
+
+	byKey := map[string]time.Duration{}
+	for k, s := range a {
+		byKey[key(a, k)] = s.End.Sub(s.Start)
+	}
+
+	var res []durationDelta
+	for k, s := range b {
+		d := durationDelta{Name: s.Name, A: byKey[key(b, k)], B: s.End.Sub(s.Start)}
+		delete(byKey, key(b, k))
+		res = append(res, d)
+	}
+
+	for k, s := range a {
+		if _, ok := byKey[key(a, k)]; ok {
+			res = append(res, durationDelta{Name: s.Name, A: s.End.Sub(s.Start)})
+		}
+	}
+
+	return res
+}
+
+func runSpan(records []jobRecord) time.Duration {
+	if len(records) == 0 {
+		return 0
+	}
+
+	start, end := records[0].Start, records[0].End
+	for _, r := range records {
+		if r.Start.Before(start) {
+			start = r.Start
+		}
+		if r.End.After(end) {
+			end = r.End
+		}
+	}
+
+	return end.Sub(start)
+}
+
+var compareMarkdown = template.Must(template.New("compare-runs").Funcs(digestFuncs).Parse(`# {{.Repository}}: {{.Workflow}}: run {{.A.GetID}} vs {{.B.GetID}}
+
+| | [{{.A.GetID}}]({{.A.GetHTMLURL}}) | [{{.B.GetID}}]({{.B.GetHTMLURL}}) | Change |
+|---|---|---|---:|
+| Commit | {{.A.GetHeadSHA}} | {{.B.GetHeadSHA}} | |
+| Conclusion | {{.A.GetConclusion}} | {{.B.GetConclusion}} | |
+| Duration | {{round .Duration.A}} | {{round .Duration.B}} | {{round .Duration.Delta}} |
+{{with .Culprit}}
+The largest slowdown is in {{$.CulpritJob}} / {{.Name}}: {{round .A}} → {{round .B}} (+{{round .Delta}}).
+{{end}}
+## Jobs
+{{range .Jobs}}
+### {{.Name}}: {{round .A}} → {{round .B}} ({{round .Delta}})
+{{if .Steps}}
+| Step | {{$.A.GetID}} | {{$.B.GetID}} | Change |
+|---|---:|---:|---:|
+{{range .Steps}}| {{.Name}} | {{round .A}} | {{round .B}} | {{round .Delta}} |
+{{end}}{{end}}{{end}}`))
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var costCenterPrefix = flag.String("cost_center_topic_prefix", "", "Assign repositories to cost centers by their topics with this prefix, "+
+	"e.g. costcenter- for a costcenter-platform topic, and aggregate minutes by cost center.")
+
+const noCostCenter = "(none)"
+
+// fetchCostCenters maps repositories to the cost center named by their first
+// topic with -cost_center_topic_prefix.
+func fetchCostCenters(ctx context.Context, client *github.Client, repoList []string) (map[string]string, error) {
+	res := map[string]string{}
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return nil, err
+		}
+
+		topics, _, err := client.Repositories.ListAllTopics(ctx, owner, name)
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(topics)
+
+		res[strings.ToLower(reponame)] = noCostCenter
+		for _, t := range topics {
+			if center, ok := strings.CutPrefix(t, *costCenterPrefix); ok && center != "" {
+				res[strings.ToLower(reponame)] = center
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	digestFlags        = flag.NewFlagSet("digest", flag.ExitOnError)
+	digestFormat       = digestFlags.String("format", "markdown", "Output format: markdown or html.")
+	digestOut          = digestFlags.String("out", "", "Write the digest to this file instead of stdout.")
+	digestWeekEnding   = digestFlags.String("week_ending", "", "Last day (YYYY-MM-DD, UTC) of the week to summarize. Defaults to yesterday.")
+	digestTop          = digestFlags.Int("top", 5, "Number of top movers to list.")
+	digestAnomalyRatio = digestFlags.Float64("anomaly_ratio", 2, "Flag workflows whose minutes grew by at least this factor over the prior week.")
+	digestAnomalyMin   = digestFlags.Float64("anomaly_min_minutes", 60, "Ignore minute spikes of workflows below this many minutes in the current week.")
+	digestReleases     = digestFlags.Bool("release_markers", true, "Annotate the daily timeline with releases published in the window.")
+	digestTags         = digestFlags.Bool("tag_markers", false, "Also annotate tags without a release; costs one API call per recent tag.")
+	digestBackfill     = digestFlags.Bool("audit_backfill", false, "Reconstruct runs older than GitHub's run retention from the organization audit log (Enterprise Cloud only).")
+)
+
+const dateLayout = "2006-01-02"
+
+type digestWeek struct {
+	Start, End time.Time // Inclusive days.
+	Minutes    float64
+	Runs       int
+	FailedRuns int
+	Workflows  map[string]*workflowStats
+	OSMinutes  map[string]float64
+
+	// Minutes reconstructed from the audit log, included in Minutes.
+	Reconstructed float64
+}
+
+type workflowStats struct {
+	Minutes    float64
+	Runs       int
+	FailedRuns int
+}
+
+type workflowDelta struct {
+	Workflow          string
+	Previous, Current float64
+	Delta             float64
+}
+
+type digestDay struct {
+	Date      time.Time
+	Minutes   float64
+	Runs      int
+	Spike     bool
+	Markers   []releaseMarker
+	Incidents []string
+}
+
+type digest struct {
+	Repos             []string
+	Current, Previous digestWeek
+	Days              []*digestDay // Both weeks, oldest first.
+	RetentionCutoff   time.Time    // Set if the window extends past GitHub's run retention.
+	MinutesDelta      float64
+	MinutesDeltaPct   string
+	RunsDelta         int
+	TopMovers         []workflowDelta
+	NewWorkflows      []workflowDelta
+	Anomalies         []string
+	LeastEfficient    []runEfficiency   // Runs of the current week.
+	Jobs              []jobRecord       // Jobs of the current week.
+	Timeline          htmltemplate.HTML // Only set for -format=html.
+}
+
+func runDigest(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	end := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	if *digestWeekEnding != "" {
+		end, err = time.Parse(dateLayout, *digestWeekEnding)
+		if err != nil {
+			return fmt.Errorf("bad -week_ending: %w", err)
+		}
+	}
+
+	d := digest{
+		Repos:    repoList,
+		Current:  newDigestWeek(end),
+		Previous: newDigestWeek(end.AddDate(0, 0, -7)),
+	}
+
+	incidents, err := loadIncidents(ctx)
+	if err != nil {
+		return err
+	}
+
+	for t := d.Previous.Start; !t.After(d.Current.End); t = t.AddDate(0, 0, 1) {
+		day := &digestDay{Date: t}
+		for _, i := range incidents {
+			if t.Before(i.End) && t.AddDate(0, 0, 1).After(i.Start) {
+				day.Incidents = append(day.Incidents, i.Title)
+			}
+		}
+		d.Days = append(d.Days, day)
+	}
+
+	for _, reponame := range repoList {
+		runs, err := fetchRunsCreated(ctx, client, reponame, d.Previous.Start, d.Current.End.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+
+		for _, w := range runs {
+			if *incidentMode == "exclude" && incidentDuring(incidents, w.GetRunStartedAt().Time, w.GetUpdatedAt().Time) != "" {
+				continue
+			}
+
+			week := &d.Previous
+			if !w.CreatedAt.Time.Before(d.Current.Start) {
+				week = &d.Current
+			}
+
+			jobs, _, err := fetchJobs(ctx, client, w, *maxJobs)
+			if err != nil {
+				return err
+			}
+
+			minutes := runMinutes(jobs)
+			for _, job := range jobs {
+				if job.CompletedAt != nil && job.StartedAt != nil {
+					week.OSMinutes[billedOS(job.Labels)] += jobMinutes(job)
+					if week == &d.Current {
+						d.Jobs = append(d.Jobs, newJobRecord(reponame, w, job))
+					}
+				}
+			}
+
+			week.add(reponame+": "+w.GetName(), minutes, w.GetConclusion() == "failure")
+			if day := d.day(w.CreatedAt.Time); day != nil {
+				day.Minutes += minutes
+				day.Runs++
+			}
+		}
+
+		if *digestReleases {
+			markers, err := fetchReleaseMarkers(ctx, client, reponame, d.Previous.Start, d.Current.End.AddDate(0, 0, 1), *digestTags)
+			if err != nil {
+				return err
+			}
+
+			for _, m := range markers {
+				if day := d.day(m.At); day != nil {
+					day.Markers = append(day.Markers, m)
+				}
+			}
+		}
+	}
+
+	if cutoff := retentionCutoff(); d.Previous.Start.Before(cutoff) {
+		d.RetentionCutoff = cutoff.UTC().Truncate(24 * time.Hour)
+		log.Printf("digest: runs before %s are past GitHub's retention", d.RetentionCutoff.Format(dateLayout))
+
+		if *digestBackfill {
+			if err := d.backfill(ctx, client, repoList, cutoff); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.compute(*digestTop, *digestAnomalyRatio, *digestAnomalyMin)
+
+	var out io.Writer = os.Stdout
+	if *digestOut != "" {
+		f, err := os.Create(*digestOut)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+		out = f
+	}
+
+	switch *digestFormat {
+	case "markdown":
+		return digestMarkdown.Execute(out, d)
+	case "html":
+		if d.Timeline, err = renderTimeline(d.Jobs); err != nil {
+			return err
+		}
+		return digestHTML.Execute(out, d)
+	default:
+		return fmt.Errorf("unsupported -format %q", *digestFormat)
+	}
+}
+
+func newDigestWeek(end time.Time) digestWeek {
+	return digestWeek{Start: end.AddDate(0, 0, -6), End: end, Workflows: map[string]*workflowStats{}, OSMinutes: map[string]float64{}}
+}
+
+func (w *digestWeek) add(workflow string, minutes float64, failed bool) {
+	stats := w.Workflows[workflow]
+	if stats == nil {
+		stats = &workflowStats{}
+		w.Workflows[workflow] = stats
+	}
+
+	w.Minutes += minutes
+	w.Runs++
+	stats.Minutes += minutes
+	stats.Runs++
+	if failed {
+		w.FailedRuns++
+		stats.FailedRuns++
+	}
+}
+
+type osRow struct {
+	OS                string
+	Current, Previous float64
+	CostShare         string // Of the current week.
+}
+
+// OSRows splits the minutes of both weeks by runner OS, with those of
+// self-hosted runners in their own row.
+func (d digest) OSRows() []osRow {
+	var weighted float64
+	for os, m := range d.Current.OSMinutes {
+		weighted += m * osMultipliers[os]
+	}
+
+	var rows []osRow
+	for _, os := range osOrder {
+		row := osRow{OS: os, Current: d.Current.OSMinutes[os], Previous: d.Previous.OSMinutes[os], CostShare: "n/a"}
+		if weighted > 0 {
+			row.CostShare = fmt.Sprintf("%.1f%%", 100*row.Current*osMultipliers[os]/weighted)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// backfill adds the runs of repoList that completed before cutoff, as
+// recorded by the audit log of their owners.
+func (d *digest) backfill(ctx context.Context, client *github.Client, repoList []string, cutoff time.Time) error {
+	wanted := map[string]bool{}
+	var orgs []string
+	for _, reponame := range repoList {
+		owner, _, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(orgs, owner) {
+			orgs = append(orgs, owner)
+		}
+
+		wanted[reponame] = true
+	}
+
+	for _, org := range orgs {
+		runs, err := fetchAuditLogRuns(ctx, client, org, d.Previous.Start, cutoff)
+		if err != nil {
+			return fmt.Errorf("%s: audit log: %w", org, err)
+		}
+
+		for _, run := range runs {
+			if !wanted[run.Repository] {
+				continue
+			}
+
+			week := &d.Previous
+			if !run.Started.Before(d.Current.Start) {
+				week = &d.Current
+			}
+
+			week.add(run.Repository+": "+run.Workflow, run.Minutes(), run.Conclusion == "failure")
+			week.Reconstructed += run.Minutes()
+			if day := d.day(run.Started); day != nil {
+				day.Minutes += run.Minutes()
+				day.Runs++
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reconstructed returns the minutes of both weeks that came from the audit log.
+func (d digest) Reconstructed() float64 {
+	return d.Current.Reconstructed + d.Previous.Reconstructed
+}
+
+func (d *digest) day(t time.Time) *digestDay {
+	k := int(t.UTC().Sub(d.Previous.Start) / (24 * time.Hour))
+	if k < 0 || k >= len(d.Days) {
+		return nil
+	}
+
+	return d.Days[k]
+}
+
+func (d *digest) compute(top int, anomalyRatio, anomalyMin float64) {
+	d.MinutesDelta = d.Current.Minutes - d.Previous.Minutes
+	d.MinutesDeltaPct = percentChange(d.Previous.Minutes, d.Current.Minutes)
+	d.RunsDelta = d.Current.Runs - d.Previous.Runs
+
+	d.LeastEfficient = scoreRuns(d.Jobs)
+	if len(d.LeastEfficient) > *topInefficient {
+		d.LeastEfficient = d.LeastEfficient[:*topInefficient]
+	}
+
+	var movers []workflowDelta
+	for name, cur := range d.Current.Workflows {
+		prev := d.Previous.Workflows[name]
+		if prev == nil {
+			d.NewWorkflows = append(d.NewWorkflows, workflowDelta{Workflow: name, Current: cur.Minutes, Delta: cur.Minutes})
+			continue
+		}
+
+		movers = append(movers, workflowDelta{Workflow: name, Previous: prev.Minutes, Current: cur.Minutes, Delta: cur.Minutes - prev.Minutes})
+
+		if prev.Minutes > 0 && cur.Minutes >= anomalyMin && cur.Minutes >= anomalyRatio*prev.Minutes {
+			d.Anomalies = append(d.Anomalies, fmt.Sprintf("%s: minutes grew from %s to %s (%s)", name, formatMinutes(prev.Minutes), formatMinutes(cur.Minutes), percentChange(prev.Minutes, cur.Minutes)))
+		}
+
+		if cur.Runs >= 5 && failureRate(cur) >= failureRate(prev)+0.25 {
+			d.Anomalies = append(d.Anomalies, fmt.Sprintf("%s: failure rate rose from %.0f%% to %.0f%%", name, 100*failureRate(prev), 100*failureRate(cur)))
+		}
+	}
+
+	for name, prev := range d.Previous.Workflows {
+		if d.Current.Workflows[name] == nil {
+			movers = append(movers, workflowDelta{Workflow: name, Previous: prev.Minutes, Delta: -prev.Minutes})
+		}
+	}
+
+	sort.Slice(movers, func(i, j int) bool {
+		if math.Abs(movers[i].Delta) != math.Abs(movers[j].Delta) {
+			return math.Abs(movers[i].Delta) > math.Abs(movers[j].Delta)
+		}
+		return movers[i].Workflow < movers[j].Workflow
+	})
+
+	if len(movers) > top {
+		movers = movers[:top]
+	}
+
+	d.TopMovers = movers
+
+	sort.Slice(d.NewWorkflows, func(i, j int) bool { return d.NewWorkflows[i].Current > d.NewWorkflows[j].Current })
+
+	if len(d.Days) > 0 {
+		mean := (d.Current.Minutes + d.Previous.Minutes) / float64(len(d.Days))
+		for _, day := range d.Days {
+			if mean > 0 && day.Minutes >= anomalyRatio*mean {
+				day.Spike = true
+
+				msg := fmt.Sprintf("%s: %s minutes, %.1fx the daily average", day.Date.Format(dateLayout), formatMinutes(day.Minutes), day.Minutes/mean)
+				if len(day.Markers) > 0 {
+					msg += fmt.Sprintf(" (releases: %v)", day.Markers)
+				}
+				d.Anomalies = append(d.Anomalies, msg)
+			}
+		}
+	}
+
+	sort.Strings(d.Anomalies)
+
+	log.Printf("digest: %s..%s: %s minutes (%s) across %d runs", d.Current.Start.Format(dateLayout), d.Current.End.Format(dateLayout), formatMinutes(d.Current.Minutes), d.MinutesDeltaPct, d.Current.Runs)
+}
+
+func failureRate(s *workflowStats) float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+
+	return float64(s.FailedRuns) / float64(s.Runs)
+}
+
+func percentChange(prev, cur float64) string {
+	if prev == 0 {
+		return "n/a"
+	}
+
+	return fmt.Sprintf("%+.1f%%", 100*(cur-prev)/prev)
+}
+
+var digestFuncs = map[string]any{
+	"date":    func(t time.Time) string { return t.Format(dateLayout) },
+	"minutes": formatMinutes,
+	"round":   func(d time.Duration) time.Duration { return d.Round(time.Second) },
+}
+
+var digestMarkdown = template.Must(template.New("digest").Funcs(digestFuncs).Parse(`# CI usage digest: {{date .Current.Start}} – {{date .Current.End}}
+
+| | This week | Prior week | Change |
+|---|---:|---:|---:|
+| Minutes | {{minutes .Current.Minutes}} | {{minutes .Previous.Minutes}} | {{minutes .MinutesDelta}} ({{.MinutesDeltaPct}}) |
+| Runs | {{.Current.Runs}} | {{.Previous.Runs}} | {{.RunsDelta}} |
+| Failed runs | {{.Current.FailedRuns}} | {{.Previous.FailedRuns}} | |
+{{range .OSRows}}| {{.OS}} minutes | {{minutes .Current}} | {{minutes .Previous}} | {{.CostShare}} of cost |
+{{end}}{{if not .RetentionCutoff.IsZero}}
+> Runs before {{date .RetentionCutoff}} are past GitHub's retention{{if .Reconstructed}}; {{minutes .Reconstructed}} minutes were reconstructed from the audit log (run wall-clock time, not billed job minutes) and are approximate{{else}} and are missing from these figures{{end}}.
+{{end}}
+## Daily timeline
+
+| Day | Minutes | Runs | Releases | Incidents |
+|---|---:|---:|---|---|
+{{range .Days}}| {{date .Date}}{{if .Spike}} ⚠{{end}} | {{minutes .Minutes}} | {{.Runs}} | {{range $i, $m := .Markers}}{{if $i}}, {{end}}{{$m}}{{end}} | {{range $i, $t := .Incidents}}{{if $i}}, {{end}}{{$t}}{{end}} |
+{{end}}{{if .TopMovers}}
+## Top movers
+{{range .TopMovers}}
+- {{.Workflow}}: {{minutes .Previous}} → {{minutes .Current}} min ({{printf "%+.0f" .Delta}})
+{{- end}}
+{{end}}{{if .NewWorkflows}}
+## New workflows
+{{range .NewWorkflows}}
+- {{.Workflow}}: {{minutes .Current}} min
+{{- end}}
+{{end}}{{if .Anomalies}}
+## Anomalies
+{{range .Anomalies}}
+- {{.}}
+{{- end}}
+{{end}}{{if .LeastEfficient}}
+## Least efficient runs
+
+| Run | Score | Runner time | Idle | Setup | Cancelled |
+|---|---:|---:|---:|---:|---:|
+{{range .LeastEfficient}}| [{{.Repository}}: {{.Workflow}}]({{.URL}}) | {{printf "%.0f" .Score}} | {{round .Runner}} | {{round .Idle}} | {{round .Setup}} | {{round .Cancelled}} |
+{{end}}{{end}}`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest").Funcs(digestFuncs).Parse(`<h1>CI usage digest: {{date .Current.Start}} – {{date .Current.End}}</h1>
+<table>
+<tr><th></th><th>This week</th><th>Prior week</th><th>Change</th></tr>
+<tr><td>Minutes</td><td>{{minutes .Current.Minutes}}</td><td>{{minutes .Previous.Minutes}}</td><td>{{minutes .MinutesDelta}} ({{.MinutesDeltaPct}})</td></tr>
+<tr><td>Runs</td><td>{{.Current.Runs}}</td><td>{{.Previous.Runs}}</td><td>{{.RunsDelta}}</td></tr>
+<tr><td>Failed runs</td><td>{{.Current.FailedRuns}}</td><td>{{.Previous.FailedRuns}}</td><td></td></tr>{{range .OSRows}}
+<tr><td>{{.OS}} minutes</td><td>{{minutes .Current}}</td><td>{{minutes .Previous}}</td><td>{{.CostShare}} of cost</td></tr>{{end}}
+</table>
+{{if not .RetentionCutoff.IsZero}}<p>Runs before {{date .RetentionCutoff}} are past GitHub's retention{{if .Reconstructed}}; {{minutes .Reconstructed}} minutes were reconstructed from the audit log (run wall-clock time, not billed job minutes) and are approximate{{else}} and are missing from these figures{{end}}.</p>
+{{end}}<h2>Daily timeline</h2>
+<table>
+<tr><th>Day</th><th>Minutes</th><th>Runs</th><th>Releases</th><th>Incidents</th></tr>{{range .Days}}
+<tr><td>{{date .Date}}{{if .Spike}} ⚠{{end}}</td><td>{{minutes .Minutes}}</td><td>{{.Runs}}</td><td>{{range $i, $m := .Markers}}{{if $i}}, {{end}}{{$m}}{{end}}</td><td>{{range $i, $t := .Incidents}}{{if $i}}, {{end}}{{$t}}{{end}}</td></tr>{{end}}
+</table>
+{{if .TopMovers}}<h2>Top movers</h2>
+<ul>{{range .TopMovers}}
+<li>{{.Workflow}}: {{minutes .Previous}} → {{minutes .Current}} min ({{printf "%+.0f" .Delta}})</li>{{end}}
+</ul>
+{{end}}{{if .NewWorkflows}}<h2>New workflows</h2>
+<ul>{{range .NewWorkflows}}
+<li>{{.Workflow}}: {{minutes .Current}} min</li>{{end}}
+</ul>
+{{end}}{{if .Anomalies}}<h2>Anomalies</h2>
+<ul>{{range .Anomalies}}
+<li>{{.}}</li>{{end}}
+</ul>
+{{end}}{{if .LeastEfficient}}<h2>Least efficient runs</h2>
+<table>
+<tr><th>Run</th><th>Score</th><th>Runner time</th><th>Idle</th><th>Setup</th><th>Cancelled</th></tr>{{range .LeastEfficient}}
+<tr><td><a href="{{.URL}}">{{.Repository}}: {{.Workflow}}</a></td><td>{{printf "%.0f" .Score}}</td><td>{{round .Runner}}</td><td>{{round .Idle}}</td><td>{{round .Setup}}</td><td>{{round .Cancelled}}</td></tr>{{end}}
+</table>
+{{end}}{{if .Jobs}}<h2>Job timeline</h2>
+{{.Timeline}}
+{{end}}`))
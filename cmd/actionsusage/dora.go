@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	doraFlags       = flag.NewFlagSet("dora", flag.ExitOnError)
+	doraEnvironment = doraFlags.String("environment", "production", "Deployment environment whose deployments are measured.")
+	doraWorkflow    = doraFlags.String("deploy_workflow", "", "Name of the workflow that deploys, for repositories that don't create Deployments; its completed runs count as deployments.")
+	doraWindow      = doraFlags.Duration("window", 30*24*time.Hour, "How far back to look for deployments.")
+)
+
+// deployment is a deployment attempt of a commit.
+type deployment struct {
+	SHA    string
+	At     time.Time
+	Failed bool
+}
+
+// doraMetrics are the DORA delivery metrics of a repository.
+type doraMetrics struct {
+	Repository  string
+	Source      string // deployments or the workflow name.
+	Deployments int    // Successful ones.
+	Failed      int
+	Rollbacks   int             // Redeployments of an earlier commit.
+	LeadTimes   []time.Duration // From commit to deployment, per commit; sorted.
+}
+
+func (m doraMetrics) PerWeek() float64 {
+	return float64(m.Deployments) / doraWindow.Hours() * 24 * 7
+}
+
+func (m doraMetrics) MedianLeadTime() time.Duration {
+	return percentile(m.LeadTimes, 0.5)
+}
+
+func (m doraMetrics) P90LeadTime() time.Duration {
+	return percentile(m.LeadTimes, 0.9)
+}
+
+// ChangeFailureRate is the share of deployments that failed or were rolled
+// back.
+func (m doraMetrics) ChangeFailureRate() float64 {
+	if total := m.Deployments + m.Failed; total > 0 {
+		return float64(m.Failed+m.Rollbacks) / float64(total)
+	}
+
+	return 0
+}
+
+func (m doraMetrics) ChangeFailurePercent() float64 {
+	return 100 * m.ChangeFailureRate()
+}
+
+func runDORA(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().Add(-*doraWindow)
+
+	var metrics []doraMetrics
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		m := doraMetrics{Repository: reponame, Source: "deployments"}
+
+		var deploys []deployment
+		if *doraWorkflow != "" {
+			m.Source = *doraWorkflow
+			deploys, err = workflowDeployments(ctx, client, reponame, *doraWorkflow, since)
+		} else {
+			deploys, err = fetchDeployments(ctx, client, owner, name, *doraEnvironment, since)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", reponame, err)
+		}
+
+		sort.Slice(deploys, func(i, j int) bool { return deploys[i].At.Before(deploys[j].At) })
+
+		deployed := map[string]bool{}
+		var previous string
+		for _, d := range deploys {
+			if d.Failed {
+				m.Failed++
+				continue
+			}
+
+			m.Deployments++
+			if deployed[d.SHA] && d.SHA != previous {
+				m.Rollbacks++
+			} else if !deployed[d.SHA] {
+				leadTimes, err := commitLeadTimes(ctx, client, owner, name, previous, d)
+				if err != nil {
+					return fmt.Errorf("%s: %w", reponame, err)
+				}
+				m.LeadTimes = append(m.LeadTimes, leadTimes...)
+			}
+
+			deployed[d.SHA] = true
+			previous = d.SHA
+		}
+
+		sort.Slice(m.LeadTimes, func(i, j int) bool { return m.LeadTimes[i] < m.LeadTimes[j] })
+
+		log.Printf("%s: %d deployments, %d failed, %d rollbacks", reponame, m.Deployments, m.Failed, m.Rollbacks)
+		metrics = append(metrics, m)
+	}
+
+	return doraMarkdown.Execute(os.Stdout, metrics)
+}
+
+// fetchDeployments returns the deployments to an environment created since.
+// A deployment counts as done when it first reported success, and as failed if
+// it never did but reported a failure or error.
+func fetchDeployments(ctx context.Context, client *github.Client, owner, name, environment string, since time.Time) ([]deployment, error) {
+	var res []deployment
+	opts := &github.DeploymentsListOptions{Environment: environment, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		deployments, r, err := client.Repositories.ListDeployments(ctx, owner, name, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range deployments {
+			if d.GetCreatedAt().Before(since) {
+				return res, nil
+			}
+
+			statuses, _, err := client.Repositories.ListDeploymentStatuses(ctx, owner, name, d.GetID(), &github.ListOptions{PerPage: 100})
+			if err != nil {
+				return nil, err
+			}
+
+			dep := deployment{SHA: d.GetSHA()}
+			// Statuses are listed newest first.
+			for _, s := range statuses {
+				switch s.GetState() {
+				case "success":
+					dep.At, dep.Failed = s.GetCreatedAt().Time, false
+				case "failure", "error":
+					if dep.At.IsZero() {
+						dep.At, dep.Failed = s.GetCreatedAt().Time, true
+					}
+				}
+			}
+
+			if !dep.At.IsZero() {
+				res = append(res, dep)
+			}
+		}
+
+		if r.NextPage == 0 {
+			return res, nil
+		}
+		opts.Page = r.NextPage
+	}
+}
+
+// workflowDeployments returns the completed runs of a deploy workflow since a
+// point in time as deployments.
+func workflowDeployments(ctx context.Context, client *github.Client, reponame, workflow string, since time.Time) ([]deployment, error) {
+	runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{
+		Status:  "completed",
+		Created: ">=" + since.Format(time.RFC3339),
+	}, *runCount)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []deployment
+	for _, w := range runs {
+		if w.GetName() != workflow || (w.GetConclusion() != "success" && w.GetConclusion() != "failure") {
+			continue
+		}
+
+		res = append(res, deployment{SHA: w.GetHeadSHA(), At: w.GetUpdatedAt().Time, Failed: w.GetConclusion() == "failure"})
+	}
+
+	return res, nil
+}
+
+// commitLeadTimes returns the time from commit to deployment of each commit
+// a deployment shipped since the previously deployed commit (or of only the
+// deployed commit, for the first one).
+func commitLeadTimes(ctx context.Context, client *github.Client, owner, name, previous string, d deployment) ([]time.Duration, error) {
+	var commits []*github.RepositoryCommit
+	if previous == "" {
+		c, _, err := client.Repositories.GetCommit(ctx, owner, name, d.SHA, nil)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	} else {
+		cmp, _, err := client.Repositories.CompareCommits(ctx, owner, name, previous, d.SHA, &github.ListOptions{PerPage: 100})
+		if err != nil {
+			return nil, err
+		}
+		commits = cmp.Commits
+	}
+
+	var res []time.Duration
+	for _, c := range commits {
+		if at := c.GetCommit().GetCommitter().GetDate(); !at.IsZero() && at.Before(d.At) {
+			res = append(res, d.At.Sub(at.Time))
+		}
+	}
+
+	return res, nil
+}
+
+var doraMarkdown = template.Must(template.New("dora").Funcs(template.FuncMap{
+	"round": func(d time.Duration) time.Duration { return d.Round(time.Minute) },
+}).Parse(`# Delivery metrics
+
+| Repository | Source | Deployments | Per week | Median lead time | p90 lead time | Failed | Rollbacks | Change failure rate |
+|---|---|---:|---:|---:|---:|---:|---:|---:|
+{{range .}}| {{.Repository}} | {{.Source}} | {{.Deployments}} | {{printf "%.1f" .PerWeek}} | {{round .MedianLeadTime}} | {{round .P90LeadTime}} | {{.Failed}} | {{.Rollbacks}} | {{printf "%.1f%%" .ChangeFailurePercent}} |
+{{end}}`))
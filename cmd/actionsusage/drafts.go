@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	draftsFlags    = flag.NewFlagSet("drafts", flag.ExitOnError)
+	draftsKeepJobs = draftsFlags.String("keep_jobs", "(?i)lint|fmt|format|check", "Regular expression of job names that would keep running on drafts when reducing, rather than skipping, their CI.")
+	draftsMinShare = draftsFlags.Float64("min_share", 0.05, "Repositories whose draft runs account for less than this share of pull request minutes get no recommendation.")
+)
+
+// draftSpend is the share of a repository's pull request minutes consumed by
+// runs that happened while their pull request was a draft.
+type draftSpend struct {
+	Repository   string
+	Runs         int     // Pull request runs.
+	Unresolved   int     // Runs whose pull request couldn't be determined, e.g. from forks.
+	DraftRuns    int     // Runs while the pull request was a draft.
+	Minutes      float64 // Of all pull request runs.
+	DraftMinutes float64
+	KeptMinutes  float64 // Of draft runs' jobs matching -keep_jobs.
+}
+
+func (d draftSpend) Share() float64 {
+	if d.Minutes == 0 {
+		return 0
+	}
+
+	return d.DraftMinutes / d.Minutes
+}
+
+func (d draftSpend) SharePercent() float64 {
+	return 100 * d.Share()
+}
+
+// ReduceSavings are the minutes saved by only running jobs matching -keep_jobs
+// on drafts.
+func (d draftSpend) ReduceSavings() float64 {
+	return d.DraftMinutes - d.KeptMinutes
+}
+
+func (d draftSpend) Recommendation() string {
+	switch {
+	case d.DraftRuns == 0 || d.Share() < *draftsMinShare:
+		return "no change needed"
+
+	case d.KeptMinutes > 0 && d.ReduceSavings() >= 0.5*d.DraftMinutes:
+		return fmt.Sprintf("run only lightweight jobs on drafts (saves %s minutes), and the rest on `ready_for_review`", formatMinutes(d.ReduceSavings()))
+
+	default:
+		return fmt.Sprintf("skip CI on drafts with `if: github.event.pull_request.draft == false` and trigger on `ready_for_review` (saves %s minutes)", formatMinutes(d.DraftMinutes))
+	}
+}
+
+func runDrafts(ctx context.Context) error {
+	keep, err := regexp.Compile(*draftsKeepJobs)
+	if err != nil {
+		return fmt.Errorf("-keep_jobs: %w", err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var spend []draftSpend
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{Event: "pull_request"}, *runCount)
+		if err != nil {
+			return err
+		}
+
+		d := draftSpend{Repository: reponame}
+		periods := map[int][]draftPeriod{}
+		for _, w := range runs {
+			if w.GetStatus() != "completed" {
+				continue
+			}
+
+			jobs, _, err := fetchJobs(ctx, client, w, *maxJobs)
+			if err != nil {
+				return err
+			}
+
+			minutes := runMinutes(jobs)
+			d.Runs++
+			d.Minutes += minutes
+
+			if len(w.PullRequests) == 0 {
+				d.Unresolved++
+				continue
+			}
+
+			number := w.PullRequests[0].GetNumber()
+			if _, ok := periods[number]; !ok {
+				if periods[number], err = fetchDraftPeriods(ctx, client, owner, name, number); err != nil {
+					return fmt.Errorf("%s#%d: %w", reponame, number, err)
+				}
+			}
+
+			if !wasDraft(periods[number], w.GetCreatedAt().Time) {
+				continue
+			}
+
+			d.DraftRuns++
+			d.DraftMinutes += minutes
+			for _, job := range jobs {
+				if job.CompletedAt != nil && job.StartedAt != nil && keep.MatchString(job.GetName()) {
+					d.KeptMinutes += jobMinutes(job)
+				}
+			}
+		}
+
+		log.Printf("%s: %d of %d pull request runs on drafts (%s of %s minutes), %d unresolved", reponame,
+			d.DraftRuns, d.Runs, formatMinutes(d.DraftMinutes), formatMinutes(d.Minutes), d.Unresolved)
+		spend = append(spend, d)
+	}
+
+	sort.SliceStable(spend, func(i, j int) bool { return spend[i].DraftMinutes > spend[j].DraftMinutes })
+
+	return draftsMarkdown.Execute(os.Stdout, spend)
+}
+
+// draftPeriod is an interval during which a pull request was a draft. A zero
+// End means it still is.
+type draftPeriod struct {
+	Start, End time.Time
+}
+
+// fetchDraftPeriods reconstructs when a pull request was a draft from its
+// ready_for_review and convert_to_draft events.
+func fetchDraftPeriods(ctx context.Context, client *github.Client, owner, name string, number int) ([]draftPeriod, error) {
+	pr, _, err := client.PullRequests.Get(ctx, owner, name, number)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*github.Timeline
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, r, err := client.Issues.ListIssueTimeline(ctx, owner, name, number, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range page {
+			if e.GetEvent() == "ready_for_review" || e.GetEvent() == "convert_to_draft" {
+				events = append(events, e)
+			}
+		}
+
+		if r.NextPage == 0 {
+			break
+		}
+		opts.Page = r.NextPage
+	}
+
+	// Without events, the pull request has been in its current state since it was opened.
+	draft := pr.GetDraft()
+	if len(events) > 0 {
+		draft = events[0].GetEvent() == "ready_for_review"
+	}
+
+	var periods []draftPeriod
+	start := pr.GetCreatedAt().Time
+	for _, e := range events {
+		at := e.GetCreatedAt().Time
+		if draft && e.GetEvent() == "ready_for_review" {
+			periods = append(periods, draftPeriod{Start: start, End: at})
+			draft = false
+		} else if !draft && e.GetEvent() == "convert_to_draft" {
+			start, draft = at, true
+		}
+	}
+
+	if draft {
+		periods = append(periods, draftPeriod{Start: start})
+	}
+
+	return periods, nil
+}
+
+func wasDraft(periods []draftPeriod, t time.Time) bool {
+	for _, p := range periods {
+		if !t.Before(p.Start) && (p.End.IsZero() || t.Before(p.End)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var draftsMarkdown = template.Must(template.New("drafts").Funcs(digestFuncs).Parse(`# Draft pull request CI spend
+
+| Repository | PR runs | Draft runs | Unresolved | PR minutes | Draft minutes | Share | Skip savings | Reduce savings | Recommendation |
+|---|---:|---:|---:|---:|---:|---:|---:|---:|---|
+{{range .}}| {{.Repository}} | {{.Runs}} | {{.DraftRuns}} | {{.Unresolved}} | {{minutes .Minutes}} | {{minutes .DraftMinutes}} | {{printf "%.1f%%" .SharePercent}} | {{minutes .DraftMinutes}} | {{minutes .ReduceSavings}} | {{.Recommendation}} |
+{{end}}
+Skipping CI on drafts saves all of their minutes; reducing it keeps jobs matching ` + "`-keep_jobs`" + `. Runs from forks aren't linked to their pull request and are counted as unresolved.
+`))
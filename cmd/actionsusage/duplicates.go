@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	duplicateSimilarity = flag.Float64("duplicate_similarity", 0.8, "Jobs of different workflows running on the same commit and event whose steps overlap at least this much (Jaccard similarity of step names) count as duplicates.")
+	duplicateMinSteps   = flag.Int("duplicate_min_steps", 3, "Only compare jobs with at least this many steps, besides the ones GitHub adds.")
+)
+
+// duplicateJobs is a pair of jobs in different workflows that run
+// substantially the same steps on the same events, typically after a workflow
+// was copied and both kept evolving.
+type duplicateJobs struct {
+	Repository  string    `json:"repo"`
+	Event       string    `json:"event"`
+	Workflows   [2]string `json:"workflows"`
+	Jobs        [2]string `json:"jobs"`
+	Similarity  float64   `json:"similarity"` // The lowest seen.
+	Occurrences int       `json:"occurrences"`
+	Minutes     float64   `json:"minutes"` // Of the cheaper job of each pair, which would be saved by dropping it.
+}
+
+func (d duplicateJobs) String() string {
+	return fmt.Sprintf("%s: on %s, %s / %s duplicates %s / %s (%.0f%% similar, %d times): %s minutes",
+		d.Repository, d.Event, d.Workflows[1], d.Jobs[1], d.Workflows[0], d.Jobs[0], 100*d.Similarity, d.Occurrences, formatMinutes(d.Minutes))
+}
+
+// isGeneratedStep returns whether GitHub adds the step to every job.
+func isGeneratedStep(name string) bool {
+	return name == "Set up job" || name == "Complete job" || strings.HasPrefix(name, "Post ")
+}
+
+func stepNames(r jobRecord) map[string]bool {
+	names := map[string]bool{}
+	for _, s := range r.Steps {
+		if !isGeneratedStep(s.Name) {
+			names[s.Name] = true
+		}
+	}
+
+	return names
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	common := 0
+	for k := range a {
+		if b[k] {
+			common++
+		}
+	}
+
+	if union := len(a) + len(b) - common; union > 0 {
+		return float64(common) / float64(union)
+	}
+
+	return 0
+}
+
+// detectDuplicateJobs returns the pairs of duplicate jobs across workflows,
+// the most expensive first. Each job is paired at most once per commit, with
+// its most similar counterpart.
+func detectDuplicateJobs(records []jobRecord) []duplicateJobs {
+	type trigger struct{ repo, commit, event string }
+	byTrigger := map[trigger][]jobRecord{}
+	for _, r := range records {
+		if r.Commit != "" {
+			t := trigger{r.Repository, r.Commit, r.Event}
+			byTrigger[t] = append(byTrigger[t], r)
+		}
+	}
+
+	type key struct{ repo, event, workflowA, jobA, workflowB, jobB string }
+	dups := map[key]*duplicateJobs{}
+
+	for t, jobs := range byTrigger {
+		steps := make([]map[string]bool, len(jobs))
+		for k, r := range jobs {
+			steps[k] = stepNames(r)
+		}
+
+		type candidate struct {
+			a, b       int
+			similarity float64
+		}
+
+		var candidates []candidate
+		for a := range jobs {
+			for b := a + 1; b < len(jobs); b++ {
+				if jobs[a].Workflow == jobs[b].Workflow || len(steps[a]) < *duplicateMinSteps || len(steps[b]) < *duplicateMinSteps {
+					continue
+				}
+
+				if s := jaccard(steps[a], steps[b]); s >= *duplicateSimilarity {
+					candidates = append(candidates, candidate{a, b, s})
+				}
+			}
+		}
+
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].similarity > candidates[j].similarity })
+
+		paired := map[int]bool{}
+		for _, c := range candidates {
+			if paired[c.a] || paired[c.b] {
+				continue
+			}
+			paired[c.a], paired[c.b] = true, true
+
+			a, b := jobs[c.a], jobs[c.b]
+			if a.Workflow+a.Job > b.Workflow+b.Job {
+				a, b = b, a
+			}
+
+			k := key{t.repo, t.event, a.Workflow, a.Job, b.Workflow, b.Job}
+			d := dups[k]
+			if d == nil {
+				d = &duplicateJobs{
+					Repository: t.repo,
+					Event:      t.event,
+					Workflows:  [2]string{a.Workflow, b.Workflow},
+					Jobs:       [2]string{a.Job, b.Job},
+					Similarity: c.similarity,
+				}
+				dups[k] = d
+			}
+
+			d.Occurrences++
+			d.Similarity = min(d.Similarity, c.similarity)
+			d.Minutes += min(a.Minutes, b.Minutes)
+		}
+	}
+
+	var res []duplicateJobs
+	for _, d := range dups {
+		res = append(res, *d)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Minutes != res[j].Minutes {
+			return res[i].Minutes > res[j].Minutes
+		}
+		return res[i].String() < res[j].String()
+	})
+
+	return res
+}
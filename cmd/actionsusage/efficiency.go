@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var topInefficient = flag.Int("top_inefficient", 10, "Number of least efficient runs to log and list in the digest.")
+
+// runEfficiency scores a run by how much of its time did useful work.
+type runEfficiency struct {
+	Repository string        `json:"repo"`
+	Workflow   string        `json:"workflow"`
+	RunID      int64         `json:"run_id"`
+	URL        string        `json:"url"`
+	Runner     time.Duration `json:"runner"`    // Total time of its jobs.
+	Idle       time.Duration `json:"idle"`      // Between its first and last job, while none ran, e.g. waiting on dependencies.
+	Setup      time.Duration `json:"setup"`     // Setting up and tearing down jobs that weren't cancelled.
+	Cancelled  time.Duration `json:"cancelled"` // Jobs cancelled, e.g. when a sibling failed.
+	Score      float64       `json:"score"`     // Useful share of Runner plus Idle, 0 to 100.
+}
+
+func (e runEfficiency) String() string {
+	return fmt.Sprintf("%s: %s: score %.0f (%v runner time, %v idle, %v setup, %v cancelled): %s", e.Repository, e.Workflow, e.Score,
+		e.Runner.Round(time.Second), e.Idle.Round(time.Second), e.Setup.Round(time.Second), e.Cancelled.Round(time.Second), e.URL)
+}
+
+// isSetupStep returns whether a step only prepares or cleans up a job.
+func isSetupStep(name string) bool {
+	return isGeneratedStep(name) || strings.HasPrefix(name, "Run actions/checkout") || strings.HasPrefix(name, "Run actions/setup-")
+}
+
+// scoreRuns returns the efficiency of each run, the least efficient first.
+func scoreRuns(records []jobRecord) []runEfficiency {
+	// Attempts are scored separately, so that the time between them isn't idle.
+	type attempt struct {
+		run, attempt int64
+	}
+
+	byRun := map[attempt][]jobRecord{}
+	for _, r := range records {
+		byRun[attempt{r.RunID, r.Attempt}] = append(byRun[attempt{r.RunID, r.Attempt}], r)
+	}
+
+	var res []runEfficiency
+	for a, jobs := range byRun {
+		sort.Slice(jobs, func(i, j int) bool { return jobs[i].Start.Before(jobs[j].Start) })
+
+		e := runEfficiency{
+			Repository: jobs[0].Repository,
+			Workflow:   jobs[0].Workflow,
+			RunID:      a.run,
+			URL:        fmt.Sprintf("https://github.com/%s/actions/runs/%d/attempts/%d", jobs[0].Repository, a.run, max(a.attempt, 1)),
+		}
+
+		busyUntil := jobs[0].Start
+		for _, r := range jobs {
+			e.Runner += r.Duration()
+			if r.Start.After(busyUntil) {
+				e.Idle += r.Start.Sub(busyUntil)
+			}
+			if r.End.After(busyUntil) {
+				busyUntil = r.End
+			}
+
+			if r.Conclusion == "cancelled" {
+				e.Cancelled += r.Duration()
+				continue
+			}
+
+			for _, s := range r.Steps {
+				if isSetupStep(s.Name) {
+					e.Setup += s.End.Sub(s.Start)
+				}
+			}
+		}
+
+		if e.Runner <= 0 {
+			continue
+		}
+
+		e.Score = 100 * float64(e.Runner-e.Setup-e.Cancelled) / float64(e.Runner+e.Idle)
+		res = append(res, e)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score < res[j].Score
+		}
+		return res[i].RunID < res[j].RunID
+	})
+	return res
+}
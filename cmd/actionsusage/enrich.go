@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var enrich = flag.Bool("enrich", false, "Add the commit subject and author, and the associated pull request's number and title, to each job; "+
+	"costs an API call per pull request and per commit of runs not triggered by a pull request.")
+
+// runMetadata is the human-readable context of a run.
+type runMetadata struct {
+	CommitSubject string
+	CommitAuthor  string
+	PRNumber      int
+	PRTitle       string
+}
+
+// runEnricher looks up runs' metadata, fetching each pull request once.
+type runEnricher struct {
+	client *github.Client
+	prs    map[string]*github.PullRequest // By repo#number.
+	bySHA  map[string]*github.PullRequest // By repo@sha; nil if the commit has none.
+}
+
+func newRunEnricher(client *github.Client) *runEnricher {
+	return &runEnricher{client: client, prs: map[string]*github.PullRequest{}, bySHA: map[string]*github.PullRequest{}}
+}
+
+func (e *runEnricher) metadata(ctx context.Context, reponame string, w *github.WorkflowRun) (runMetadata, error) {
+	subject, _, _ := strings.Cut(w.GetHeadCommit().GetMessage(), "\n")
+	m := runMetadata{
+		CommitSubject: subject,
+		CommitAuthor:  w.GetHeadCommit().GetAuthor().GetName(),
+	}
+
+	owner, name, err := ghclient.SplitRepo(reponame)
+	if err != nil {
+		return m, err
+	}
+
+	var pr *github.PullRequest
+	if len(w.PullRequests) > 0 {
+		number := w.PullRequests[0].GetNumber()
+		key := fmt.Sprintf("%s#%d", reponame, number)
+		if pr = e.prs[key]; pr == nil {
+			if pr, _, err = e.client.PullRequests.Get(ctx, owner, name, number); err != nil {
+				return m, err
+			}
+			e.prs[key] = pr
+		}
+	} else {
+		key := reponame + "@" + w.GetHeadSHA()
+		var ok bool
+		if pr, ok = e.bySHA[key]; !ok {
+			prs, _, err := e.client.PullRequests.ListPullRequestsWithCommit(ctx, owner, name, w.GetHeadSHA(), &github.ListOptions{PerPage: 1})
+			if err != nil {
+				return m, err
+			}
+			if len(prs) > 0 {
+				pr = prs[0]
+			}
+			e.bySHA[key] = pr
+		}
+	}
+
+	if pr != nil {
+		m.PRNumber = pr.GetNumber()
+		m.PRTitle = pr.GetTitle()
+	}
+
+	return m, nil
+}
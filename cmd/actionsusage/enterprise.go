@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+)
+
+var enterprise = flag.String("enterprise", "", "Also consider the repositories of every organization of this enterprise, by slug, as with -org; "+
+	"minutes are then also broken down by organization. The token needs the read:enterprise scope.")
+
+const enterpriseOrgsQuery = `query($slug: String!, $after: String) {
+  enterprise(slug: $slug) {
+    organizations(first: 100, after: $after) {
+      nodes { login }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+// enterpriseOrgs returns the logins of the organizations of an enterprise,
+// which only the GraphQL API lists.
+func enterpriseOrgs(ctx context.Context, client *github.Client, slug string) ([]string, error) {
+	var res []string
+	var after *string
+	for {
+		req, err := client.NewRequest(http.MethodPost, "graphql", map[string]any{
+			"query":     enterpriseOrgsQuery,
+			"variables": map[string]any{"slug": slug, "after": after},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var resp struct {
+			Data struct {
+				Enterprise *struct {
+					Organizations struct {
+						Nodes []struct {
+							Login string
+						}
+						PageInfo struct {
+							HasNextPage bool
+							EndCursor   string
+						}
+					}
+				}
+			}
+			Errors []struct {
+				Message string
+			}
+		}
+
+		if _, err := client.Do(ctx, req, &resp); err != nil {
+			return nil, err
+		}
+
+		if len(resp.Errors) > 0 {
+			return nil, fmt.Errorf("enterprise %s: %s", slug, resp.Errors[0].Message)
+		}
+
+		if resp.Data.Enterprise == nil {
+			return nil, fmt.Errorf("enterprise %s not found", slug)
+		}
+
+		for _, o := range resp.Data.Enterprise.Organizations.Nodes {
+			res = append(res, o.Login)
+		}
+
+		page := resp.Data.Enterprise.Organizations.PageInfo
+		if !page.HasNextPage {
+			return res, nil
+		}
+		after = &page.EndCursor
+	}
+}
+
+// orgOf returns the owner of a repository.
+func orgOf(j jobRecord) string {
+	owner, _, _ := strings.Cut(j.Repository, "/")
+	return owner
+}
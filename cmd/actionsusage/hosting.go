@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+var splitHosting = flag.Bool("split_hosting", false, "Split minutes, regions and max concurrency between GitHub-hosted and self-hosted runners; "+
+	"with -format=json, the regions of each are written to their own file.")
+
+// hostedRunnerGroup is the runner group of GitHub's standard hosted runners.
+const hostedRunnerGroup = "GitHub Actions"
+
+// hostingUsage is the usage of either GitHub-hosted or self-hosted runners.
+type hostingUsage struct {
+	Hosting        string   `json:"hosting"`
+	Jobs           int      `json:"jobs"`
+	Minutes        float64  `json:"minutes"`
+	MaxConcurrency int      `json:"max_concurrency"`
+	Regions        []Region `json:"regions"`
+}
+
+func (u hostingUsage) String() string {
+	return fmt.Sprintf("%s: %s minutes across %d jobs, max concurrency %d", u.Hosting, formatMinutes(u.Minutes), u.Jobs, u.MaxConcurrency)
+}
+
+// hosting returns whether a job ran on a GitHub-hosted or a self-hosted
+// runner. Jobs that asked for a self-hosted runner, or were picked up by a
+// runner outside GitHub's hosted group, count as self-hosted.
+func hosting(r jobRecord) string {
+	if selfHosted(r) || (r.RunnerGroup != "" && r.RunnerGroup != hostedRunnerGroup) {
+		return "self-hosted"
+	}
+
+	return "GitHub-hosted"
+}
+
+// splitByHosting returns the usage of GitHub-hosted runners, then that of
+// self-hosted runners, leaving out either if no job ran on it.
+func splitByHosting(records []jobRecord) []hostingUsage {
+	usage := map[string]*hostingUsage{}
+	sets := map[string]*regionSet{}
+	for _, r := range records {
+		h := hosting(r)
+		if usage[h] == nil {
+			usage[h] = &hostingUsage{Hosting: h}
+			sets[h] = &regionSet{quiet: true}
+		}
+
+		usage[h].Jobs++
+		usage[h].Minutes += r.Minutes
+		if r.Duration() < *minJobDuration {
+			continue
+		}
+
+		sets[h].add(Region{
+			Start:  r.Start.UnixMilli(),
+			End:    r.End.UnixMilli(),
+			JobIDs: []JobID{{Repository: r.Repository, WorkflowRunID: r.RunID, JobID: r.JobID}},
+		})
+	}
+
+	var res []hostingUsage
+	for _, h := range []string{"GitHub-hosted", "self-hosted"} {
+		if u := usage[h]; u != nil {
+			u.MaxConcurrency, u.Regions = sets[h].maxConcurrency, sets[h].regions
+			res = append(res, *u)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	hangMultiple   = flag.Float64("hang_multiple", 3, "Cancelled or timed out jobs that ran for at least this many times their median successful duration are reported as likely hung.")
+	hangMinSamples = flag.Int("hang_min_samples", 5, "Minimum number of successful runs of a job needed to tell whether it hung.")
+)
+
+// hungJob is a job that ran far longer than it usually does and was then
+// cancelled or timed out, which typically means it hung.
+type hungJob struct {
+	Repository string        `json:"repo"`
+	Workflow   string        `json:"workflow"`
+	Job        string        `json:"job"`
+	Start      time.Time     `json:"start"`
+	Duration   time.Duration `json:"duration"`
+	Median     time.Duration `json:"median"` // Of the job's successful runs.
+	Conclusion string        `json:"conclusion"`
+	Minutes    float64       `json:"minutes"`
+	URL        string        `json:"url"`
+}
+
+func (h hungJob) String() string {
+	return fmt.Sprintf("%s: %s / %s: %s after %v, %.0fx its median of %v: %s", h.Repository, h.Workflow, h.Job, h.Conclusion,
+		h.Duration.Round(time.Second), float64(h.Duration)/float64(h.Median), h.Median.Round(time.Second), h.URL)
+}
+
+// detectHungJobs returns the jobs that likely hung, the longest first.
+// Matrix jobs are compared with all the shards of their job.
+func detectHungJobs(records []jobRecord) []hungJob {
+	type key struct{ repo, workflow, job string }
+	keyOf := func(r jobRecord) key {
+		job := r.Job
+		if base, _, ok := splitMatrixJob(job); ok {
+			job = base
+		}
+		return key{r.Repository, r.Workflow, job}
+	}
+
+	successful := map[key][]runSample{}
+	for _, r := range records {
+		if r.Conclusion == "success" {
+			k := keyOf(r)
+			successful[k] = append(successful[k], runSample{start: r.Start, duration: r.Duration()})
+		}
+	}
+
+	var res []hungJob
+	for _, r := range records {
+		if (r.Conclusion != "cancelled" && r.Conclusion != "timed_out") || r.Superseded {
+			continue
+		}
+
+		samples := successful[keyOf(r)]
+		if len(samples) < *hangMinSamples {
+			continue
+		}
+
+		m := median(samples)
+		if m == 0 || float64(r.Duration()) < *hangMultiple*float64(m) {
+			continue
+		}
+
+		res = append(res, hungJob{
+			Repository: r.Repository,
+			Workflow:   r.Workflow,
+			Job:        r.Job,
+			Start:      r.Start,
+			Duration:   r.Duration(),
+			Median:     m,
+			Conclusion: r.Conclusion,
+			Minutes:    r.Minutes,
+			URL:        fmt.Sprintf("https://github.com/%s/actions/runs/%d/job/%d", r.Repository, r.RunID, r.JobID),
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Duration != res[j].Duration {
+			return res[i].Duration > res[j].Duration
+		}
+		return res[i].URL < res[j].URL
+	})
+
+	return res
+}
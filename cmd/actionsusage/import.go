@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var importFlags = flag.NewFlagSet("import", flag.ExitOnError)
+
+// billingRow is a line of a GitHub billing usage report.
+type billingRow struct {
+	Date         time.Time `json:"date"`
+	Product      string    `json:"product"`
+	SKU          string    `json:"sku"`
+	Quantity     float64   `json:"quantity"`
+	UnitType     string    `json:"unit_type"`
+	PricePerUnit float64   `json:"price_per_unit"`
+	Multiplier   float64   `json:"multiplier,omitempty"`
+	NetAmount    float64   `json:"net_amount,omitempty"`
+	Repository   string    `json:"repo,omitempty"`     // owner/name
+	Workflow     string    `json:"workflow,omitempty"` // Workflow file path.
+	Username     string    `json:"username,omitempty"`
+	CostCenter   string    `json:"cost_center,omitempty"`
+}
+
+func (r billingRow) storeKey() string {
+	return strings.Join([]string{r.Product, r.SKU, r.UnitType, r.Repository, r.Workflow, r.Username, r.CostCenter}, "|")
+}
+
+func (r billingRow) storeDay() time.Time {
+	return r.Date
+}
+
+// mergeBillingRows combines rows of the same key into one dated on week,
+// e.g. those of a week, dated on its Monday.
+func mergeBillingRows(week time.Time, rows []billingRow) billingRow {
+	res := rows[0]
+	res.Date = week
+	res.Quantity, res.NetAmount = 0, 0
+	for _, r := range rows {
+		res.Quantity += r.Quantity
+		res.NetAmount += r.NetAmount
+	}
+
+	return res
+}
+
+// billingColumns maps the header names used by the classic usage report and
+// the enhanced billing platform export to billingRow fields.
+var billingColumns = map[string]string{
+	"date":                      "date",
+	"product":                   "product",
+	"sku":                       "sku",
+	"quantity":                  "quantity",
+	"unit type":                 "unit_type",
+	"unit_type":                 "unit_type",
+	"price per unit ($)":        "price",
+	"applied_cost_per_quantity": "price",
+	"multiplier":                "multiplier",
+	"net_amount":                "net_amount",
+	"owner":                     "owner",
+	"organization":              "owner",
+	"repository slug":           "repo",
+	"repository":                "repo",
+	"actions workflow":          "workflow",
+	"workflow_path":             "workflow",
+	"username":                  "username",
+	"cost_center_name":          "cost_center",
+}
+
+func runImport(ctx context.Context) error {
+	if importFlags.NArg() == 0 {
+		return errors.New("usage: actionsusage import [-store dir] report.csv... (- for stdin)")
+	}
+
+	s, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range importFlags.Args() {
+		if err := importBillingFile(s, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func importBillingFile(s *store, name string) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+		r = f
+	}
+
+	rows, err := parseBillingCSV(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	rows = sumBillingRows(rows)
+	if err := put(s, "billing", rows); err != nil {
+		return err
+	}
+
+	log.Printf("%s: imported %d billing rows into %s", name, len(rows), s)
+	return nil
+}
+
+// sumBillingRows combines the rows of a report with the same key and day,
+// e.g. usage split across several lines, so that they don't replace each
+// other in the store. Re-importing a report still replaces its rows, unless
+// prune rolled their week up (see put).
+func sumBillingRows(rows []billingRow) []billingRow {
+	var order []string
+	byKey := map[string][]billingRow{}
+	for _, r := range rows {
+		k := r.storeDay().Format(dateLayout) + "|" + r.storeKey()
+		if _, ok := byKey[k]; !ok {
+			order = append(order, k)
+		}
+		byKey[k] = append(byKey[k], r)
+	}
+
+	res := make([]billingRow, 0, len(order))
+	for _, k := range order {
+		res = append(res, mergeBillingRows(byKey[k][0].Date, byKey[k]))
+	}
+
+	return res
+}
+
+func parseBillingCSV(r io.Reader) ([]billingRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for k, h := range header {
+		if field, ok := billingColumns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))]; ok {
+			columns[field] = k
+		}
+	}
+
+	for _, required := range []string{"date", "product", "sku", "quantity"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("not a usage report: missing %q column", required)
+		}
+	}
+
+	var rows []billingRow
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		get := func(field string) string {
+			if k, ok := columns[field]; ok && k < len(rec) {
+				return strings.TrimSpace(rec[k])
+			}
+			return ""
+		}
+
+		row := billingRow{
+			Product:    get("product"),
+			SKU:        get("sku"),
+			UnitType:   get("unit_type"),
+			Repository: get("repo"),
+			Workflow:   get("workflow"),
+			Username:   get("username"),
+			CostCenter: get("cost_center"),
+		}
+
+		row.Date, err = parseBillingDate(get("date"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		for field, dst := range map[string]*float64{
+			"quantity":   &row.Quantity,
+			"price":      &row.PricePerUnit,
+			"multiplier": &row.Multiplier,
+			"net_amount": &row.NetAmount,
+		} {
+			if v := strings.TrimPrefix(get(field), "$"); v != "" {
+				if *dst, err = strconv.ParseFloat(v, 64); err != nil {
+					return nil, fmt.Errorf("line %d: bad %s: %w", line, field, err)
+				}
+			}
+		}
+
+		if owner := get("owner"); owner != "" && row.Repository != "" && !strings.Contains(row.Repository, "/") {
+			row.Repository = owner + "/" + row.Repository
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseBillingDate(v string) (time.Time, error) {
+	for _, layout := range []string{dateLayout, time.RFC3339, "2006-01-02T15:04:05", "01/02/2006"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC().Truncate(24 * time.Hour), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("bad date %q", v)
+}
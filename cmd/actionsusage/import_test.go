@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBillingCSV(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		csv  string
+		want []billingRow
+	}{
+		{
+			name: "classic",
+			csv: "\ufeffDate,Product,SKU,Quantity,Unit Type,Price Per Unit ($),Multiplier,Owner,Repository Slug,Username,Actions Workflow\n" +
+				"2024-03-01,Actions,Compute - UBUNTU,12,minute,$0.008,1,acme,app,octocat,.github/workflows/ci.yml\n",
+			want: []billingRow{{
+				Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Product: "Actions", SKU: "Compute - UBUNTU", Quantity: 12, UnitType: "minute",
+				PricePerUnit: 0.008, Multiplier: 1, Repository: "acme/app", Workflow: ".github/workflows/ci.yml", Username: "octocat",
+			}},
+		},
+		{
+			name: "enhanced",
+			csv: "date,product,sku,quantity,unit_type,applied_cost_per_quantity,net_amount,organization,repository,workflow_path,cost_center_name\n" +
+				"2024-03-01T00:00:00Z,actions,actions_linux,3.5,minutes,0.008,0.028,acme,acme/app,.github/workflows/ci.yml,platform\n",
+			want: []billingRow{{
+				Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Product: "actions", SKU: "actions_linux", Quantity: 3.5, UnitType: "minutes",
+				PricePerUnit: 0.008, NetAmount: 0.028, Repository: "acme/app", Workflow: ".github/workflows/ci.yml", CostCenter: "platform",
+			}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseBillingCSV(strings.NewReader(tc.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseBillingCSVErrors(t *testing.T) {
+	for _, csv := range []string{
+		"Date,Product,Quantity\n2024-03-01,Actions,1\n",
+		"Date,Product,SKU,Quantity\nyesterday,Actions,UBUNTU,1\n",
+		"Date,Product,SKU,Quantity\n2024-03-01,Actions,UBUNTU,lots\n",
+	} {
+		if _, err := parseBillingCSV(strings.NewReader(csv)); err == nil {
+			t.Errorf("parseBillingCSV(%q) succeeded", csv)
+		}
+	}
+}
+
+func TestSumBillingRows(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rows := []billingRow{
+		{Date: day, SKU: "UBUNTU", Repository: "acme/app", Quantity: 2, NetAmount: 0.016},
+		{Date: day, SKU: "WINDOWS", Repository: "acme/app", Quantity: 1, NetAmount: 0.016},
+		{Date: day, SKU: "UBUNTU", Repository: "acme/app", Quantity: 3, NetAmount: 0.024},
+		{Date: day.AddDate(0, 0, 1), SKU: "UBUNTU", Repository: "acme/app", Quantity: 5, NetAmount: 0.04},
+	}
+
+	got := sumBillingRows(rows)
+	want := []billingRow{
+		{Date: day, SKU: "UBUNTU", Repository: "acme/app", Quantity: 5, NetAmount: 0.04},
+		{Date: day, SKU: "WINDOWS", Repository: "acme/app", Quantity: 1, NetAmount: 0.016},
+		{Date: day.AddDate(0, 0, 1), SKU: "UBUNTU", Repository: "acme/app", Quantity: 5, NetAmount: 0.04},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+var (
+	incidentsFile = flag.String("incidents", "", `JSON file of incident windows, e.g. [{"title": "runner outage", "start": "2024-03-01T10:00:00Z", "end": "2024-03-01T12:30:00Z"}].`)
+	incidentsURL  = flag.String("incidents_url", "", "Statuspage API incidents URL to fetch incident windows from, e.g. https://www.githubstatus.com/api/v2/incidents.json.")
+	incidentMode  = flag.String("incident_mode", "mark", "What to do with jobs and runs during incidents: mark them, or exclude them from the figures.")
+)
+
+// incident is a window during which CI behaved abnormally (e.g. a GitHub
+// outage), which would skew capacity and queue-time analysis.
+type incident struct {
+	Title string    `json:"title"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// incidentImpact is the usage that overlapped an incident.
+type incidentImpact struct {
+	incident
+	Jobs         int           `json:"jobs"`
+	Minutes      float64       `json:"minutes"`
+	MedianQueued time.Duration `json:"median_queued"`
+}
+
+func (i incidentImpact) String() string {
+	return fmt.Sprintf("%s (%s – %s): %d jobs, %s minutes, median queue time %v", i.Title,
+		i.Start.Format(time.RFC3339), i.End.Format(time.RFC3339), i.Jobs, formatMinutes(i.Minutes), i.MedianQueued.Round(time.Second))
+}
+
+// loadIncidents returns the incident windows of -incidents and
+// -incidents_url, oldest first.
+func loadIncidents(ctx context.Context) ([]incident, error) {
+	switch *incidentMode {
+	case "mark", "exclude":
+	default:
+		return nil, fmt.Errorf("unsupported -incident_mode %q", *incidentMode)
+	}
+
+	var res []incident
+	if *incidentsFile != "" {
+		contents, err := os.ReadFile(*incidentsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(contents, &res); err != nil {
+			return nil, fmt.Errorf("%s: %w", *incidentsFile, err)
+		}
+	}
+
+	if *incidentsURL != "" {
+		fetched, err := fetchStatusPageIncidents(ctx, *incidentsURL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", *incidentsURL, err)
+		}
+		res = append(res, fetched...)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Start.Before(res[j].Start) })
+	return res, nil
+}
+
+func fetchStatusPageIncidents(ctx context.Context, url string) ([]incident, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var page struct {
+		Incidents []struct {
+			Name       string     `json:"name"`
+			CreatedAt  time.Time  `json:"created_at"`
+			StartedAt  *time.Time `json:"started_at"`
+			ResolvedAt *time.Time `json:"resolved_at"`
+		} `json:"incidents"`
+	}
+
+	if _, err := doJSON(req, &page); err != nil {
+		return nil, err
+	}
+
+	var res []incident
+	for _, i := range page.Incidents {
+		in := incident{Title: i.Name, Start: i.CreatedAt, End: time.Now()}
+		if i.StartedAt != nil {
+			in.Start = *i.StartedAt
+		}
+		if i.ResolvedAt != nil {
+			in.End = *i.ResolvedAt
+		}
+		res = append(res, in)
+	}
+
+	return res, nil
+}
+
+// incidentDuring returns the title of the first incident overlapping
+// [start, end], if any.
+func incidentDuring(incidents []incident, start, end time.Time) string {
+	for _, i := range incidents {
+		if start.Before(i.End) && end.After(i.Start) {
+			return i.Title
+		}
+	}
+
+	return ""
+}
+
+// summarizeIncidents returns the usage that overlapped each incident.
+func summarizeIncidents(incidents []incident, records []jobRecord) []incidentImpact {
+	var res []incidentImpact
+	for _, i := range incidents {
+		impact := incidentImpact{incident: i}
+
+		var queued []runSample
+		for _, r := range records {
+			if r.Start.Before(i.End) && r.End.After(i.Start) {
+				impact.Jobs++
+				impact.Minutes += r.Minutes
+				queued = append(queued, runSample{start: r.Start, duration: r.Queued})
+			}
+		}
+
+		impact.MedianQueued = median(queued)
+		res = append(res, impact)
+	}
+
+	return res
+}
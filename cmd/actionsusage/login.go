@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"namespacelabs.dev/githubtools/internal/oauthlogin"
+)
+
+var (
+	login         = flag.Bool("login", false, "Log in to GitHub in the browser, and store the token in plain text for later runs (readable only by you) to use when GITHUB_TOKEN isn't set.")
+	oauthClientID = flag.String("oauth_client_id", os.Getenv("GITHUB_OAUTH_CLIENT_ID"), "Client ID of the OAuth app, with device flow enabled, that -login authorizes. Defaults to GITHUB_OAUTH_CLIENT_ID.")
+)
+
+// runLogin runs the OAuth device flow, asking for the scopes that reading
+// the Actions usage of private repositories and organizations needs.
+func runLogin(ctx context.Context) error {
+	if *oauthClientID == "" {
+		return errors.New("-login requires -oauth_client_id")
+	}
+
+	token, err := oauthlogin.Login(ctx, *oauthClientID, "repo read:org", func(uri, code string) {
+		fmt.Fprintf(os.Stderr, "Open %s and enter the code %s\n", uri, code)
+	})
+	if err != nil {
+		return err
+	}
+
+	p, err := oauthlogin.Store(token)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Logged in; stored the token in %s", p)
+	return nil
+}
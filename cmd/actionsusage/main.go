@@ -2,208 +2,618 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/cli"
+	"namespacelabs.dev/githubtools/internal/ghclient"
 )
 
 var (
-	repos    = flag.String("repos", "", "List of repositories, separated by commas. E.g. namespacelabs/foundation")
-	runCount = flag.Int("run_count", 1000, "Maximum number of runs to consider per repo.")
-	maxJobs  = flag.Int("max_jobs", 1000, "Max jobs per run.")
+	repos     = flag.String("repos", "", "List of repositories, separated by commas. E.g. namespacelabs/foundation")
+	reposFile = flag.String("repos-file", "", "File listing repositories, one per line, with # comments; - reads stdin.")
+	org       = flag.String("org", "", "Also consider the repositories of this organization, except forks and archived ones.")
+	runIDs    = flag.String("run-ids", "", "Only consider these runs, as owner/repo:id separated by commas, instead of listing the runs of -repos, -org or -enterprise.")
+	topics    = flag.String("topics", "", "Only consider the repositories of -org or -enterprise with at least one of these topics, separated by commas, e.g. ci-heavy,backend.")
+	branch    = flag.String("branch", "", "Only consider runs for this branch, e.g. main.")
+	actor     = flag.String("actor", "", "Only consider runs triggered by this user or app, e.g. dependabot[bot].")
+	event     = flag.String("event", "", "Only consider runs triggered by this event, e.g. push, pull_request, schedule or workflow_dispatch.")
+	runCount  = flag.Int("run_count", 1000, "Maximum number of runs to consider per repo, unless -since is set.")
+	maxJobs   = flag.Int("max_jobs", 1000, "Max jobs per run.")
+	preflight = flag.Bool("preflight", true, "Check that the token can read the runs of each repository before collecting; those it can't are skipped, and make the result partial.")
+	rounding  = flag.String("rounding", "job", "How job durations become billed minutes: job (each job rounded up to a whole minute, as GitHub bills hosted runners), "+
+		"run (each run's total rounded up) or exact (per second, as self-hosted cost models often bill).")
+	groupBy = flag.String("group-by", "", "Go template evaluated against each job to aggregate minutes by, e.g. '{{.Workflow}} on {{.Label}}'. "+
+		"Fields: Repository, Workflow, RunID, Job, JobID, Branch, Commit, Event, Actor, Attempt, Labels, Label, OS, Conclusion, RunnerGroup, Superseded, Incident, Queued, Start, End, Minutes, Duration, CostCenter, Component, System, Owner, and with -enrich CommitSubject, CommitAuthor, PRNumber, PRTitle.")
+	selectExpr = flag.String("select", "", "Go template evaluated against each job (same fields as -group-by); only jobs for which it yields true are counted, "+
+		"e.g. '{{gt .Minutes 10}}'.")
+	includeForks       = flag.Bool("include-forks", false, "Also consider the forks of -org and -enterprise, which are skipped by default.")
+	includeArchived    = flag.Bool("include-archived", false, "Also consider the archived repositories of -org and -enterprise, which are skipped by default.")
+	excludeRepos       = flag.String("exclude-repos", "", "Glob patterns of repositories to skip, separated by commas, e.g. '*-mirror,sandbox/*'; patterns without a slash match the name within any owner.")
+	jobFilter          = flag.String("job-filter", "", "Regular expression; only jobs whose name matches it are counted, e.g. '^(build|test)'.")
+	jobExclude         = flag.String("job-exclude", "", "Regular expression; jobs whose name matches it aren't counted, e.g. '^(lint|docs)'.")
+	allAttempts        = flag.Bool("all_attempts", false, "Also count the jobs of earlier attempts of re-run runs, each with its attempt number, so that re-runs show up on the timeline of their run.")
+	minJobDuration     = flag.Duration("min-job-duration", 0, "Leave jobs shorter than this, e.g. 30s, out of the regions and max concurrency; they still count towards minutes.")
+	runnerLabel        = flag.String("runner-label", "", "Glob patterns of runner labels, separated by commas; only jobs with a matching label are counted, e.g. 'nscloud-*,self-hosted'.")
+	excludeConclusions = flag.String("exclude-conclusions", "", "Job conclusions to leave out of the minutes and concurrency, separated by commas, e.g. cancelled,skipped.")
 )
 
+type command struct {
+	flags *flag.FlagSet
+	run   func(context.Context) error
+}
+
+var commands = map[string]command{
+	"digest":       {digestFlags, runDigest},
+	"import":       {importFlags, runImport},
+	"reconcile":    {reconcileFlags, runReconcile},
+	"approvals":    {approvalsFlags, runApprovals},
+	"actions":      {actionsFlags, runActions},
+	"drafts":       {draftsFlags, runDrafts},
+	"paths":        {pathsFlags, runPaths},
+	"timeouts":     {timeoutsFlags, runTimeouts},
+	"artifacts":    {artifactsFlags, runArtifacts},
+	"pipelines":    {pipelinesFlags, runPipelines},
+	"dora":         {doraFlags, runDORA},
+	"trend":        {trendFlags, runTrend},
+	"queue":        {queueFlags, runQueue},
+	"compare-runs": {compareFlags, runCompareRuns},
+	"bisect":       {bisectFlags, runBisect},
+	"prune":        {pruneFlags, runPrune},
+	"seats":        {seatsFlags, runSeats},
+}
+
 func main() {
-	flag.Parse()
+	if handled, err := cli.Run(os.Stdout, os.Args[1:], describe); err != nil {
+		log.Fatal(err)
+	} else if handled {
+		return
+	}
 
-	if err := (func(ctx context.Context) error {
-		ghToken := os.Getenv("GITHUB_TOKEN")
-		if ghToken == "" {
-			return errors.New("GITHUB_TOKEN is required")
+	run, fs, args := runUsage, flag.CommandLine, os.Args[1:]
+	result.Command, result.Started = "usage", time.Now().UTC()
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			// Subcommands accept all of the top-level flags in addition to their own.
+			flag.CommandLine.VisitAll(func(f *flag.Flag) {
+				if cmd.flags.Lookup(f.Name) == nil {
+					cmd.flags.Var(f.Value, f.Name, f.Usage)
+				}
+			})
+			run, fs, args = cmd.run, cmd.flags, args[1:]
+			result.Command = os.Args[1]
 		}
+	}
+
+	_ = fs.Parse(args)
 
-		if *repos == "" {
-			return errors.New("-repos is required")
+	if err := checkFlags(); err != nil {
+		log.Print(err)
+		os.Exit(finish(usageError{err}))
+	}
+
+	if *login {
+		result.Command = "login"
+		err := runLogin(context.Background())
+		if err != nil {
+			log.Print(err)
 		}
+		os.Exit(finish(err))
+	}
 
-		client := github.NewClient(nil).WithAuthToken(ghToken)
+	err := run(context.Background())
+	if err != nil {
+		log.Print(err)
+	}
+	os.Exit(finish(err))
+}
 
-		var ws []*github.WorkflowRun
-		for _, reponame := range strings.Split(*repos, ",") {
-			parts := strings.Split(reponame, "/")
-			if len(parts) != 2 {
-				return fmt.Errorf("bad repository format: %q", reponame)
-			}
+// checkFlags validates the flags that all commands share, and configures the
+// HTTP transport with them.
+func checkFlags() error {
+	if err := ghclient.ConfigureHTTP(); err != nil {
+		return err
+	}
 
-			for k := 1; ; k++ {
-				runs, r, err := client.Actions.ListRepositoryWorkflowRuns(ctx, parts[0], parts[1], &github.ListWorkflowRunsOptions{
-					ListOptions: github.ListOptions{
-						PerPage: min(100, *runCount),
-						Page:    k,
-					},
-				})
-				if err != nil {
-					return err
-				}
+	if *maxJobs <= 0 {
+		return fmt.Errorf("-max_jobs must be positive, got %d", *maxJobs)
+	}
 
-				if len(runs.WorkflowRuns) == 0 {
-					break
-				}
+	switch *rounding {
+	case "job", "run", "exact":
+	default:
+		return fmt.Errorf("unsupported -rounding %q", *rounding)
+	}
 
-				ws = append(ws, runs.WorkflowRuns...)
-				log.Printf("%s: got %d runs (total: %d rate_limit: %d/%d from: %v to %v)",
-					reponame, len(runs.WorkflowRuns), len(ws), r.Rate.Remaining, r.Rate.Limit,
-					ws[0].CreatedAt.Time.Format(time.RFC3339), ws[len(ws)-1].CreatedAt.Time.Format(time.RFC3339),
-				)
-				if len(ws) >= *runCount {
-					break
-				}
+	switch *runConclusion {
+	case "", "success", "failure", "cancelled", "skipped", "timed_out", "action_required", "neutral", "stale", "startup_failure":
+	default:
+		return fmt.Errorf("unsupported -conclusion %q", *runConclusion)
+	}
+
+	switch *timestampPolicy {
+	case "skip", "clamp", "estimate":
+	default:
+		return fmt.Errorf("unsupported -timestamp_policy %q", *timestampPolicy)
+	}
+
+	return parseWindow(time.Now())
+}
+
+func runUsage(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	var repoList []string
+	var pickedRuns map[string][]int64
+	if *runIDs != "" {
+		if repoList, pickedRuns, err = parseRunIDs(*runIDs); err == nil {
+			repoList, err = preflightRepos(ctx, client, repoList)
+		}
+	} else {
+		repoList, err = targetRepos(ctx, client)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *pullRequest != 0 && (pickedRuns != nil || len(repoList) != 1) {
+		return fmt.Errorf("-pr requires -repos to name a single repository")
+	}
+
+	var groups *grouper
+	if *groupBy != "" {
+		groups, err = newGrouper(*groupBy)
+		if err != nil {
+			return err
+		}
+	}
+
+	var filter *recordFilter
+	if *selectExpr != "" {
+		filter, err = newRecordFilter(*selectExpr)
+		if err != nil {
+			return err
+		}
+	}
+
+	var packages packageMapping
+	if *packagesFile != "" {
+		packages, err = loadPackageMapping(*packagesFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	incidents, err := loadIncidents(ctx)
+	if err != nil {
+		return err
+	}
+
+	poolSizes, poolCaps, err := parsePoolSizes()
+	if err != nil {
+		return err
+	}
+
+	var ws []*github.WorkflowRun
+	var botRuns int
+	for k, reponame := range repoList {
+		var runs []*github.WorkflowRun
+		if pickedRuns != nil {
+			runs, err = fetchRunsByID(ctx, client, reponame, pickedRuns[reponame])
+		} else if *pullRequest != 0 {
+			runs, err = fetchPullRequestRuns(ctx, client, reponame, *pullRequest)
+		} else {
+			runs, err = fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{}, *runCount)
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Printf("%s: %d runs (repository %d of %d)", reponame, len(runs), k+1, len(repoList))
+		for _, w := range runs {
+			if excludedBot(w.GetActor().GetLogin()) {
+				botRuns++
+				continue
 			}
+			ws = append(ws, w)
+		}
+	}
+
+	superseded := supersededRuns(ws)
+
+	var totalminutes float64
+	var regions regionSet
+	var costCenters map[string]string
+	if *costCenterPrefix != "" {
+		if costCenters, err = fetchCostCenters(ctx, client, repoList); err != nil {
+			return err
 		}
+	}
 
-		var totalminutes int64
-		var regions []Region // Sorted
-		var maxconcurrency int
+	var entities map[string]catalogEntity
+	if *backstageCatalog != "" {
+		if entities, err = fetchCatalogEntities(ctx, client, repoList); err != nil {
+			return err
+		}
+	}
 
-		checkMaxConc := func(val int) {
-			if val > maxconcurrency {
-				maxconcurrency = val
-				log.Printf("new max concurrency: %d", maxconcurrency)
+	var enricher *runEnricher
+	if *enrich {
+		enricher = newRunEnricher(client)
+	}
+
+	jobInclude, jobSkip, err := compileJobFilters()
+	if err != nil {
+		return err
+	}
+
+	var excluded []string
+	if *excludeConclusions != "" {
+		excluded = strings.Split(*excludeConclusions, ",")
+	}
+
+	var records []jobRecord
+	var overlapping []jobRecord // Jobs during incidents, including excluded ones.
+	var excludedJobs int
+	var badTimestamps int // Completed jobs with missing or inconsistent timestamps.
+	var shortJobs int     // Counted, but shorter than -min-job-duration.
+
+	for _, w := range ws {
+		repo := fmt.Sprintf("%s/%s", *w.Repository.Owner.Login, *w.Repository.Name)
+
+		jobsFilter := "latest"
+		if *allAttempts || *pullRequest != 0 {
+			jobsFilter = "all"
+		}
+
+		jobs, r, err := listJobs(ctx, client, w, *maxJobs, jobsFilter)
+		if err != nil {
+			return err
+		}
+
+		if r != nil && r.NextPage != 0 {
+			log.Printf("%s: %d: only counted the first %d jobs; see -max_jobs", repo, *w.ID, len(jobs))
+			result.TruncatedRuns++
+		}
+
+		var metadata runMetadata
+		if enricher != nil {
+			if metadata, err = enricher.metadata(ctx, repo, w); err != nil {
+				return err
 			}
 		}
 
-		for _, w := range ws {
-			var jobs []*github.WorkflowJob
-			for k := 1; len(jobs) < *maxJobs; k++ {
-				j, r, err := client.Actions.ListWorkflowJobs(ctx, *w.Repository.Owner.Login, *w.Repository.Name, *w.ID, &github.ListWorkflowJobsOptions{
-					ListOptions: github.ListOptions{
-						Page:    k,
-						PerPage: 100,
-					},
-				})
+		runStart := len(records)
+
+		for _, job := range jobs {
+			bad, ok := checkTimestamps(job)
+			if bad {
+				badTimestamps++
+			}
+
+			if !ok {
+				log.Printf("%d: skipped job %d: started_at=%v completed_at=%v", *w.ID, *job.ID, job.StartedAt, job.CompletedAt)
+				continue
+			}
+
+			record := newJobRecord(repo, w, job)
+			record.Superseded = superseded[w.GetID()]
+			record.runMetadata = metadata
+			if costCenters != nil {
+				record.CostCenter = costCenters[strings.ToLower(repo)]
+			}
+			if e, ok := entities[strings.ToLower(repo)]; ok {
+				record.Component, record.System, record.Owner = e.Metadata.Name, e.Spec.System, e.Spec.Owner
+			}
+			if record.Incident = incidentDuring(incidents, record.Start, record.End); record.Incident != "" {
+				overlapping = append(overlapping, record)
+				if *incidentMode == "exclude" {
+					continue
+				}
+			}
+
+			if slices.Contains(excluded, record.Conclusion) {
+				excludedJobs++
+				continue
+			}
+
+			if (jobInclude != nil && !jobInclude.MatchString(record.Job)) || (jobSkip != nil && jobSkip.MatchString(record.Job)) {
+				continue
+			}
+
+			if *runnerLabel != "" {
+				ok, err := matchesRunnerLabel(record.Labels)
 				if err != nil {
 					return err
 				}
 
-				if len(j.Jobs) == 0 {
-					break
+				if !ok {
+					continue
 				}
+			}
 
-				repo := fmt.Sprintf("%s/%s", *w.Repository.Owner.Login, *w.Repository.Name)
-
-				for _, job := range j.Jobs {
-					if job.CompletedAt == nil || job.StartedAt == nil {
-						log.Printf("%d: skipped job %d: started_at=%v completed_at=%v", *w.ID, *job.ID, job.StartedAt, job.CompletedAt)
-						continue
-					}
-
-					totalminutes += int64(math.Ceil(job.CompletedAt.Time.Sub(job.StartedAt.Time).Seconds() / 60))
-
-					jobregion := Region{
-						Start: job.StartedAt.UnixMilli(),
-						End:   job.CompletedAt.UnixMilli(),
-						JobIDs: []JobID{
-							{Repository: repo, WorkflowRunID: *w.ID, JobID: *job.ID},
-						},
-					}
-
-					inserted := false
-					for k, reg := range regions {
-						if jobregion.Start < reg.End {
-							newRegions := regions[:k]
-							if jobregion.Start < reg.Start {
-								newRegions = append(newRegions, Region{
-									Start:  jobregion.Start,
-									End:    jobregion.End,
-									JobIDs: jobregion.JobIDs,
-								})
-								newRegions = append(newRegions, regions[k:]...)
-							} else {
-								newRegions = append(newRegions, Region{
-									Start:  reg.Start,
-									End:    jobregion.Start,
-									JobIDs: reg.JobIDs,
-								})
-								newRegions = append(newRegions, Region{
-									Start:  jobregion.Start,
-									End:    jobregion.End,
-									JobIDs: append(jobregion.JobIDs, reg.JobIDs...),
-								})
-								checkMaxConc(len(reg.JobIDs) + len(jobregion.JobIDs))
-								newRegions = append(newRegions, regions[k+1:]...)
-							}
-
-							regions = newRegions
-							inserted = true
-							break
-						}
-					}
-
-					if !inserted {
-						regions = append(regions, jobregion)
-						checkMaxConc(len(jobregion.JobIDs))
-					}
+			if filter != nil {
+				ok, err := filter.match(record)
+				if err != nil {
+					return err
 				}
 
-				log.Printf("%s/%s: %d: got %d jobs (total_minutes: %d max_concurrency: %d%s region_count: %d rate_limit: %d/%d)",
-					*w.Repository.Owner.Login, *w.Repository.Name, *w.ID, len(j.Jobs), totalminutes,
-					maxconcurrency, regionRange(regions), len(regions), r.Rate.Remaining, r.Rate.Limit)
-
-				jobs = append(jobs, j.Jobs...)
-				if j.TotalCount != nil && len(jobs) == *j.TotalCount {
-					break
+				if !ok {
+					continue
 				}
 			}
+
+			totalminutes += record.Minutes
+			records = append(records, record)
+
+			if record.Duration() < *minJobDuration {
+				shortJobs++
+				continue
+			}
+
+			regions.add(Region{
+				Start: job.StartedAt.UnixMilli(),
+				End:   job.CompletedAt.UnixMilli(),
+				JobIDs: []JobID{
+					{Repository: repo, WorkflowRunID: *w.ID, JobID: *job.ID},
+				},
+			})
+		}
+
+		extra, err := groupRun(records[runStart:], groups)
+		if err != nil {
+			return err
+		}
+		totalminutes += extra
+
+		log.Printf("%s: %d: got %d jobs (total_minutes: %.0f max_concurrency: %d%s region_count: %d rate_limit: %d/%d)",
+			repo, *w.ID, len(jobs), totalminutes,
+			regions.maxConcurrency, regionRange(regions.regions), len(regions.regions), r.Rate.Remaining, r.Rate.Limit)
+	}
+
+	report := &Report{
+		Repos:          repoList,
+		Branch:         *branch,
+		Event:          *event,
+		Actor:          *actor,
+		RunConclusion:  *runConclusion,
+		PullRequest:    *pullRequest,
+		RunnerLabel:    *runnerLabel,
+		JobFilter:      *jobFilter,
+		JobExclude:     *jobExclude,
+		ExcludedBots:   botRuns,
+		ExcludedJobs:   excludedJobs,
+		BadTimestamps:  badTimestamps,
+		ShortJobs:      shortJobs,
+		Runs:           len(ws),
+		TotalMinutes:   totalminutes,
+		MaxConcurrency: regions.maxConcurrency,
+		Regions:        regions.regions,
+		Skipped:        result.Skipped,
+		Jobs:           records,
+	}
+
+	report.computeBreakdowns()
+	result.Repositories, result.Runs, result.Jobs = len(repoList), report.Runs, len(report.Jobs)
+	result.TotalMinutes, result.MaxConcurrency = report.TotalMinutes, report.MaxConcurrency
+	report.Durations, report.Regressions = analyzeDurations(ws)
+	report.Incidents = summarizeIncidents(incidents, overlapping)
+	if *engineerHourRate > 0 {
+		report.QueueCosts = computeQueueCosts(report.Jobs)
+	}
+	if report.PullRequest != 0 {
+		report.BusyTime = busyTime(report.Jobs)
+	}
+	if *splitHosting {
+		report.Hosting = splitByHosting(report.Jobs)
+	}
+	if len(poolSizes) > 0 || len(poolCaps) > 0 {
+		report.Simulations = simulatePools(report.Jobs, poolSizes, poolCaps)
+	}
+
+	log.Printf("Summary: %s minutes across %d runs and %d jobs, max concurrency %d",
+		formatMinutes(report.TotalMinutes), report.Runs, len(report.Jobs), report.MaxConcurrency)
+	if len(report.Skipped) > 0 {
+		log.Printf("  skipped %d repositories whose runs the token can't read", len(report.Skipped))
+	}
+	if report.Branch != "" {
+		log.Printf("  only runs for branch %s", report.Branch)
+	}
+	if report.Event != "" {
+		log.Printf("  only runs triggered by %s", report.Event)
+	}
+	if report.Actor != "" {
+		log.Printf("  only runs by %s", report.Actor)
+	}
+	if report.RunConclusion != "" {
+		log.Printf("  only runs that concluded %s", report.RunConclusion)
+	}
+	if report.PullRequest != 0 {
+		log.Printf("  only runs of pull request #%d, including re-runs; its jobs ran for %v of wall-clock time", report.PullRequest, report.BusyTime)
+	}
+	if report.RunnerLabel != "" {
+		log.Printf("  only jobs on runners labelled %s", report.RunnerLabel)
+	}
+	if report.JobFilter != "" {
+		log.Printf("  only jobs named like %s", report.JobFilter)
+	}
+	if report.JobExclude != "" {
+		log.Printf("  no jobs named like %s", report.JobExclude)
+	}
+	if *excludeBots {
+		log.Printf("  excluded %d runs triggered by bots", report.ExcludedBots)
+	}
+	if excluded != nil {
+		log.Printf("  excluded %d %s jobs", report.ExcludedJobs, strings.Join(excluded, " or "))
+	}
+	if *minJobDuration > 0 {
+		log.Printf("  left %d jobs shorter than %v out of the regions", report.ShortJobs, *minJobDuration)
+	}
+	if report.BadTimestamps > 0 {
+		log.Printf("  %d jobs had missing or inconsistent timestamps (-timestamp_policy=%s)", report.BadTimestamps, *timestampPolicy)
+	}
+	for _, u := range report.Hosting {
+		log.Printf("  %s", u)
+	}
+	for _, m := range report.ByOS {
+		log.Printf("  %s", m)
+	}
+	log.Printf("  %s", report.Waste)
+	for _, s := range report.Startup {
+		log.Printf("  possible startup failure loop: %s", s)
+	}
+	for k, s := range report.Steps {
+		if k == *topSteps {
+			break
+		}
+		log.Printf("  step %s", s)
+	}
+	var botMinutes float64
+	for _, b := range report.Bots {
+		botMinutes += b.Minutes
+		log.Printf("  dependency bot: %s", b)
+	}
+	if botMinutes > 0 {
+		log.Printf("  dependency bots: %s minutes in total (%.1f%%)", formatMinutes(botMinutes), 100*botMinutes/report.TotalMinutes)
+	}
+	for _, d := range report.Duplicates {
+		log.Printf("  duplicate jobs: %s", d)
+	}
+	for _, s := range report.Shards {
+		log.Printf("  unbalanced shards: %s: %s", s, s.Suggestion())
+	}
+	for _, h := range report.Hung {
+		log.Printf("  likely hung: %s", h)
+	}
+	for k, e := range report.Efficiency {
+		if k == *topInefficient {
+			break
+		}
+		log.Printf("  inefficient run: %s", e)
+	}
+	for _, c := range report.Capacity {
+		log.Printf("  runner capacity: %s: %d jobs, peak %d, median %d, p%g %d", c.Label, c.Jobs, c.Peak, c.Median, *capacityPercentile, c.Recommended)
+	}
+	for _, s := range report.Simulations {
+		log.Printf("  simulated pool: %s", s)
+	}
+	for _, c := range report.QueueCosts {
+		log.Printf("  queueing cost: %s", c)
+	}
+	for _, r := range report.Regressions {
+		log.Printf("  duration regression: %s", r)
+	}
+	for _, i := range report.Incidents {
+		if *incidentMode == "exclude" {
+			log.Printf("  excluded incident: %s", i)
+		} else {
+			log.Printf("  incident: %s", i)
+		}
+	}
+
+	if *enterprise != "" {
+		report.ByOrganization = aggregate(report.Jobs, orgOf)
+		for _, g := range report.ByOrganization {
+			log.Printf("organization %s", g)
+		}
+	}
+
+	if costCenters != nil {
+		report.ByCostCenter = aggregate(report.Jobs, func(j jobRecord) string { return j.CostCenter })
+		for _, g := range report.ByCostCenter {
+			log.Printf("cost center %s", g)
+		}
+	}
+
+	if entities != nil {
+		report.BySystem = aggregate(report.Jobs, func(j jobRecord) string { return orNone(j.System) })
+		report.ByOwner = aggregate(report.Jobs, func(j jobRecord) string { return orNone(j.Owner) })
+		for _, g := range report.BySystem {
+			log.Printf("system %s", g)
+		}
+		for _, g := range report.ByOwner {
+			log.Printf("owner %s", g)
+		}
+
+		if *backstageMetrics != "" {
+			if err := writeCatalogMetrics(*backstageMetrics, catalogMetrics(report.Jobs, entities)); err != nil {
+				return err
+			}
+			log.Printf("Wrote Backstage metrics: %s", *backstageMetrics)
 		}
+	}
 
-		f, err := os.CreateTemp("", "regionoutput.json")
+	if *storeJobs {
+		s, err := openStore()
 		if err != nil {
 			return err
 		}
 
-		defer f.Close()
+		if err := put(s, "jobs", report.Jobs); err != nil {
+			return err
+		}
+		log.Printf("Stored %d jobs in %s", len(report.Jobs), s)
+	}
 
-		enc := json.NewEncoder(f)
-		enc.SetIndent("", "  ")
-		if err := enc.Encode(regions); err != nil {
+	if *capacityTFVars != "" {
+		if err := writeCapacityTFVars(*capacityTFVars, report.Capacity); err != nil {
 			return err
 		}
+		log.Printf("Wrote runner pool sizes: %s", *capacityTFVars)
+	}
 
-		log.Printf("Computed region data: %s", f.Name())
+	if *arcManifests != "" {
+		if err := patchARCManifests(report.Jobs); err != nil {
+			return err
+		}
+	}
 
-		return nil
-	})(context.Background()); err != nil {
-		log.Fatal(err)
+	if packages != nil {
+		report.Packages = attributePackages(report.Jobs, packages)
+		for _, p := range report.Packages {
+			log.Printf("package %s: %s minutes, $%.2f", p.Package, formatMinutes(p.Minutes), p.Cost)
+		}
 	}
-}
 
-type JobID struct {
-	Repository    string `json:"repo"`
-	WorkflowRunID int64  `json:"workflow_run_id"`
-	JobID         int64  `json:"job_id"`
-}
+	if groups != nil {
+		report.Groups = groups.sorted()
+		for _, g := range report.Groups {
+			log.Printf("group %s", g)
+		}
+	}
+
+	if err := writeReport(report); err != nil {
+		return err
+	}
+
+	if *sheetsID != "" {
+		if err := appendSheetRow(ctx, report); err != nil {
+			return err
+		}
+	}
+
+	if *maxMinutes > 0 && report.TotalMinutes > *maxMinutes {
+		return thresholdError{minutes: report.TotalMinutes}
+	}
 
-type Region struct {
-	Start  int64   `json:"start"` // Unix milliseconds
-	End    int64   `json:"end"`   // Unix milliseconds
-	JobIDs []JobID `json:"count"`
+	return nil
 }
 
-func regionRange(regions []Region) string {
-	if len(regions) == 0 {
-		return ""
+// describe describes the subcommands and flags, for completion and -help-json.
+func describe() cli.Command {
+	subcommands := map[string]*flag.FlagSet{}
+	for name, cmd := range commands {
+		subcommands[name] = cmd.flags
 	}
 
-	return fmt.Sprintf(" range_start: %s range_end: %s",
-		time.UnixMilli(regions[0].Start).Format(time.RFC3339),
-		time.UnixMilli(regions[len(regions)-1].End).Format(time.RFC3339),
-	)
+	return cli.Describe("actionsusage", flag.CommandLine, subcommands)
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+)
+
+var packagesFile = flag.String("packages", "", "JSON file mapping jobs to the monorepo packages they build or test, to compute per-package cost and duration series. "+
+	`E.g. [{"workflow": "^CI$", "job": "^test \\((.+)\\)$", "package": "$1"}, {"job": "^e2e", "package": "//e2e"}]`)
+
+// packageRule attributes the jobs whose workflow and job names match (both
+// optional) to a package. The package may reference the job pattern's
+// submatches, e.g. $1.
+type packageRule struct {
+	Workflow string `json:"workflow"`
+	Job      string `json:"job"`
+	Package  string `json:"package"`
+
+	workflow, job *regexp.Regexp
+}
+
+type packageMapping []*packageRule
+
+func loadPackageMapping(p string) (packageMapping, error) {
+	contents, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules packageMapping
+	if err := json.Unmarshal(contents, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+
+	for _, r := range rules {
+		if r.workflow, err = regexp.Compile(r.Workflow); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		if r.job, err = regexp.Compile(r.Job); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// packageOf returns the package of the first rule matching the job, if any.
+func (m packageMapping) packageOf(r jobRecord) (string, bool) {
+	for _, rule := range m {
+		if !rule.workflow.MatchString(r.Workflow) {
+			continue
+		}
+
+		if match := rule.job.FindStringSubmatchIndex(r.Job); match != nil {
+			return string(rule.job.ExpandString(nil, rule.Package, r.Job, match)), true
+		}
+	}
+
+	return "", false
+}
+
+// packageSeries is the daily CI cost and duration of a package.
+type packageSeries struct {
+	Package string         `json:"package"`
+	Minutes float64        `json:"minutes"`
+	Cost    float64        `json:"cost"`
+	Daily   []packageDaily `json:"daily"`
+}
+
+type packageDaily struct {
+	Day            time.Time     `json:"day"`
+	Jobs           int           `json:"jobs"`
+	Minutes        float64       `json:"minutes"`
+	Cost           float64       `json:"cost"`
+	MedianDuration time.Duration `json:"median_duration"`
+	Failures       int           `json:"failures"`
+}
+
+// attributePackages returns the daily series of each package, the most
+// expensive first. Jobs no rule matches are attributed to "(unmapped)".
+func attributePackages(records []jobRecord, m packageMapping) []packageSeries {
+	type key struct {
+		pkg string
+		day time.Time
+	}
+
+	days := map[key][]jobRecord{}
+	totals := map[string]*packageSeries{}
+	for _, r := range records {
+		pkg, ok := m.packageOf(r)
+		if !ok {
+			pkg = "(unmapped)"
+		}
+
+		k := key{pkg, r.Start.UTC().Truncate(24 * time.Hour)}
+		days[k] = append(days[k], r)
+
+		if totals[pkg] == nil {
+			totals[pkg] = &packageSeries{Package: pkg}
+		}
+		totals[pkg].Minutes += r.Minutes
+		totals[pkg].Cost += jobCost(r)
+	}
+
+	for k, jobs := range days {
+		d := packageDaily{Day: k.day, Jobs: len(jobs)}
+
+		var samples []runSample
+		for _, r := range jobs {
+			d.Minutes += r.Minutes
+			d.Cost += jobCost(r)
+			if r.Conclusion == "failure" {
+				d.Failures++
+			}
+			samples = append(samples, runSample{start: r.Start, duration: r.Duration()})
+		}
+		d.MedianDuration = median(samples)
+
+		totals[k.pkg].Daily = append(totals[k.pkg].Daily, d)
+	}
+
+	var res []packageSeries
+	for _, s := range totals {
+		sort.Slice(s.Daily, func(i, j int) bool { return s.Daily[i].Day.Before(s.Daily[j].Day) })
+		res = append(res, *s)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Cost != res[j].Cost {
+			return res[i].Cost > res[j].Cost
+		}
+		return res[i].Package < res[j].Package
+	})
+
+	return res
+}
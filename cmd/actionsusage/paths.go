@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v58/github"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+var (
+	pathsFlags     = flag.NewFlagSet("paths", flag.ExitOnError)
+	pathsIgnorable = pathsFlags.String("ignorable", "**.md,docs/**,LICENSE,.github/ISSUE_TEMPLATE/**", "Patterns of files whose changes alone rarely need CI, separated by commas; "+
+		"suggested as paths-ignore for unfiltered workflows that often run on them.")
+	pathsMinShare = pathsFlags.Float64("min_share", 0.2, "Share of a workflow's runs on unrelated changes above which a filter is suggested.")
+)
+
+// pathFilterStats describes how often a workflow's runs were caused by
+// commits that touched the paths it cares about.
+type pathFilterStats struct {
+	Repository string
+	Workflow   string
+	Filters    []string // "event: paths ..." for each filtered trigger.
+	Runs       int
+	Unrelated  int // Runs on commits that touched no relevant path.
+	Minutes    float64
+	Wasted     float64                            // Minutes of unrelated runs.
+	Ignorable  []string                           // For unfiltered workflows, -ignorable patterns that matched all of an unrelated run's files.
+	filters    map[string]workflows.TriggerFilter // By event.
+}
+
+func (s pathFilterStats) UnrelatedShare() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+
+	return float64(s.Unrelated) / float64(s.Runs)
+}
+
+func (s pathFilterStats) Suggestion() string {
+	switch {
+	case s.Unrelated == 0 || s.UnrelatedShare() < *pathsMinShare:
+		return ""
+
+	case len(s.Filters) > 0:
+		// GitHub matches pull request filters against the whole pull request,
+		// so later commits re-run the workflow even if they didn't touch its paths.
+		return "runs on commits outside its paths; skip unaffected jobs with a per-commit changed-files check"
+
+	default:
+		return fmt.Sprintf("add `paths-ignore: [%s]`", strings.Join(s.Ignorable, ", "))
+	}
+}
+
+func runPaths(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	ignorable := strings.Split(*pathsIgnorable, ",")
+
+	var stats []*pathFilterStats
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		files, err := workflows.Fetch(ctx, client, owner, name, "")
+		if err != nil {
+			return err
+		}
+
+		byPath := map[string]*pathFilterStats{}
+		for _, w := range files {
+			s := &pathFilterStats{Repository: reponame, Workflow: w.DisplayName(), filters: map[string]workflows.TriggerFilter{}}
+			for event, f := range w.Triggers() {
+				if event != "push" && event != "pull_request" {
+					continue
+				}
+
+				s.filters[event] = f
+				if f.HasPathFilter() {
+					s.Filters = append(s.Filters, fmt.Sprintf("%s: %s", event, describePathFilter(f)))
+				}
+			}
+
+			if len(s.filters) > 0 {
+				sort.Strings(s.Filters)
+				byPath[w.Path] = s
+			}
+		}
+
+		paths, err := fetchWorkflowPaths(ctx, client, reponame)
+		if err != nil {
+			return err
+		}
+
+		runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{}, *runCount)
+		if err != nil {
+			return err
+		}
+
+		changed := map[string][]string{} // By commit.
+		for _, w := range runs {
+			s := byPath[paths[w.GetWorkflowID()]]
+			if s == nil || w.GetStatus() != "completed" {
+				continue
+			}
+
+			f, ok := s.filters[w.GetEvent()]
+			if !ok {
+				continue
+			}
+
+			sha := w.GetHeadSHA()
+			if _, ok := changed[sha]; !ok {
+				if changed[sha], err = fetchChangedFiles(ctx, client, owner, name, sha); err != nil {
+					return fmt.Errorf("%s: %s: %w", reponame, sha, err)
+				}
+			}
+
+			jobs, _, err := fetchJobs(ctx, client, w, *maxJobs)
+			if err != nil {
+				return err
+			}
+
+			minutes := runMinutes(jobs)
+			s.Runs++
+			s.Minutes += minutes
+
+			unrelated := false
+			if f.HasPathFilter() {
+				unrelated = !f.MatchesPaths(changed[sha])
+			} else if patterns := ignorableMatches(ignorable, changed[sha]); len(patterns) > 0 {
+				unrelated = true
+				for _, p := range patterns {
+					if !slices.Contains(s.Ignorable, p) {
+						s.Ignorable = append(s.Ignorable, p)
+					}
+				}
+			}
+
+			if unrelated {
+				s.Unrelated++
+				s.Wasted += minutes
+			}
+		}
+
+		for _, s := range byPath {
+			if s.Runs > 0 {
+				log.Printf("%s: %s: %d of %d runs on unrelated changes", reponame, s.Workflow, s.Unrelated, s.Runs)
+				sort.Strings(s.Ignorable)
+				stats = append(stats, s)
+			}
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Wasted != stats[j].Wasted {
+			return stats[i].Wasted > stats[j].Wasted
+		}
+		return stats[i].Repository+stats[i].Workflow < stats[j].Repository+stats[j].Workflow
+	})
+
+	return pathsMarkdown.Execute(os.Stdout, stats)
+}
+
+func describePathFilter(f workflows.TriggerFilter) string {
+	if len(f.Paths) > 0 {
+		return "paths " + strings.Join(f.Paths, ", ")
+	}
+
+	return "paths-ignore " + strings.Join(f.PathsIgnore, ", ")
+}
+
+// fetchChangedFiles returns the files a commit changed relative to its first
+// parent.
+func fetchChangedFiles(ctx context.Context, client *github.Client, owner, name, sha string) ([]string, error) {
+	var files []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		commit, r, err := client.Repositories.GetCommit(ctx, owner, name, sha, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range commit.Files {
+			files = append(files, f.GetFilename())
+		}
+
+		if r.NextPage == 0 {
+			return files, nil
+		}
+		opts.Page = r.NextPage
+	}
+}
+
+// ignorableMatches returns the patterns matching files if every file matches
+// one of them, and nil otherwise.
+func ignorableMatches(patterns, files []string) []string {
+	var matched []string
+	for _, file := range files {
+		found := false
+		for _, p := range patterns {
+			if workflows.MatchGlob(p, file) {
+				found = true
+				if !slices.Contains(matched, p) {
+					matched = append(matched, p)
+				}
+				break
+			}
+		}
+
+		if !found {
+			return nil
+		}
+	}
+
+	return matched
+}
+
+var pathsMarkdown = template.Must(template.New("paths").Funcs(digestFuncs).Parse(`# Path filter effectiveness
+
+| Repository | Workflow | Path filters | Runs | Unrelated runs | Unrelated minutes | Suggestion |
+|---|---|---|---:|---:|---:|---|
+{{range .}}| {{.Repository}} | {{.Workflow}} | {{range $k, $f := .Filters}}{{if $k}}; {{end}}{{$f}}{{else}}none{{end}} | {{.Runs}} | {{.Unrelated}} | {{minutes .Wasted}} | {{.Suggestion}} |
+{{end}}
+A run is unrelated if the commit it ran on didn't change any of the workflow's paths or, for workflows without path filters, only changed files matching ` + "`-ignorable`" + `.
+`))
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+var (
+	pipelinesFlags = flag.NewFlagSet("pipelines", flag.ExitOnError)
+	pipelinesSlack = pipelinesFlags.Duration("slack", time.Minute, "How long after an upstream run ended a downstream run may still be created and be attributed to it.")
+)
+
+// pipelineStage is a run within a pipeline trace.
+type pipelineStage struct {
+	Run     *github.WorkflowRun
+	Parent  *pipelineStage
+	Depth   int
+	Minutes float64
+	Cost    float64
+
+	children []*pipelineStage
+}
+
+func (s *pipelineStage) Label() string {
+	return fmt.Sprintf("%s: %s (%s, %s)", s.Run.GetRepository().GetFullName(), s.Run.GetName(), s.Run.GetEvent(), s.Run.GetConclusion())
+}
+
+func (s *pipelineStage) Duration() time.Duration {
+	return s.Run.GetUpdatedAt().Sub(s.Run.GetCreatedAt().Time)
+}
+
+// pipelineTrace is a run and the runs it transitively triggered through
+// workflow_run and repository_dispatch events, possibly across repositories.
+type pipelineTrace struct {
+	Stages       []*pipelineStage // In trigger order, the root first.
+	Repositories int
+	Latency      time.Duration // From the root's creation until the last run completed.
+	Minutes      float64
+	Cost         float64
+}
+
+func (t pipelineTrace) Root() *pipelineStage {
+	return t.Stages[0]
+}
+
+func runPipelines(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var ws []*github.WorkflowRun
+	for _, reponame := range repoList {
+		runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{Status: "completed"}, *runCount)
+		if err != nil {
+			return err
+		}
+
+		ws = append(ws, runs...)
+	}
+
+	stages := linkPipelineStages(ws)
+
+	var traces []pipelineTrace
+	for _, s := range stages {
+		if s.Parent != nil || len(s.children) == 0 {
+			continue
+		}
+
+		t := pipelineTrace{}
+		repos := map[string]bool{}
+		var walk func(s *pipelineStage)
+		walk = func(s *pipelineStage) {
+			t.Stages = append(t.Stages, s)
+			repos[s.Run.GetRepository().GetFullName()] = true
+			for _, c := range s.children {
+				c.Depth = s.Depth + 1
+				walk(c)
+			}
+		}
+		walk(s)
+
+		for _, stage := range t.Stages {
+			jobs, _, err := fetchJobs(ctx, client, stage.Run, *maxJobs)
+			if err != nil {
+				return err
+			}
+
+			for _, job := range jobs {
+				if job.StartedAt != nil && job.CompletedAt != nil {
+					r := newJobRecord(stage.Run.GetRepository().GetFullName(), stage.Run, job)
+					stage.Minutes += r.Minutes
+					stage.Cost += jobCost(r)
+				}
+			}
+
+			t.Minutes += stage.Minutes
+			t.Cost += stage.Cost
+			t.Latency = max(t.Latency, stage.Run.GetUpdatedAt().Sub(s.Run.GetCreatedAt().Time))
+		}
+
+		t.Repositories = len(repos)
+		traces = append(traces, t)
+	}
+
+	sort.Slice(traces, func(i, j int) bool { return traces[i].Latency > traces[j].Latency })
+
+	log.Printf("reconstructed %d pipelines from %d runs", len(traces), len(ws))
+
+	return pipelinesMarkdown.Execute(os.Stdout, traces)
+}
+
+// linkPipelineStages attributes each run triggered by workflow_run or
+// repository_dispatch to its most likely upstream run. GitHub doesn't record
+// the link, so a workflow_run run is attributed to the run of the same commit
+// that completed last before it was created, and a repository_dispatch run
+// to a run by the same actor that was in progress when it was created.
+func linkPipelineStages(ws []*github.WorkflowRun) []*pipelineStage {
+	stages := make([]*pipelineStage, len(ws))
+	for k, w := range ws {
+		stages[k] = &pipelineStage{Run: w}
+	}
+
+	for _, s := range stages {
+		w := s.Run
+		created := w.GetCreatedAt().Time
+
+		var best *pipelineStage
+		for _, u := range stages {
+			up := u.Run
+			if up.GetID() == w.GetID() || !up.GetCreatedAt().Before(created) || up.GetUpdatedAt().Add(*pipelinesSlack).Before(created) {
+				continue
+			}
+
+			switch w.GetEvent() {
+			case "workflow_run":
+				if up.GetHeadSHA() != w.GetHeadSHA() || up.GetRepository().GetID() != w.GetRepository().GetID() || up.GetUpdatedAt().After(created) {
+					continue
+				}
+
+			case "repository_dispatch":
+				if up.GetActor().GetLogin() != w.GetActor().GetLogin() {
+					continue
+				}
+
+			default:
+				continue
+			}
+
+			if best == nil || up.GetUpdatedAt().After(best.Run.GetUpdatedAt().Time) {
+				best = u
+			}
+		}
+
+		if best != nil {
+			s.Parent = best
+			best.children = append(best.children, s)
+		}
+	}
+
+	return stages
+}
+
+var pipelinesMarkdown = template.Must(template.New("pipelines").Funcs(digestFuncs).Funcs(template.FuncMap{
+	"round":  func(d time.Duration) time.Duration { return d.Round(time.Second) },
+	"indent": func(depth int) string { return fmt.Sprintf("%*s", 2*depth, "") },
+}).Parse(`# Pipelines
+
+| Root | Commit | Runs | Repositories | End-to-end latency | Minutes | Cost |
+|---|---|---:|---:|---:|---:|---:|
+{{range .}}| {{.Root.Label}} | {{printf "%.7s" .Root.Run.GetHeadSHA}} | {{len .Stages}} | {{.Repositories}} | {{round .Latency}} | {{minutes .Minutes}} | ${{printf "%.2f" .Cost}} |
+{{end}}
+{{range .}}## {{.Root.Label}} at {{printf "%.7s" .Root.Run.GetHeadSHA}}
+
+{{range .Stages}}{{indent .Depth}}- {{.Label}}: {{round .Duration}}, {{minutes .Minutes}} minutes, {{.Run.GetHTMLURL}}
+{{end}}
+{{end}}`))
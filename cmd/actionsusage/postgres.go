@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// postgresStore keeps the records of each kind and day in a row of a table,
+// so that collectors on several machines can share a store.
+type postgresStore struct {
+	db   *sql.DB
+	name string // The URL, without its password.
+}
+
+func openPostgresStore(dsn string) (*postgresStore, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("bad -store: %w", err)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS actionsusage_store (
+	kind text NOT NULL,
+	day date NOT NULL,
+	records jsonb NOT NULL,
+	PRIMARY KEY (kind, day)
+)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", u.Redacted(), err)
+	}
+
+	return &postgresStore{db: db, name: u.Redacted()}, nil
+}
+
+func (s *postgresStore) String() string {
+	return s.name
+}
+
+func (s *postgresStore) read(kind string, day time.Time) ([]byte, error) {
+	var contents []byte
+	err := s.db.QueryRow(`SELECT records FROM actionsusage_store WHERE kind = $1 AND day = $2`, kind, day.UTC().Format(dateLayout)).Scan(&contents)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return contents, err
+}
+
+func (s *postgresStore) write(kind string, day time.Time, contents []byte) error {
+	_, err := s.db.Exec(`INSERT INTO actionsusage_store (kind, day, records) VALUES ($1, $2, $3)
+ON CONFLICT (kind, day) DO UPDATE SET records = EXCLUDED.records`, kind, day.UTC().Format(dateLayout), string(contents))
+	return err
+}
+
+// update locks the day's row, creating it if needed so that concurrent
+// updates of a new day wait on each other too.
+func (s *postgresStore) update(kind string, day time.Time, f func(contents []byte) ([]byte, error)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	d := day.UTC().Format(dateLayout)
+	if _, err := tx.Exec(`INSERT INTO actionsusage_store (kind, day, records) VALUES ($1, $2, '[]') ON CONFLICT (kind, day) DO NOTHING`, kind, d); err != nil {
+		return err
+	}
+
+	var contents []byte
+	if err := tx.QueryRow(`SELECT records FROM actionsusage_store WHERE kind = $1 AND day = $2 FOR UPDATE`, kind, d).Scan(&contents); err != nil {
+		return err
+	}
+
+	updated, err := f(contents)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE actionsusage_store SET records = $3 WHERE kind = $1 AND day = $2`, kind, d, string(updated)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// storeLockID is the advisory lock that lock holds.
+const storeLockID = 0x61637573 // "acus"
+
+// lock holds a transaction-level advisory lock, which the server releases
+// when the transaction ends, even if the collector dies.
+func (s *postgresStore) lock() (func(), error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, storeLockID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return func() { tx.Rollback() }, nil
+}
+
+func (s *postgresStore) remove(kind string, day time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM actionsusage_store WHERE kind = $1 AND day = $2`, kind, day.UTC().Format(dateLayout))
+	return err
+}
+
+func (s *postgresStore) days(kind string) ([]time.Time, error) {
+	rows, err := s.db.Query(`SELECT to_char(day, 'YYYY-MM-DD') FROM actionsusage_store WHERE kind = $1 ORDER BY day`, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var res []time.Time
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+
+		day, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, day)
+	}
+
+	return res, rows.Err()
+}
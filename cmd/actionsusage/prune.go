@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"time"
+)
+
+var (
+	pruneFlags      = flag.NewFlagSet("prune", flag.ExitOnError)
+	pruneKeepDaily  = pruneFlags.Int("keep_daily", 90, "Days to keep daily records for; older records are rolled up into one per week.")
+	pruneKeepWeekly = pruneFlags.Int("keep_weekly", 104, "Weeks to keep weekly records for; older records are removed.")
+)
+
+func runPrune(ctx context.Context) error {
+	if *pruneKeepDaily < 0 || *pruneKeepWeekly*7 < *pruneKeepDaily {
+		return errors.New("-keep_weekly must cover at least -keep_daily days")
+	}
+
+	s, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	rolled, err := rollup(s, "billing", today.AddDate(0, 0, -*pruneKeepDaily), mergeBillingRows)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.expire("billing", weekOf(today).AddDate(0, 0, -7**pruneKeepWeekly))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%s: billing: rolled %d days up into weeks, removed %d older days", s, rolled, removed)
+
+	// Jobs are too detailed to roll up; they're only kept for -keep_daily.
+	removed, err = s.expire("jobs", today.AddDate(0, 0, -*pruneKeepDaily))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%s: jobs: removed %d older days", s, removed)
+	return nil
+}
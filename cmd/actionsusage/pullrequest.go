@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var pullRequest = flag.Int("pr", 0, "Only consider the runs of this pull request, across all of its pushes and re-runs; requires -repos to name a single repository.")
+
+// fetchPullRequestRuns returns the runs triggered by a pull request, for each
+// of the commits that were pushed to it. Runs of commits that were since
+// force-pushed away are only found while the pull request's head still has
+// them.
+func fetchPullRequestRuns(ctx context.Context, client *github.Client, reponame string, number int) ([]*github.WorkflowRun, error) {
+	owner, name, err := ghclient.SplitRepo(reponame)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, _, err := client.PullRequests.Get(ctx, owner, name, number)
+	if err != nil {
+		return nil, fmt.Errorf("%s#%d: %w", reponame, number, err)
+	}
+
+	shas := map[string]bool{pr.GetHead().GetSHA(): true}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		commits, resp, err := client.PullRequests.ListCommits(ctx, owner, name, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s#%d: %w", reponame, number, err)
+		}
+
+		for _, c := range commits {
+			shas[c.GetSHA()] = true
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	var res []*github.WorkflowRun
+	for sha := range shas {
+		runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{HeadSHA: sha}, *runCount)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range runs {
+			if triggeredBy(w, number) {
+				res = append(res, w)
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].GetCreatedAt().After(res[j].GetCreatedAt().Time) })
+	return res, nil
+}
+
+// triggeredBy returns whether a run was triggered by the pull request. Runs of
+// pull requests from forks don't list the pull request, so any pull request
+// run of one of its commits counts.
+func triggeredBy(w *github.WorkflowRun, number int) bool {
+	if !strings.HasPrefix(w.GetEvent(), "pull_request") {
+		return false
+	}
+
+	if len(w.PullRequests) == 0 {
+		return true
+	}
+
+	for _, pr := range w.PullRequests {
+		if pr.GetNumber() == number {
+			return true
+		}
+	}
+
+	return false
+}
+
+// busyTime returns how long at least one of the jobs was running.
+func busyTime(records []jobRecord) time.Duration {
+	sorted := append([]jobRecord(nil), records...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var total time.Duration
+	var busyUntil time.Time
+	for _, r := range sorted {
+		if r.Start.After(busyUntil) {
+			total += r.Duration()
+		} else if r.End.After(busyUntil) {
+			total += r.End.Sub(busyUntil)
+		}
+		if r.End.After(busyUntil) {
+			busyUntil = r.End
+		}
+	}
+
+	return total
+}
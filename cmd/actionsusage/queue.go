@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+var (
+	queueFlags    = flag.NewFlagSet("queue", flag.ExitOnError)
+	queueInterval = queueFlags.Duration("interval", time.Minute, "How often to poll for queued jobs.")
+	queueListen   = queueFlags.String("listen", ":9090", "Address to serve Prometheus metrics on, at /metrics.")
+	queueLabels   = queueFlags.String("labels", "", "Also monitor jobs with these runs-on labels, separated by commas, e.g. ARC scale set names; "+
+		"jobs on self-hosted runners are always monitored.")
+)
+
+// labelQueue is the backlog of jobs waiting for a runner label.
+type labelQueue struct {
+	Label  string
+	Depth  int
+	Oldest time.Duration // Age of the longest waiting job.
+}
+
+// queueMonitor keeps the latest backlog of each label.
+type queueMonitor struct {
+	client   *github.Client
+	repoList []string
+	labels   []string
+
+	mu     sync.Mutex
+	queues []labelQueue
+	polled time.Time
+}
+
+func (m *queueMonitor) monitored(job *github.WorkflowJob) bool {
+	for _, l := range job.Labels {
+		if l == "self-hosted" || slices.Contains(m.labels, l) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// poll fetches the queued jobs of every repository's queued and in progress
+// runs (a run is in progress as soon as one of its jobs is).
+func (m *queueMonitor) poll(ctx context.Context) error {
+	now := time.Now()
+	byLabel := map[string]*labelQueue{}
+	for _, reponame := range m.repoList {
+		for _, status := range []string{"queued", "in_progress"} {
+			runs, err := fetchRuns(ctx, m.client, reponame, github.ListWorkflowRunsOptions{Status: status}, *runCount)
+			if err != nil {
+				return err
+			}
+
+			for _, w := range runs {
+				jobs, _, err := fetchJobs(ctx, m.client, w, *maxJobs)
+				if err != nil {
+					return err
+				}
+
+				for _, job := range jobs {
+					if job.GetStatus() != "queued" || !m.monitored(job) {
+						continue
+					}
+
+					label := strings.Join(job.Labels, ",")
+					q := byLabel[label]
+					if q == nil {
+						q = &labelQueue{Label: label}
+						byLabel[label] = q
+					}
+
+					q.Depth++
+					q.Oldest = max(q.Oldest, now.Sub(job.GetCreatedAt().Time))
+				}
+			}
+		}
+	}
+
+	var queues []labelQueue
+	for _, q := range byLabel {
+		log.Printf("queue: %s: %d jobs, oldest waiting %v", q.Label, q.Depth, q.Oldest.Round(time.Second))
+		queues = append(queues, *q)
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Label < queues[j].Label })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Labels whose backlog cleared are reported as empty rather than dropped,
+	// so that graphs go back to zero.
+	for _, previous := range m.queues {
+		if byLabel[previous.Label] == nil {
+			queues = append(queues, labelQueue{Label: previous.Label})
+		}
+	}
+
+	m.queues, m.polled = queues, now
+	return nil
+}
+
+func (m *queueMonitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP github_actions_queued_jobs Jobs waiting for a runner, per runs-on labels.")
+	fmt.Fprintln(w, "# TYPE github_actions_queued_jobs gauge")
+	for _, q := range m.queues {
+		fmt.Fprintf(w, "github_actions_queued_jobs{labels=%q} %d\n", q.Label, q.Depth)
+	}
+
+	fmt.Fprintln(w, "# HELP github_actions_queued_job_oldest_age_seconds How long the longest waiting job has been queued, per runs-on labels.")
+	fmt.Fprintln(w, "# TYPE github_actions_queued_job_oldest_age_seconds gauge")
+	for _, q := range m.queues {
+		fmt.Fprintf(w, "github_actions_queued_job_oldest_age_seconds{labels=%q} %g\n", q.Label, q.Oldest.Seconds())
+	}
+
+	if !m.polled.IsZero() {
+		fmt.Fprintln(w, "# HELP github_actions_queue_last_poll_timestamp_seconds When the queues were last polled.")
+		fmt.Fprintln(w, "# TYPE github_actions_queue_last_poll_timestamp_seconds gauge")
+		fmt.Fprintf(w, "github_actions_queue_last_poll_timestamp_seconds %d\n", m.polled.Unix())
+	}
+}
+
+func runQueue(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	m := &queueMonitor{client: client, repoList: repoList}
+	if *queueLabels != "" {
+		m.labels = strings.Split(*queueLabels, ",")
+	}
+
+	go func() {
+		for {
+			// Keep serving the last known queues while the API misbehaves.
+			if err := m.poll(ctx); err != nil {
+				log.Printf("queue: poll failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(*queueInterval):
+			}
+		}
+	}()
+
+	http.Handle("/metrics", m)
+	log.Printf("queue: serving metrics on %s/metrics", *queueListen)
+	return http.ListenAndServe(*queueListen, nil)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var engineerHourRate = flag.Float64("engineer_hour_rate", 0, "Cost in USD of an engineer hour; if set, the time people waited for runners is priced per week, alongside the machine cost.")
+
+// queueCost is what waiting for runners cost in a week.
+type queueCost struct {
+	Week        time.Time     `json:"week"` // Monday.
+	Runs        int           `json:"runs"` // Runs someone waited on.
+	Waited      time.Duration `json:"waited"`
+	HumanCost   float64       `json:"human_cost"`
+	MachineCost float64       `json:"machine_cost"` // Of all runs.
+}
+
+func (c queueCost) String() string {
+	return fmt.Sprintf("week of %s: %v waited on %d runs ($%.2f), machine cost $%.2f",
+		c.Week.Format(dateLayout), c.Waited.Round(time.Minute), c.Runs, c.HumanCost, c.MachineCost)
+}
+
+// computeQueueCosts returns the weekly cost of waiting for runners, oldest
+// first. Someone is assumed to wait on each run not triggered by a schedule
+// or a bot, for as long as its longest queued job, which approximates the
+// delay queueing added to the run.
+func computeQueueCosts(records []jobRecord) []queueCost {
+	type run struct {
+		start  time.Time
+		waited time.Duration
+		human  bool
+	}
+
+	weeks := map[time.Time]*queueCost{}
+	runs := map[int64]*run{}
+	for _, r := range records {
+		week := weekOf(r.Start)
+		if weeks[week] == nil {
+			weeks[week] = &queueCost{Week: week}
+		}
+		weeks[week].MachineCost += jobCost(r)
+
+		w := runs[r.RunID]
+		if w == nil {
+			w = &run{start: r.Start, human: r.Event != "schedule" && !isBot(r.Actor)}
+			runs[r.RunID] = w
+		}
+		if r.Start.Before(w.start) {
+			w.start = r.Start
+		}
+		w.waited = max(w.waited, r.Queued)
+	}
+
+	for _, w := range runs {
+		if !w.human {
+			continue
+		}
+
+		c := weeks[weekOf(w.start)]
+		c.Runs++
+		c.Waited += w.waited
+	}
+
+	var res []queueCost
+	for _, c := range weeks {
+		c.HumanCost = c.Waited.Hours() * *engineerHourRate
+		res = append(res, *c)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Week.Before(res[j].Week) })
+	return res
+}
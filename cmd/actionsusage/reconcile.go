@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	reconcileFlags = flag.NewFlagSet("reconcile", flag.ExitOnError)
+	reconcileSince = reconcileFlags.String("since", "", "First day (YYYY-MM-DD, UTC) to reconcile.")
+	reconcileUntil = reconcileFlags.String("until", "", "Last day (YYYY-MM-DD, UTC) to reconcile. Defaults to yesterday.")
+	reconcileTop   = reconcileFlags.Int("top", 20, "Number of discrepancies to list.")
+)
+
+// discrepancy compares computed and billed minutes of a workflow.
+type discrepancy struct {
+	Repository string
+	Workflow   string // Workflow file path.
+	Computed   float64
+	Billed     float64
+	Jobs       int
+	Reruns     int     // Runs with more than one attempt.
+	SelfHosted float64 // Computed minutes on self-hosted runners.
+}
+
+func (d *discrepancy) Diff() float64 {
+	return d.Billed - d.Computed
+}
+
+// Cause guesses why computed and billed minutes differ.
+func (d *discrepancy) Cause() string {
+	diff := d.Diff()
+	switch {
+	case diff == 0:
+		return "match"
+	case diff < 0 && d.SelfHosted > 0 && -diff <= d.SelfHosted:
+		return "self-hosted minutes are not billed"
+	case diff < 0 && d.Billed == 0:
+		return "not billed (public repository, free runners or included minutes)"
+	case math.Abs(diff) <= float64(d.Jobs):
+		return "rounding"
+	case diff > 0 && d.Reruns > 0:
+		return fmt.Sprintf("re-runs: %d runs had earlier attempts whose jobs are not listed", d.Reruns)
+	case diff > 0 && d.Computed == 0:
+		return "no runs found: deleted runs"
+	case diff > 0:
+		return "deleted runs"
+	default:
+		return "runs billed outside the window"
+	}
+}
+
+type reconciliation struct {
+	Since, Until  time.Time
+	Computed      float64
+	Billed        float64
+	Discrepancies []*discrepancy
+}
+
+func runReconcile(ctx context.Context) error {
+	if *reconcileSince == "" {
+		return fmt.Errorf("-since is required")
+	}
+
+	since, err := time.Parse(dateLayout, *reconcileSince)
+	if err != nil {
+		return fmt.Errorf("bad -since: %w", err)
+	}
+
+	until := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	if *reconcileUntil != "" {
+		until, err = time.Parse(dateLayout, *reconcileUntil)
+		if err != nil {
+			return fmt.Errorf("bad -until: %w", err)
+		}
+	}
+
+	s, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	rows, err := list[billingRow](s, "billing", since, until.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return fmt.Errorf("no billing data between %s and %s in %s; see actionsusage import", since.Format(dateLayout), until.Format(dateLayout), s)
+	}
+
+	byKey := map[string]*discrepancy{}
+	entry := func(repo, workflow string) *discrepancy {
+		k := repo + "|" + workflow
+		if byKey[k] == nil {
+			byKey[k] = &discrepancy{Repository: repo, Workflow: workflow}
+		}
+		return byKey[k]
+	}
+
+	var billedRepos []string
+	for _, row := range rows {
+		if !strings.EqualFold(row.Product, "actions") || !strings.HasPrefix(strings.ToLower(row.UnitType), "minute") || row.Repository == "" {
+			continue
+		}
+
+		entry(row.Repository, row.Workflow).Billed += row.Quantity
+		if !slices.Contains(billedRepos, row.Repository) {
+			billedRepos = append(billedRepos, row.Repository)
+		}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList := billedRepos
+	if *repos != "" || *reposFile != "" || *org != "" || *enterprise != "" {
+		repoList, err = targetRepos(ctx, client)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, reponame := range repoList {
+		paths, err := fetchWorkflowPaths(ctx, client, reponame)
+		if err != nil {
+			return err
+		}
+
+		runs, err := fetchRunsCreated(ctx, client, reponame, since, until.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+
+		for _, w := range runs {
+			jobs, _, err := fetchJobs(ctx, client, w, *maxJobs)
+			if err != nil {
+				return err
+			}
+
+			d := entry(reponame, paths[w.GetWorkflowID()])
+			if w.GetRunAttempt() > 1 {
+				d.Reruns++
+			}
+
+			d.Computed += runMinutes(jobs)
+			for _, job := range jobs {
+				if job.CompletedAt == nil || job.StartedAt == nil {
+					continue
+				}
+
+				d.Jobs++
+				if slices.Contains(job.Labels, "self-hosted") {
+					d.SelfHosted += jobMinutes(job)
+				}
+			}
+		}
+	}
+
+	r := reconciliation{Since: since, Until: until}
+	for _, d := range byKey {
+		if (*repos != "" || *reposFile != "" || *org != "" || *enterprise != "") && !slices.Contains(repoList, d.Repository) {
+			continue
+		}
+
+		r.Computed += d.Computed
+		r.Billed += d.Billed
+		r.Discrepancies = append(r.Discrepancies, d)
+	}
+
+	sort.Slice(r.Discrepancies, func(i, j int) bool {
+		a, b := r.Discrepancies[i], r.Discrepancies[j]
+		if math.Abs(a.Diff()) != math.Abs(b.Diff()) {
+			return math.Abs(a.Diff()) > math.Abs(b.Diff())
+		}
+		return a.Repository+a.Workflow < b.Repository+b.Workflow
+	})
+
+	if len(r.Discrepancies) > *reconcileTop {
+		r.Discrepancies = r.Discrepancies[:*reconcileTop]
+	}
+
+	log.Printf("reconcile: computed %s minutes, billed %s minutes", formatMinutes(r.Computed), formatMinutes(r.Billed))
+
+	return reconcileMarkdown.Execute(os.Stdout, r)
+}
+
+var reconcileMarkdown = template.Must(template.New("reconcile").Funcs(digestFuncs).Parse(`# Computed vs billed minutes: {{date .Since}} – {{date .Until}}
+
+Computed: {{minutes .Computed}} minutes. Billed: {{minutes .Billed}} minutes.
+
+| Repository | Workflow | Computed | Billed | Difference | Likely cause |
+|---|---|---:|---:|---:|---|
+{{range .Discrepancies}}| {{.Repository}} | {{or .Workflow "(unknown)"}} | {{minutes .Computed}} | {{minutes .Billed}} | {{printf "%+.1f" .Diff}} | {{.Cause}} |
+{{end}}`))
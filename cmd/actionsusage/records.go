@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// jobRecord is the flattened view of a job (and the run it belongs to) that
+// user-provided expressions are evaluated against.
+type jobRecord struct {
+	Repository  string
+	Workflow    string
+	RunID       int64
+	Attempt     int64 // Of the run, starting at 1.
+	Job         string
+	JobID       int64
+	Branch      string
+	Commit      string
+	Event       string
+	Actor       string
+	Labels      []string
+	Conclusion  string
+	RunnerGroup string        // Of the runner that picked the job up.
+	Superseded  bool          // The run was cancelled in favor of a newer one.
+	Incident    string        // Title of the incident the job overlapped, with -incidents.
+	Queued      time.Duration // From the job's creation until a runner picked it up.
+	Start       time.Time
+	End         time.Time
+	Minutes     float64
+	Steps       []stepRecord
+	CostCenter  string // Only set with -cost_center_topic_prefix.
+	Component   string // Only set with -backstage_catalog; likewise System and Owner.
+	System      string
+	Owner       string
+
+	runMetadata // Only set with -enrich.
+}
+
+type stepRecord struct {
+	Name       string
+	Conclusion string
+	Start      time.Time
+	End        time.Time
+}
+
+func newJobRecord(repo string, w *github.WorkflowRun, job *github.WorkflowJob) jobRecord {
+	return jobRecord{
+		Repository:  repo,
+		Workflow:    w.GetName(),
+		RunID:       w.GetID(),
+		Attempt:     job.GetRunAttempt(),
+		Job:         job.GetName(),
+		JobID:       job.GetID(),
+		Branch:      w.GetHeadBranch(),
+		Commit:      w.GetHeadSHA(),
+		Event:       w.GetEvent(),
+		Actor:       w.GetActor().GetLogin(),
+		Labels:      job.Labels,
+		Conclusion:  job.GetConclusion(),
+		RunnerGroup: job.GetRunnerGroupName(),
+		Start:       job.GetStartedAt().Time,
+		End:         job.GetCompletedAt().Time,
+		Queued:      job.GetStartedAt().Sub(job.GetCreatedAt().Time),
+		Minutes:     jobMinutes(job),
+		Steps:       newStepRecords(job.Steps),
+	}
+}
+
+func newStepRecords(steps []*github.TaskStep) []stepRecord {
+	var res []stepRecord
+	for _, s := range steps {
+		if s.StartedAt == nil || s.CompletedAt == nil {
+			continue
+		}
+
+		res = append(res, stepRecord{
+			Name:       s.GetName(),
+			Conclusion: s.GetConclusion(),
+			Start:      s.GetStartedAt().Time,
+			End:        s.GetCompletedAt().Time,
+		})
+	}
+
+	return res
+}
+
+func (r jobRecord) storeKey() string {
+	return fmt.Sprintf("%s/%d/%d", r.Repository, r.RunID, r.JobID)
+}
+
+func (r jobRecord) storeDay() time.Time {
+	return r.Start
+}
+
+// Label returns the job's runner labels as a single string.
+func (r jobRecord) Label() string {
+	return strings.Join(r.Labels, ",")
+}
+
+// OS returns the operating system the job is billed as: Linux, Windows or macOS.
+func (r jobRecord) OS() string {
+	return runnerOS(r.Labels)
+}
+
+// Duration returns the wall-clock duration of the job.
+func (r jobRecord) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+var recordFuncs = map[string]any{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"minutes": func(d time.Duration) float64 {
+		return d.Minutes()
+	},
+	"formatMinutes": formatMinutes,
+}
+
+type groupStats struct {
+	Key     string  `json:"key"`
+	Minutes float64 `json:"minutes"`
+	Jobs    int     `json:"jobs"`
+}
+
+func (g groupStats) String() string {
+	return fmt.Sprintf("%s: minutes=%s jobs=%d", g.Key, formatMinutes(g.Minutes), g.Jobs)
+}
+
+// grouper aggregates job records by the output of a user-provided template.
+type grouper struct {
+	tmpl   *template.Template
+	groups map[string]*groupStats
+}
+
+func newGrouper(expr string) (*grouper, error) {
+	tmpl, err := template.New("group-by").Funcs(recordFuncs).Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("bad -group-by: %w", err)
+	}
+
+	return &grouper{tmpl: tmpl, groups: map[string]*groupStats{}}, nil
+}
+
+func (g *grouper) add(r jobRecord) error {
+	var key strings.Builder
+	if err := g.tmpl.Execute(&key, r); err != nil {
+		return fmt.Errorf("-group-by: %w", err)
+	}
+
+	stats := g.groups[key.String()]
+	if stats == nil {
+		stats = &groupStats{Key: key.String()}
+		g.groups[key.String()] = stats
+	}
+
+	stats.Minutes += r.Minutes
+	stats.Jobs++
+	return nil
+}
+
+// sorted returns the groups by decreasing minutes.
+func (g *grouper) sorted() []groupStats {
+	return sortGroups(g.groups)
+}
+
+// aggregate groups job records by a key.
+func aggregate(records []jobRecord, key func(jobRecord) string) []groupStats {
+	groups := map[string]*groupStats{}
+	for _, r := range records {
+		k := key(r)
+		stats := groups[k]
+		if stats == nil {
+			stats = &groupStats{Key: k}
+			groups[k] = stats
+		}
+
+		stats.Minutes += r.Minutes
+		stats.Jobs++
+	}
+
+	return sortGroups(groups)
+}
+
+func sortGroups(groups map[string]*groupStats) []groupStats {
+	var res []groupStats
+	for _, stats := range groups {
+		res = append(res, *stats)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Minutes != res[j].Minutes {
+			return res[i].Minutes > res[j].Minutes
+		}
+		return res[i].Key < res[j].Key
+	})
+
+	return res
+}
+
+// recordFilter keeps the job records for which a user-provided template
+// evaluates to true.
+type recordFilter struct {
+	tmpl *template.Template
+}
+
+func newRecordFilter(expr string) (*recordFilter, error) {
+	tmpl, err := template.New("select").Funcs(recordFuncs).Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("bad -select: %w", err)
+	}
+
+	return &recordFilter{tmpl: tmpl}, nil
+}
+
+func (f *recordFilter) match(r jobRecord) (bool, error) {
+	var out strings.Builder
+	if err := f.tmpl.Execute(&out, r); err != nil {
+		return false, fmt.Errorf("-select: %w", err)
+	}
+
+	ok, err := strconv.ParseBool(strings.TrimSpace(out.String()))
+	if err != nil {
+		return false, fmt.Errorf("-select must evaluate to true or false, got %q", out.String())
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+type JobID struct {
+	Repository    string `json:"repo"`
+	WorkflowRunID int64  `json:"workflow_run_id"`
+	JobID         int64  `json:"job_id"`
+}
+
+type Region struct {
+	Start  int64   `json:"start"` // Unix milliseconds
+	End    int64   `json:"end"`   // Unix milliseconds
+	JobIDs []JobID `json:"count"`
+}
+
+type regionSet struct {
+	regions        []Region // Sorted
+	maxConcurrency int
+	quiet          bool // Don't log each new max concurrency.
+}
+
+func (s *regionSet) checkMaxConc(val int) {
+	if val > s.maxConcurrency {
+		s.maxConcurrency = val
+		if s.quiet {
+			return
+		}
+		log.Printf("new max concurrency: %d", s.maxConcurrency)
+	}
+}
+
+// add adds a job, in any order: the regions it partly overlaps are split, so
+// that each region lists the jobs that run throughout it.
+func (s *regionSet) add(job Region) {
+	var res []Region
+	place := func(start, end int64, jobs []JobID) {
+		res = append(res, Region{Start: start, End: end, JobIDs: jobs})
+		s.checkMaxConc(len(jobs))
+	}
+
+	at := job.Start // Start of the part of the job that's yet to be placed.
+	for _, reg := range s.regions {
+		if reg.End <= at || reg.Start >= job.End {
+			if reg.Start >= job.End && at < job.End {
+				place(at, job.End, job.JobIDs)
+				at = job.End
+			}
+			res = append(res, reg)
+			continue
+		}
+
+		if reg.Start < at {
+			res = append(res, Region{Start: reg.Start, End: at, JobIDs: reg.JobIDs})
+		} else if at < reg.Start {
+			place(at, reg.Start, job.JobIDs)
+		}
+
+		end := min(reg.End, job.End)
+		place(max(reg.Start, at), end, append(append([]JobID(nil), job.JobIDs...), reg.JobIDs...))
+
+		if reg.End > end {
+			res = append(res, Region{Start: end, End: reg.End, JobIDs: reg.JobIDs})
+		}
+		at = end
+	}
+
+	if at < job.End {
+		place(at, job.End, job.JobIDs)
+	}
+
+	s.regions = res
+}
+
+func regionRange(regions []Region) string {
+	if len(regions) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" range_start: %s range_end: %s",
+		time.UnixMilli(regions[0].Start).Format(time.RFC3339),
+		time.UnixMilli(regions[len(regions)-1].End).Format(time.RFC3339),
+	)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestRegionSetAdd(t *testing.T) {
+	ids := func(ks ...int64) []JobID {
+		var res []JobID
+		for _, k := range ks {
+			res = append(res, JobID{JobID: k})
+		}
+		return res
+	}
+
+	for _, tc := range []struct {
+		name string
+		jobs [][2]int64
+		max  int
+		want []Region
+	}{
+		{"disjoint", [][2]int64{{0, 10}, {10, 20}}, 1, []Region{
+			{0, 10, ids(0)}, {10, 20, ids(1)},
+		}},
+		{"overlapping", [][2]int64{{0, 10}, {5, 15}}, 2, []Region{
+			{0, 5, ids(0)}, {5, 10, ids(1, 0)}, {10, 15, ids(1)},
+		}},
+		{"nested", [][2]int64{{0, 100}, {10, 20}, {30, 40}, {35, 45}}, 3, []Region{
+			{0, 10, ids(0)}, {10, 20, ids(1, 0)}, {20, 30, ids(0)}, {30, 35, ids(2, 0)}, {35, 40, ids(3, 2, 0)}, {40, 45, ids(3, 0)}, {45, 100, ids(0)},
+		}},
+		{"later job first", [][2]int64{{50, 60}, {0, 100}}, 2, []Region{
+			{0, 50, ids(1)}, {50, 60, ids(1, 0)}, {60, 100, ids(1)},
+		}},
+		{"bridging", [][2]int64{{30, 40}, {0, 10}, {5, 35}}, 2, []Region{
+			{0, 5, ids(1)}, {5, 10, ids(2, 1)}, {10, 30, ids(2)}, {30, 35, ids(2, 0)}, {35, 40, ids(0)},
+		}},
+	} {
+		s := regionSet{quiet: true}
+		for k, j := range tc.jobs {
+			s.add(Region{Start: j[0], End: j[1], JobIDs: ids(int64(k))})
+		}
+
+		if s.maxConcurrency != tc.max {
+			t.Errorf("%s: max concurrency %d, want %d", tc.name, s.maxConcurrency, tc.max)
+		}
+		if !reflect.DeepEqual(s.regions, tc.want) {
+			t.Errorf("%s: got %v\nwant %v", tc.name, s.regions, tc.want)
+		}
+	}
+}
+
+func TestRegionSetAddAnyOrder(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		jobs := make([][2]int64, 1+r.Intn(20))
+		for k := range jobs {
+			start := r.Int63n(100)
+			jobs[k] = [2]int64{start, start + 1 + r.Int63n(50)}
+		}
+
+		// The most jobs running at any instant.
+		want := 0
+		for at := int64(0); at < 160; at++ {
+			running := 0
+			for _, j := range jobs {
+				if j[0] <= at && at < j[1] {
+					running++
+				}
+			}
+			want = max(want, running)
+		}
+
+		s := regionSet{quiet: true}
+		for k, j := range jobs {
+			s.add(Region{Start: j[0], End: j[1], JobIDs: []JobID{{JobID: int64(k)}}})
+		}
+
+		if s.maxConcurrency != want {
+			t.Fatalf("%v: max concurrency %d, want %d", jobs, s.maxConcurrency, want)
+		}
+
+		for k, reg := range s.regions {
+			if reg.Start >= reg.End || (k > 0 && s.regions[k-1].End > reg.Start) {
+				t.Fatalf("%v: regions overlap or are empty: %v", jobs, s.regions)
+			}
+
+			for _, id := range reg.JobIDs {
+				if j := jobs[id.JobID]; j[0] > reg.Start || j[1] < reg.End {
+					t.Fatalf("%v: job %d isn't running throughout %v", jobs, id.JobID, reg)
+				}
+			}
+		}
+	}
+}
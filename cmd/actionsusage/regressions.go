@@ -0,0 +1,213 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+var (
+	regressionZ          = flag.Float64("regression_z", 3, "Minimum Mann-Whitney z-score for a workflow slowdown to be reported as a regression.")
+	regressionMinChange  = flag.Float64("regression_min_change", 0.1, "Minimum relative increase of the median run duration for a regression to be reported.")
+	regressionMinSamples = flag.Int("regression_min_samples", 5, "Minimum number of successful runs required on each side of a regression.")
+)
+
+type dailyMedian struct {
+	Day    time.Time     `json:"day"`
+	Median time.Duration `json:"median"`
+	Runs   int           `json:"runs"`
+}
+
+// workflowDurations is the per-day median wall-clock duration of the
+// successful runs of a workflow.
+type workflowDurations struct {
+	Repository string        `json:"repo"`
+	Workflow   string        `json:"workflow"`
+	Daily      []dailyMedian `json:"daily"`
+}
+
+// durationRegression is a statistically significant slowdown of a workflow.
+type durationRegression struct {
+	Repository  string        `json:"repo"`
+	Workflow    string        `json:"workflow"`
+	Since       time.Time     `json:"since"` // Start of the first slow run.
+	Before      time.Duration `json:"median_before"`
+	After       time.Duration `json:"median_after"`
+	Z           float64       `json:"z"`
+	LastGoodSHA string        `json:"last_good_sha"`
+	FirstBadSHA string        `json:"first_bad_sha"`
+	CompareURL  string        `json:"compare_url"`
+}
+
+func (r durationRegression) String() string {
+	return fmt.Sprintf("%s: %s: median run duration went from %v to %v since %s (z=%.1f): %s",
+		r.Repository, r.Workflow, r.Before.Round(time.Second), r.After.Round(time.Second), r.Since.Format(time.RFC3339), r.Z, r.CompareURL)
+}
+
+type runSample struct {
+	start    time.Time
+	duration time.Duration
+	sha      string
+}
+
+// analyzeDurations computes the daily median durations of each workflow's
+// successful runs, and finds the point (if any) where each got slower.
+func analyzeDurations(ws []*github.WorkflowRun) ([]workflowDurations, []durationRegression) {
+	type key struct{ repo, workflow string }
+
+	samples := map[key][]runSample{}
+	for _, w := range ws {
+		if w.GetConclusion() != "success" || w.RunStartedAt == nil || w.UpdatedAt == nil {
+			continue
+		}
+
+		k := key{w.GetRepository().GetFullName(), w.GetName()}
+		samples[k] = append(samples[k], runSample{
+			start:    w.RunStartedAt.Time,
+			duration: w.UpdatedAt.Sub(w.RunStartedAt.Time),
+			sha:      w.GetHeadSHA(),
+		})
+	}
+
+	var durations []workflowDurations
+	var regressions []durationRegression
+	for k, s := range samples {
+		sort.Slice(s, func(i, j int) bool { return s[i].start.Before(s[j].start) })
+
+		durations = append(durations, workflowDurations{Repository: k.repo, Workflow: k.workflow, Daily: dailyMedians(s)})
+
+		if reg, ok := findRegression(s); ok {
+			reg.Repository = k.repo
+			reg.Workflow = k.workflow
+			reg.CompareURL = fmt.Sprintf("https://github.com/%s/compare/%s...%s", k.repo, reg.LastGoodSHA, reg.FirstBadSHA)
+			regressions = append(regressions, reg)
+		}
+	}
+
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i].Repository+durations[i].Workflow < durations[j].Repository+durations[j].Workflow
+	})
+	sort.Slice(regressions, func(i, j int) bool { return regressions[i].Z > regressions[j].Z })
+
+	return durations, regressions
+}
+
+func dailyMedians(samples []runSample) []dailyMedian {
+	var res []dailyMedian
+	for i := 0; i < len(samples); {
+		day := samples[i].start.UTC().Truncate(24 * time.Hour)
+
+		j := i
+		for j < len(samples) && samples[j].start.UTC().Truncate(24*time.Hour).Equal(day) {
+			j++
+		}
+
+		res = append(res, dailyMedian{Day: day, Median: median(samples[i:j]), Runs: j - i})
+		i = j
+	}
+
+	return res
+}
+
+// findRegression tries every day boundary as a change point, and returns the
+// one where later runs are most significantly slower than earlier ones.
+func findRegression(samples []runSample) (durationRegression, bool) {
+	var best durationRegression
+	found := false
+
+	for split := *regressionMinSamples; split <= len(samples)-*regressionMinSamples; split++ {
+		if sameDay(samples[split-1].start, samples[split].start) {
+			continue
+		}
+
+		before, after := samples[:split], samples[split:]
+		z := mannWhitneyZ(before, after)
+		mb, ma := median(before), median(after)
+
+		if z < *regressionZ || float64(ma) < float64(mb)*(1+*regressionMinChange) || (found && z <= best.Z) {
+			continue
+		}
+
+		best = durationRegression{
+			Since:       after[0].start,
+			Before:      mb,
+			After:       ma,
+			Z:           z,
+			LastGoodSHA: before[len(before)-1].sha,
+			FirstBadSHA: after[0].sha,
+		}
+		found = true
+	}
+
+	return best, found
+}
+
+// mannWhitneyZ returns the normal approximation of the Mann-Whitney U
+// statistic; it's positive when the durations in b tend to be longer than a.
+func mannWhitneyZ(a, b []runSample) float64 {
+	type ranked struct {
+		d     time.Duration
+		fromB bool
+	}
+
+	all := make([]ranked, 0, len(a)+len(b))
+	for _, s := range a {
+		all = append(all, ranked{s.duration, false})
+	}
+	for _, s := range b {
+		all = append(all, ranked{s.duration, true})
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].d < all[j].d })
+
+	// Ties share the average of their ranks.
+	var rankSumB float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].d == all[i].d {
+			j++
+		}
+
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromB {
+				rankSumB += rank
+			}
+		}
+		i = j
+	}
+
+	na, nb := float64(len(a)), float64(len(b))
+	u := rankSumB - nb*(nb+1)/2
+	sigma := math.Sqrt(na * nb * (na + nb + 1) / 12)
+	if sigma == 0 {
+		return 0
+	}
+
+	return (u - na*nb/2) / sigma
+}
+
+func median(samples []runSample) time.Duration {
+	ds := make([]time.Duration, len(samples))
+	for k, s := range samples {
+		ds[k] = s.duration
+	}
+
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+
+	if len(ds) == 0 {
+		return 0
+	} else if len(ds)%2 == 1 {
+		return ds[len(ds)/2]
+	}
+
+	return (ds[len(ds)/2-1] + ds[len(ds)/2]) / 2
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.UTC().Truncate(24 * time.Hour).Equal(b.UTC().Truncate(24 * time.Hour))
+}
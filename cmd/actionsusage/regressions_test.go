@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func samples(minutes ...int) []runSample {
+	var res []runSample
+	for _, m := range minutes {
+		res = append(res, runSample{duration: time.Duration(m) * time.Minute})
+	}
+	return res
+}
+
+func TestMannWhitneyZ(t *testing.T) {
+	for _, tc := range []struct {
+		a, b []runSample
+		want float64
+	}{
+		{samples(1, 2, 3), samples(4, 5, 6), 4.5 / math.Sqrt(5.25)},
+		{samples(4, 5, 6), samples(1, 2, 3), -4.5 / math.Sqrt(5.25)},
+		{samples(1, 3, 5), samples(2, 4, 6), 1.5 / math.Sqrt(5.25)},
+		{samples(5, 5), samples(5, 5), 0}, // Ties share their ranks.
+		{nil, samples(1, 2), 0},
+	} {
+		if got := mannWhitneyZ(tc.a, tc.b); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("mannWhitneyZ(%v, %v) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestFindRegression(t *testing.T) {
+	day := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	// One run a day: seven at 10 minutes, then seven at 15.
+	var slower, steady []runSample
+	for k := 0; k < 14; k++ {
+		d := 10 * time.Minute
+		if k >= 7 {
+			d = 15 * time.Minute
+		}
+
+		at := day.AddDate(0, 0, k)
+		sha := fmt.Sprintf("sha%d", k)
+		slower = append(slower, runSample{start: at, duration: d, sha: sha})
+		steady = append(steady, runSample{start: at, duration: 10 * time.Minute, sha: sha})
+	}
+
+	reg, ok := findRegression(slower)
+	if !ok {
+		t.Fatal("no regression found")
+	}
+
+	want := durationRegression{
+		Since:       day.AddDate(0, 0, 7),
+		Before:      10 * time.Minute,
+		After:       15 * time.Minute,
+		Z:           24.5 / math.Sqrt(61.25),
+		LastGoodSHA: "sha6",
+		FirstBadSHA: "sha7",
+	}
+	if math.Abs(reg.Z-want.Z) > 1e-9 {
+		t.Errorf("z = %v, want %v", reg.Z, want.Z)
+	}
+	reg.Z = want.Z
+	if reg != want {
+		t.Errorf("got %+v\nwant %+v", reg, want)
+	}
+
+	if reg, ok := findRegression(steady); ok {
+		t.Errorf("found a regression in steady durations: %+v", reg)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+type releaseMarker struct {
+	Repository string
+	Name       string
+	At         time.Time
+}
+
+func (m releaseMarker) String() string {
+	return m.Repository + "@" + m.Name
+}
+
+// fetchReleaseMarkers returns the releases (and optionally tags) of a
+// repository that were published within [since, until).
+func fetchReleaseMarkers(ctx context.Context, client *github.Client, reponame string, since, until time.Time, includeTags bool) ([]releaseMarker, error) {
+	owner, name, err := ghclient.SplitRepo(reponame)
+	if err != nil {
+		return nil, err
+	}
+
+	var markers []releaseMarker
+	seen := map[string]bool{}
+
+	// Releases are listed newest first.
+	for k, done := 1, false; !done; k++ {
+		releases, _, err := client.Repositories.ListReleases(ctx, owner, name, &github.ListOptions{Page: k, PerPage: 100})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(releases) == 0 {
+			break
+		}
+
+		for _, rel := range releases {
+			seen[rel.GetTagName()] = true
+
+			if rel.GetDraft() {
+				continue
+			}
+
+			at := rel.GetCreatedAt().Time
+			if rel.PublishedAt != nil {
+				at = rel.PublishedAt.Time
+			}
+
+			if at.Before(since) {
+				done = true
+				continue
+			}
+
+			if at.Before(until) {
+				markers = append(markers, releaseMarker{Repository: reponame, Name: rel.GetTagName(), At: at})
+			}
+		}
+	}
+
+	if includeTags {
+		// Tags carry no timestamp of their own, so only the most recent page is
+		// considered and each tag costs a commit lookup.
+		tags, _, err := client.Repositories.ListTags(ctx, owner, name, &github.ListOptions{PerPage: 100})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range tags {
+			if seen[tag.GetName()] || tag.Commit == nil {
+				continue
+			}
+
+			commit, _, err := client.Repositories.GetCommit(ctx, owner, name, tag.Commit.GetSHA(), nil)
+			if err != nil {
+				return nil, err
+			}
+
+			at := commit.GetCommit().GetCommitter().GetDate().Time
+			if !at.Before(since) && at.Before(until) {
+				markers = append(markers, releaseMarker{Repository: reponame, Name: tag.GetName(), At: at})
+			}
+		}
+	}
+
+	sort.Slice(markers, func(i, j int) bool { return markers[i].At.Before(markers[j].At) })
+
+	log.Printf("%s: found %d release markers", reponame, len(markers))
+
+	return markers, nil
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	format       = flag.String("format", "json", "Output format: json (region data, plus group data with -group-by), template or xlsx.")
+	templatePath = flag.String("template", "", "Go template file the Report is rendered with, for -format=template.")
+	out          = flag.String("out", "", "Write the output (the region data, for json) to this file. Defaults to a temporary file for json and xlsx, and stdout otherwise.")
+)
+
+// Report is the result of a usage collection, as exposed to output formats.
+type Report struct {
+	Repos          []string
+	Branch         string // The -branch runs were filtered by, if any.
+	Event          string // The -event runs were filtered by, if any.
+	Actor          string // The -actor runs were filtered by, if any.
+	RunConclusion  string // The -conclusion runs were filtered by, if any.
+	PullRequest    int    // The -pr runs were collected for, if any.
+	RunnerLabel    string // The -runner-label jobs were filtered by, if any.
+	JobFilter      string // The -job-filter jobs were filtered by, if any.
+	JobExclude     string // The -job-exclude jobs were filtered by, if any.
+	ExcludedBots   int    // Runs left out by -exclude-bots.
+	ExcludedJobs   int    // Jobs left out by -exclude-conclusions.
+	ShortJobs      int    // Jobs left out of Regions by -min-job-duration.
+	BadTimestamps  int    // Completed jobs with missing or inconsistent timestamps, handled per -timestamp_policy.
+	Runs           int
+	TotalMinutes   float64
+	MaxConcurrency int
+	BusyTime       time.Duration // With -pr, the wall-clock time during which any of its jobs ran.
+	ByOS           []osMinutes
+	Waste          wasteSummary
+	ByConclusion   []groupStats
+	Startup        []startupFailures // Labels and workflows with startup failure loops.
+	Steps          []stepCost        // Cost attributed to step names, the most expensive first.
+	Bots           []botUsage        // Minutes of runs triggered by dependency update bots.
+	Duplicates     []duplicateJobs   // Jobs that different workflows run on the same commit.
+	Shards         []shardBalance    // Matrix jobs that split their work unevenly.
+	Hung           []hungJob         // Jobs that likely hung before being cancelled or timing out.
+	Efficiency     []runEfficiency   // Every run's efficiency, the least efficient first.
+	Capacity       []runnerCapacity  // Demand for each self-hosted runner label.
+	Simulations    []poolSimulation  // Only set with -simulate_sizes or -simulate_cap.
+	QueueCosts     []queueCost       // Only set with -engineer_hour_rate.
+	Packages       []packageSeries   // Only set with -packages.
+	Incidents      []incidentImpact  // Usage during the -incidents windows, whether marked or excluded.
+	Durations      []workflowDurations
+	Regressions    []durationRegression
+	Regions        []Region
+	Skipped        []skippedRepo  // Repositories whose runs the token can't read, with -preflight.
+	Hosting        []hostingUsage // Only set with -split_hosting.
+	Groups         []groupStats   // Only set with -group-by.
+	ByRepository   []groupStats
+	ByWorkflow     []groupStats
+	ByLabel        []groupStats
+	ByOrganization []groupStats // Only set with -enterprise.
+	ByCostCenter   []groupStats // Only set with -cost_center_topic_prefix.
+	BySystem       []groupStats // Only set with -backstage_catalog; likewise ByOwner.
+	ByOwner        []groupStats
+	Jobs           []jobRecord // The jobs that were counted.
+}
+
+func (r *Report) computeBreakdowns() {
+	r.ByOS = splitByOS(r.Jobs)
+	r.Waste = summarizeWaste(r.Jobs)
+	r.ByConclusion = aggregate(r.Jobs, func(j jobRecord) string { return j.Conclusion })
+	r.Startup = detectStartupFailures(r.Jobs)
+	r.Steps = attributeStepCosts(r.Jobs)
+	r.Bots = attributeBotUsage(r.Jobs)
+	r.Duplicates = detectDuplicateJobs(r.Jobs)
+	r.Shards = analyzeShards(r.Jobs)
+	r.Hung = detectHungJobs(r.Jobs)
+	r.Efficiency = scoreRuns(r.Jobs)
+	r.Capacity = recommendCapacity(r.Jobs, selfHosted)
+	r.ByRepository = aggregate(r.Jobs, func(j jobRecord) string { return j.Repository })
+	r.ByWorkflow = aggregate(r.Jobs, func(j jobRecord) string { return j.Repository + ": " + j.Workflow })
+	r.ByLabel = aggregate(r.Jobs, jobRecord.Label)
+}
+
+func writeReport(report *Report) error {
+	switch *format {
+	case "json":
+		return writeJSONReport(report)
+
+	case "template":
+		if *templatePath == "" {
+			return fmt.Errorf("-format=template requires -template")
+		}
+
+		tmpl, err := template.New(filepath.Base(*templatePath)).Funcs(recordFuncs).ParseFiles(*templatePath)
+		if err != nil {
+			return err
+		}
+
+		return writeOutput(func(w io.Writer) error {
+			return tmpl.Execute(w, report)
+		})
+
+	case "xlsx":
+		if *out != "" {
+			return writeOutput(func(w io.Writer) error {
+				return writeXLSX(w, reportSheets(report))
+			})
+		}
+
+		f, err := os.CreateTemp("", "usage*.xlsx")
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		if err := writeXLSX(f, reportSheets(report)); err != nil {
+			return err
+		}
+
+		log.Printf("Computed workbook: %s", f.Name())
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported -format %q", *format)
+	}
+}
+
+func writeJSONReport(report *Report) error {
+	if *out != "" {
+		if err := writeOutput(func(w io.Writer) error {
+			return encodeJSON(w, report.Regions)
+		}); err != nil {
+			return err
+		}
+	} else {
+		name, err := writeJSONTemp("regionoutput.json", report.Regions)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Computed region data: %s", name)
+	}
+
+	for _, u := range report.Hosting {
+		name, err := writeJSONTemp("regionoutput-"+strings.ToLower(u.Hosting)+".json", u.Regions)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Computed %s region data: %s", u.Hosting, name)
+	}
+
+	if report.Packages != nil {
+		name, err := writeJSONTemp("packageoutput.json", report.Packages)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Computed package data: %s", name)
+	}
+
+	if report.Groups != nil {
+		name, err := writeJSONTemp("groupoutput.json", report.Groups)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Computed group data: %s", name)
+	}
+
+	return nil
+}
+
+func reportSheets(report *Report) []sheet {
+	sheets := []sheet{
+		{name: "Summary", rows: [][]any{
+			{"Metric", "Value"},
+			{"Repositories", strings.Join(report.Repos, ", ")},
+			{"Skipped repositories", len(report.Skipped)},
+			{"Runs", report.Runs},
+			{"Jobs", len(report.Jobs)},
+			{"Total minutes", report.TotalMinutes},
+			{"Max concurrency", report.MaxConcurrency},
+			{"Cancelled job minutes", report.Waste.CancelledMinutes},
+			{"Superseded runs", report.Waste.SupersededRuns},
+			{"Superseded run minutes", report.Waste.SupersededMinutes},
+			{"Branch filter", report.Branch},
+			{"Event filter", report.Event},
+			{"Actor filter", report.Actor},
+			{"Conclusion filter", report.RunConclusion},
+			{"Pull request", report.PullRequest},
+			{"Pull request wall-clock minutes", report.BusyTime.Minutes()},
+			{"Runner label filter", report.RunnerLabel},
+			{"Job name filter", report.JobFilter},
+			{"Job name exclusion", report.JobExclude},
+			{"Excluded bot runs", report.ExcludedBots},
+			{"Excluded jobs", report.ExcludedJobs},
+			{"Jobs with bad timestamps", report.BadTimestamps},
+			{"Jobs left out of regions", report.ShortJobs},
+		}},
+		osSheet(report.ByOS),
+		groupSheet("Repositories", "Repository", report.ByRepository),
+		groupSheet("Workflows", "Workflow", report.ByWorkflow),
+		groupSheet("Labels", "Label", report.ByLabel),
+		groupSheet("Conclusions", "Conclusion", report.ByConclusion),
+	}
+
+	if len(report.Skipped) > 0 {
+		rows := [][]any{{"Repository", "Reason"}}
+		for _, s := range report.Skipped {
+			rows = append(rows, []any{s.Repo, s.Reason})
+		}
+		sheets = append(sheets, sheet{name: "Skipped repositories", rows: rows})
+	}
+
+	if len(report.Startup) > 0 {
+		rows := [][]any{{"Kind", "Key", "Startup failures", "Jobs", "Rate"}}
+		for _, s := range report.Startup {
+			rows = append(rows, []any{s.Kind, s.Key, s.Failures, s.Jobs, s.Rate})
+		}
+		sheets = append(sheets, sheet{name: "Startup failures", rows: rows})
+	}
+
+	if len(report.Steps) > 0 {
+		rows := [][]any{{"Step", "Runs", "Minutes", "Cost (USD)", "Monthly cost (USD)"}}
+		for _, s := range report.Steps {
+			rows = append(rows, []any{s.Step, s.Runs, s.Minutes, s.Cost, s.Monthly})
+		}
+		sheets = append(sheets, sheet{name: "Steps", rows: rows})
+	}
+
+	if len(report.Bots) > 0 {
+		rows := [][]any{{"Repository", "Bot", "Week", "Bot minutes", "Total minutes", "Share"}}
+		for _, b := range report.Bots {
+			for _, w := range b.Weekly {
+				share := 0.0
+				if w.TotalMinutes > 0 {
+					share = w.Minutes / w.TotalMinutes
+				}
+				rows = append(rows, []any{b.Repository, b.Bot, w.Week.Format(dateLayout), w.Minutes, w.TotalMinutes, share})
+			}
+		}
+		sheets = append(sheets, sheet{name: "Bots", rows: rows})
+	}
+
+	if len(report.Duplicates) > 0 {
+		rows := [][]any{{"Repository", "Event", "Workflow", "Job", "Duplicate workflow", "Duplicate job", "Similarity", "Occurrences", "Minutes"}}
+		for _, d := range report.Duplicates {
+			rows = append(rows, []any{d.Repository, d.Event, d.Workflows[0], d.Jobs[0], d.Workflows[1], d.Jobs[1], d.Similarity, d.Occurrences, d.Minutes})
+		}
+		sheets = append(sheets, sheet{name: "Duplicate jobs", rows: rows})
+	}
+
+	if len(report.Shards) > 0 {
+		rows := [][]any{{"Repository", "Workflow", "Job", "Shard", "Runs", "Mean duration (s)", "Stddev (s)", "Imbalance", "Suggestion"}}
+		for _, s := range report.Shards {
+			for _, b := range s.ByShard {
+				rows = append(rows, []any{s.Repository, s.Workflow, s.Job, b.Shard, b.Runs, b.Mean.Seconds(), math.Sqrt(b.Variance), s.Imbalance, s.Suggestion()})
+			}
+		}
+		sheets = append(sheets, sheet{name: "Shards", rows: rows})
+	}
+
+	if len(report.Hung) > 0 {
+		rows := [][]any{{"Repository", "Workflow", "Job", "Start", "Duration (s)", "Median (s)", "Conclusion", "Minutes", "URL"}}
+		for _, h := range report.Hung {
+			rows = append(rows, []any{h.Repository, h.Workflow, h.Job, h.Start.Format(time.RFC3339), h.Duration.Seconds(), h.Median.Seconds(), h.Conclusion, h.Minutes, h.URL})
+		}
+		sheets = append(sheets, sheet{name: "Likely hung", rows: rows})
+	}
+
+	if len(report.Efficiency) > 0 {
+		rows := [][]any{{"Repository", "Workflow", "Score", "Runner time (s)", "Idle (s)", "Setup (s)", "Cancelled (s)", "URL"}}
+		for _, e := range report.Efficiency {
+			rows = append(rows, []any{e.Repository, e.Workflow, e.Score, e.Runner.Seconds(), e.Idle.Seconds(), e.Setup.Seconds(), e.Cancelled.Seconds(), e.URL})
+		}
+		sheets = append(sheets, sheet{name: "Run efficiency", rows: rows})
+	}
+
+	if len(report.Capacity) > 0 {
+		rows := [][]any{{"Labels", "Jobs", "Peak", "Median", "Recommended"}}
+		for _, c := range report.Capacity {
+			rows = append(rows, []any{c.Label, c.Jobs, c.Peak, c.Median, c.Recommended})
+		}
+		sheets = append(sheets, sheet{name: "Runner capacity", rows: rows})
+	}
+
+	if len(report.Hosting) > 0 {
+		rows := [][]any{{"Hosting", "Jobs", "Minutes", "Max concurrency"}}
+		for _, u := range report.Hosting {
+			rows = append(rows, []any{u.Hosting, u.Jobs, u.Minutes, u.MaxConcurrency})
+		}
+		sheets = append(sheets, sheet{name: "Hosting", rows: rows})
+	}
+
+	if len(report.Simulations) > 0 {
+		rows := [][]any{{"Labels", "Runners", "Jobs", "Median queue (s)", "p95 queue (s)", "Max queue (s)", "Observed median queue (s)"}}
+		for _, s := range report.Simulations {
+			rows = append(rows, []any{s.Label, s.Size, s.Jobs, s.MedianQueue.Seconds(), s.P95Queue.Seconds(), s.MaxQueue.Seconds(), s.Observed.Seconds()})
+		}
+		sheets = append(sheets, sheet{name: "Pool simulation", rows: rows})
+	}
+
+	if len(report.QueueCosts) > 0 {
+		rows := [][]any{{"Week", "Runs waited on", "Hours waited", "Human cost (USD)", "Machine cost (USD)"}}
+		for _, c := range report.QueueCosts {
+			rows = append(rows, []any{c.Week.Format(dateLayout), c.Runs, c.Waited.Hours(), c.HumanCost, c.MachineCost})
+		}
+		sheets = append(sheets, sheet{name: "Queueing cost", rows: rows})
+	}
+
+	if len(report.Regressions) > 0 {
+		rows := [][]any{{"Repository", "Workflow", "Since", "Median before (s)", "Median after (s)", "z", "Commits"}}
+		for _, r := range report.Regressions {
+			rows = append(rows, []any{r.Repository, r.Workflow, r.Since.Format(time.RFC3339), r.Before.Seconds(), r.After.Seconds(), r.Z, r.CompareURL})
+		}
+		sheets = append(sheets, sheet{name: "Duration regressions", rows: rows})
+	}
+
+	if len(report.Incidents) > 0 {
+		rows := [][]any{{"Incident", "Start", "End", "Jobs", "Minutes", "Median queue time (s)", "Mode"}}
+		for _, i := range report.Incidents {
+			rows = append(rows, []any{i.Title, i.Start.Format(time.RFC3339), i.End.Format(time.RFC3339), i.Jobs, i.Minutes, i.MedianQueued.Seconds(), *incidentMode})
+		}
+		sheets = append(sheets, sheet{name: "Incidents", rows: rows})
+	}
+
+	if report.Packages != nil {
+		rows := [][]any{{"Package", "Day", "Jobs", "Minutes", "Cost (USD)", "Median duration (s)", "Failures"}}
+		for _, p := range report.Packages {
+			for _, d := range p.Daily {
+				rows = append(rows, []any{p.Package, d.Day.Format(dateLayout), d.Jobs, d.Minutes, d.Cost, d.MedianDuration.Seconds(), d.Failures})
+			}
+		}
+		sheets = append(sheets, sheet{name: "Packages", rows: rows})
+	}
+
+	if *enrich {
+		rows := [][]any{{"Repository", "Workflow", "Job", "Conclusion", "Start", "Minutes", "Commit", "Subject", "Author", "Pull request", "Title"}}
+		for _, j := range report.Jobs {
+			rows = append(rows, []any{j.Repository, j.Workflow, j.Job, j.Conclusion, j.Start.Format(time.RFC3339), j.Minutes, j.Commit, j.CommitSubject, j.CommitAuthor, j.PRNumber, j.PRTitle})
+		}
+		sheets = append(sheets, sheet{name: "Jobs", rows: rows})
+	}
+
+	if report.ByOrganization != nil {
+		sheets = append(sheets, groupSheet("Organizations", "Organization", report.ByOrganization))
+	}
+
+	if report.ByCostCenter != nil {
+		sheets = append(sheets, groupSheet("Cost centers", "Cost center", report.ByCostCenter))
+	}
+
+	if report.BySystem != nil {
+		sheets = append(sheets, groupSheet("Systems", "System", report.BySystem))
+		sheets = append(sheets, groupSheet("Owners", "Owner", report.ByOwner))
+	}
+
+	if report.Groups != nil {
+		sheets = append(sheets, groupSheet("Groups", "Group", report.Groups))
+	}
+
+	return sheets
+}
+
+func osSheet(byOS []osMinutes) sheet {
+	rows := [][]any{{"OS", "Minutes", "Multiplier", "Weighted minutes", "Cost share"}}
+	for _, m := range byOS {
+		rows = append(rows, []any{m.OS, m.Minutes, osMultipliers[m.OS], m.Weighted, m.CostShare})
+	}
+
+	return sheet{name: "OS", rows: rows}
+}
+
+func groupSheet(name, column string, groups []groupStats) sheet {
+	rows := [][]any{{column, "Minutes", "Jobs"}}
+	for _, g := range groups {
+		rows = append(rows, []any{g.Key, g.Minutes, g.Jobs})
+	}
+
+	return sheet{name: name, rows: rows}
+}
+
+// writeOutput calls write with -out, or stdout if unset.
+func writeOutput(write func(io.Writer) error) error {
+	if *out == "" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func writeJSONTemp(pattern string, v any) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	return f.Name(), encodeJSON(f, v)
+}
+
+func encodeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	resultFile = flag.String("result-file", "", "Write a JSON description of the outcome, including the exit code, to this file. "+
+		"Exit codes: 0 success, 1 other errors, 2 bad usage, 3 partial data, 4 rate limited, 5 authentication failure, 6 -max_minutes exceeded.")
+	maxMinutes = flag.Float64("max_minutes", 0, "If set, exit with code 6 when the total minutes exceed this, once the report is written.")
+)
+
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitPartial     = 3 // Some runs had more jobs than -max_jobs, or some repositories failed -preflight.
+	exitRateLimited = 4
+	exitAuth        = 5
+	exitThreshold   = 6
+)
+
+// usageError is a bad flag value.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// thresholdError is returned when the usage exceeded -max_minutes.
+type thresholdError struct {
+	minutes float64
+}
+
+func (e thresholdError) Error() string {
+	return fmt.Sprintf("%s minutes exceed -max_minutes=%s", formatMinutes(e.minutes), formatMinutes(*maxMinutes))
+}
+
+// runResult is the outcome of a command, as written to -result-file.
+type runResult struct {
+	Command        string        `json:"command"`
+	Status         string        `json:"status"` // ok, partial, bad_usage, rate_limited, auth_failure, threshold_exceeded or error.
+	ExitCode       int           `json:"exit_code"`
+	Error          string        `json:"error,omitempty"`
+	Started        time.Time     `json:"started"`
+	Finished       time.Time     `json:"finished"`
+	Repositories   int           `json:"repositories,omitempty"`
+	Runs           int           `json:"runs,omitempty"`
+	Jobs           int           `json:"jobs,omitempty"`
+	TruncatedRuns  int           `json:"truncated_runs,omitempty"`       // Runs with more jobs than -max_jobs.
+	Skipped        []skippedRepo `json:"skipped_repositories,omitempty"` // Repositories that failed -preflight.
+	TotalMinutes   float64       `json:"total_minutes,omitempty"`
+	MaxConcurrency int           `json:"max_concurrency,omitempty"`
+}
+
+// result is filled in by the command as it goes.
+var result runResult
+
+// finish classifies the error a command returned, writes -result-file, and
+// returns the exit code.
+func finish(err error) int {
+	result.Finished = time.Now().UTC()
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	var rateLimit *github.RateLimitError
+	var abuse *github.AbuseRateLimitError
+	var resp *github.ErrorResponse
+	var threshold thresholdError
+	var usage usageError
+	switch {
+	case err == nil && (result.TruncatedRuns > 0 || len(result.Skipped) > 0):
+		result.Status, result.ExitCode = "partial", exitPartial
+	case err == nil:
+		result.Status, result.ExitCode = "ok", exitOK
+	case errors.As(err, &rateLimit) || errors.As(err, &abuse):
+		result.Status, result.ExitCode = "rate_limited", exitRateLimited
+	case errors.Is(err, ghclient.ErrNoToken) || (errors.As(err, &resp) && resp.Response.StatusCode == http.StatusUnauthorized):
+		result.Status, result.ExitCode = "auth_failure", exitAuth
+	case errors.As(err, &usage):
+		result.Status, result.ExitCode = "bad_usage", exitUsage
+	case errors.As(err, &threshold):
+		result.Status, result.ExitCode = "threshold_exceeded", exitThreshold
+	default:
+		result.Status, result.ExitCode = "error", exitError
+	}
+
+	if *resultFile != "" {
+		if err := writeResultFile(*resultFile); err != nil {
+			log.Printf("failed to write -result-file: %v", err)
+		}
+	}
+
+	return result.ExitCode
+}
+
+func writeResultFile(p string) error {
+	contents, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(p, append(contents, '\n'), 0o644)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+func TestFinish(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		truncated int
+		status    string
+		code      int
+	}{
+		{nil, 0, "ok", exitOK},
+		{nil, 2, "partial", exitPartial},
+		{usageError{errors.New(`unsupported -rounding "up"`)}, 0, "bad_usage", exitUsage},
+		{ghclient.ErrNoToken, 0, "auth_failure", exitAuth},
+		{thresholdError{minutes: 10}, 0, "threshold_exceeded", exitThreshold},
+		{errors.New("boom"), 0, "error", exitError},
+	} {
+		result = runResult{TruncatedRuns: tc.truncated}
+		if code := finish(tc.err); code != tc.code || result.Status != tc.status {
+			t.Errorf("finish(%v) = %d, status %q; want %d, status %q", tc.err, code, result.Status, tc.code, tc.status)
+		}
+	}
+}
+
+func TestCheckFlagsMaxJobs(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		old := *maxJobs
+		*maxJobs = n
+		err := checkFlags()
+		*maxJobs = old
+
+		if err == nil {
+			t.Errorf("-max_jobs=%d: want an error", n)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// GitHub only retains workflow run history for 400 days; older runs are no
+// longer returned by the runs API.
+const runRetention = 400 * 24 * time.Hour
+
+func retentionCutoff() time.Time {
+	return time.Now().Add(-runRetention)
+}
+
+// auditRun is a workflow run reconstructed from the organization audit log.
+// The audit log only records the run's wall-clock time, not its jobs, so its
+// minutes are an approximation of what was billed.
+type auditRun struct {
+	Repository string
+	Workflow   string
+	RunID      int64
+	Conclusion string
+	Started    time.Time
+	Completed  time.Time
+}
+
+func (r auditRun) Minutes() float64 {
+	return math.Ceil(r.Completed.Sub(r.Started).Minutes())
+}
+
+// fetchAuditLogRuns returns the workflow runs an organization's audit log
+// recorded as completed within [since, until). Requires GitHub Enterprise
+// Cloud and a token with read:audit_log.
+func fetchAuditLogRuns(ctx context.Context, client *github.Client, org string, since, until time.Time) ([]auditRun, error) {
+	phrase := fmt.Sprintf("action:workflows.completed_workflow_run created:%s..%s",
+		since.UTC().Format(dateLayout), until.UTC().Format(dateLayout))
+
+	opts := &github.GetAuditLogOptions{
+		Phrase:            &phrase,
+		ListCursorOptions: github.ListCursorOptions{PerPage: 100},
+	}
+
+	var runs []auditRun
+	for {
+		entries, r, err := client.Organizations.GetAuditLog(ctx, org, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			run := auditRun{
+				Repository: stringField(e.AdditionalFields, "repo"),
+				Workflow:   stringField(e.AdditionalFields, "name"),
+				RunID:      int64(numberField(e.AdditionalFields, "workflow_run_id")),
+				Conclusion: stringField(e.AdditionalFields, "conclusion"),
+				Started:    timeField(e.AdditionalFields, "started_at"),
+				Completed:  timeField(e.AdditionalFields, "completed_at"),
+			}
+
+			if run.Started.IsZero() || run.Completed.Before(run.Started) {
+				continue
+			}
+
+			if !run.Completed.Before(since) && run.Completed.Before(until) {
+				runs = append(runs, run)
+			}
+		}
+
+		log.Printf("%s: got %d audit log entries (total runs: %d rate_limit: %d/%d)", org, len(entries), len(runs), r.Rate.Remaining, r.Rate.Limit)
+
+		if r.After == "" {
+			break
+		}
+
+		opts.ListCursorOptions.After = r.After
+	}
+
+	return runs, nil
+}
+
+func stringField(fields map[string]any, key string) string {
+	v, _ := fields[key].(string)
+	return v
+}
+
+func numberField(fields map[string]any, key string) float64 {
+	v, _ := fields[key].(float64)
+	return v
+}
+
+func timeField(fields map[string]any, key string) time.Time {
+	t, _ := time.Parse(time.RFC3339, stringField(fields, key))
+	return t
+}
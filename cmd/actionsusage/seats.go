@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+var (
+	seatsFlags       = flag.NewFlagSet("seats", flag.ExitOnError)
+	seatsDormantDays = seatsFlags.Int("dormant_days", 90, "Members without contributions to the organization, nor Copilot activity, in this many days (at most 365) are dormant.")
+)
+
+// orgSeats is the seat utilization of an organization.
+type orgSeats struct {
+	Org                  string
+	Members              int
+	Admins               int
+	OutsideCollaborators int
+	PendingInvitations   int
+	PlanSeats            int // Seats paid for, if the token may see the org's plan.
+	FilledSeats          int
+	Dormant              []dormantMember
+	Unknown              []string      // Members whose contributions couldn't be looked up, e.g. suspended users.
+	Copilot              *copilotSeats // Unless Copilot isn't enabled, or the token may not see its billing.
+}
+
+type dormantMember struct {
+	Login        string
+	Admin        bool
+	CopilotSince time.Time // When their Copilot seat was assigned, if they have one.
+}
+
+type copilotSeats struct {
+	Seats               int
+	ActiveThisCycle     int
+	InactiveThisCycle   int
+	PendingCancellation int
+	Unused              []unusedCopilotSeat // Seats without activity within -dormant_days.
+}
+
+type unusedCopilotSeat struct {
+	Login        string
+	LastActivity time.Time // Zero if it was never used.
+}
+
+func runSeats(ctx context.Context) error {
+	if *seatsDormantDays <= 0 || *seatsDormantDays > 365 {
+		return errors.New("-dormant_days must be between 1 and 365")
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	orgs, err := targetOrgs(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if len(orgs) == 0 {
+		return errors.New("-org or -enterprise is required")
+	}
+
+	since := time.Now().AddDate(0, 0, -*seatsDormantDays)
+
+	var res []*orgSeats
+	for _, o := range orgs {
+		s, err := collectSeats(ctx, client, o, since)
+		if err != nil {
+			return fmt.Errorf("%s: %w", o, err)
+		}
+
+		log.Printf("%s: %d members, %d outside collaborators, %d dormant, %d unknown", o, s.Members, s.OutsideCollaborators, len(s.Dormant), len(s.Unknown))
+		res = append(res, s)
+	}
+
+	return seatsMarkdown.Execute(os.Stdout, map[string]any{"Orgs": res, "DormantDays": *seatsDormantDays})
+}
+
+func collectSeats(ctx context.Context, client *github.Client, org string, since time.Time) (*orgSeats, error) {
+	o, _, err := client.Organizations.Get(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &orgSeats{Org: org, PlanSeats: o.GetPlan().GetSeats(), FilledSeats: o.GetPlan().GetFilledSeats()}
+
+	members, err := listOrgUsers(func(opts github.ListOptions) ([]*github.User, *github.Response, error) {
+		return client.Organizations.ListMembers(ctx, org, &github.ListMembersOptions{ListOptions: opts})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Members = len(members)
+
+	admins, err := listOrgUsers(func(opts github.ListOptions) ([]*github.User, *github.Response, error) {
+		return client.Organizations.ListMembers(ctx, org, &github.ListMembersOptions{Role: "admin", ListOptions: opts})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Admins = len(admins)
+
+	isAdmin := map[string]bool{}
+	for _, a := range admins {
+		isAdmin[a.GetLogin()] = true
+	}
+
+	outside, err := listOrgUsers(func(opts github.ListOptions) ([]*github.User, *github.Response, error) {
+		return client.Organizations.ListOutsideCollaborators(ctx, org, &github.ListOutsideCollaboratorsOptions{ListOptions: opts})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.OutsideCollaborators = len(outside)
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		invitations, resp, err := client.Organizations.ListPendingOrgInvitations(ctx, org, opts)
+		if ghclient.IsForbidden(err) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		s.PendingInvitations += len(invitations)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	copilotActivity := map[string]time.Time{} // Last activity, by login.
+	copilotAssigned := map[string]time.Time{}
+	if s.Copilot, err = collectCopilotSeats(ctx, client, org, since, copilotActivity, copilotAssigned); err != nil {
+		return nil, err
+	}
+
+	var logins []string
+	for _, m := range members {
+		if !copilotActivity[m.GetLogin()].After(since) {
+			logins = append(logins, m.GetLogin())
+		}
+	}
+
+	for len(logins) > 0 {
+		batch := logins[:min(len(logins), contributionsBatch)]
+		logins = logins[len(batch):]
+
+		active, err := contributedSince(ctx, client, o.GetNodeID(), batch, since)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, login := range batch {
+			if a, ok := active[login]; !ok {
+				s.Unknown = append(s.Unknown, login)
+			} else if !a {
+				s.Dormant = append(s.Dormant, dormantMember{Login: login, Admin: isAdmin[login], CopilotSince: copilotAssigned[login]})
+			}
+		}
+	}
+
+	sort.Slice(s.Dormant, func(i, j int) bool { return s.Dormant[i].Login < s.Dormant[j].Login })
+	sort.Strings(s.Unknown)
+	return s, nil
+}
+
+// collectCopilotSeats returns the Copilot seats of org, recording each
+// user's last activity and when their seat was assigned.
+func collectCopilotSeats(ctx context.Context, client *github.Client, org string, since time.Time, activity, assigned map[string]time.Time) (*copilotSeats, error) {
+	billing, _, err := client.Copilot.GetCopilotBilling(ctx, org)
+	if ghclient.IsNotFound(err) || ghclient.IsForbidden(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	c := &copilotSeats{}
+	if b := billing.SeatBreakdown; b != nil {
+		c.Seats, c.ActiveThisCycle, c.InactiveThisCycle, c.PendingCancellation = b.Total, b.ActiveThisCycle, b.InactiveThisCycle, b.PendingCancellation
+	}
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		seats, resp, err := client.Copilot.ListCopilotSeats(ctx, org, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, seat := range seats.Seats {
+			user, ok := seat.GetUser()
+			if !ok {
+				continue
+			}
+
+			last := seat.GetLastActivityAt().Time
+			activity[user.GetLogin()], assigned[user.GetLogin()] = last, seat.GetCreatedAt().Time
+			if last.Before(since) {
+				c.Unused = append(c.Unused, unusedCopilotSeat{Login: user.GetLogin(), LastActivity: last})
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	sort.Slice(c.Unused, func(i, j int) bool { return c.Unused[i].LastActivity.Before(c.Unused[j].LastActivity) })
+	return c, nil
+}
+
+// contributionsBatch is how many users contributedSince looks up per query.
+const contributionsBatch = 50
+
+// contributedSince returns whether each user committed, opened issues or pull
+// requests, or reviewed in the organization since then, which only the
+// GraphQL API tells. Users it couldn't look up, e.g. suspended ones, are left
+// out, rather than failing the whole batch.
+func contributedSince(ctx context.Context, client *github.Client, orgID string, logins []string, since time.Time) (map[string]bool, error) {
+	var params, fields strings.Builder
+	variables := map[string]any{"org": orgID, "from": since.UTC().Format(time.RFC3339)}
+	for k, login := range logins {
+		fmt.Fprintf(&params, ", $l%d: String!", k)
+		fmt.Fprintf(&fields, "  u%d: user(login: $l%d) { contributionsCollection(organizationID: $org, from: $from) { hasAnyContributions } }\n", k, k)
+		variables[fmt.Sprintf("l%d", k)] = login
+	}
+
+	req, err := client.NewRequest(http.MethodPost, "graphql", map[string]any{
+		"query":     fmt.Sprintf("query($org: ID!, $from: DateTime!%s) {\n%s}", params.String(), fields.String()),
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data map[string]*struct {
+			ContributionsCollection struct {
+				HasAnyContributions bool
+			}
+		}
+		Errors []struct {
+			Message string
+			Path    []any
+		}
+	}
+
+	if _, err := client.Do(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Data == nil && len(resp.Errors) > 0 {
+		return nil, errors.New(resp.Errors[0].Message)
+	}
+
+	for _, e := range resp.Errors {
+		if len(e.Path) > 0 {
+			if alias, ok := e.Path[0].(string); ok && strings.HasPrefix(alias, "u") {
+				if k, err := strconv.Atoi(alias[1:]); err == nil && k < len(logins) {
+					log.Printf("%s: unknown activity: %s", logins[k], e.Message)
+				}
+			}
+		}
+	}
+
+	res := map[string]bool{}
+	for k, login := range logins {
+		if u := resp.Data[fmt.Sprintf("u%d", k)]; u != nil {
+			res[login] = u.ContributionsCollection.HasAnyContributions
+		}
+	}
+
+	return res, nil
+}
+
+func listOrgUsers(list func(github.ListOptions) ([]*github.User, *github.Response, error)) ([]*github.User, error) {
+	var res []*github.User
+	opts := github.ListOptions{PerPage: 100}
+	for {
+		users, resp, err := list(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, users...)
+		if resp.NextPage == 0 {
+			return res, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+var seatsMarkdown = template.Must(template.New("seats").Funcs(digestFuncs).Parse(`# Seat utilization
+
+| Organization | Members | Admins | Outside collaborators | Pending invitations | Plan seats | Filled seats | Dormant ({{.DormantDays}} days) | Unknown activity | Copilot seats | Copilot active this cycle | Unused Copilot seats |
+|---|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|
+{{range .Orgs}}| {{.Org}} | {{.Members}} | {{.Admins}} | {{.OutsideCollaborators}} | {{.PendingInvitations}} | {{.PlanSeats}} | {{.FilledSeats}} | {{len .Dormant}} | {{len .Unknown}} | {{with .Copilot}}{{.Seats}} | {{.ActiveThisCycle}} | {{len .Unused}}{{else}} | | {{end}} |
+{{end}}{{range .Orgs}}{{$org := .Org}}{{if .Dormant}}
+## {{.Org}}: dormant members
+
+| Member | Admin | Copilot seat since |
+|---|---|---|
+{{range .Dormant}}| {{.Login}} | {{if .Admin}}yes{{end}} | {{if not .CopilotSince.IsZero}}{{date .CopilotSince}}{{end}} |
+{{end}}{{end}}{{if .Unknown}}
+## {{.Org}}: members of unknown activity
+
+Their contributions couldn't be looked up, e.g. as they're suspended: {{range $k, $login := .Unknown}}{{if $k}}, {{end}}{{$login}}{{end}}.
+{{end}}{{with .Copilot}}{{if .Unused}}
+## {{$org}}: Copilot seats unused for {{$.DormantDays}} days
+
+| Member | Last activity |
+|---|---|
+{{range .Unused}}| {{.Login}} | {{if .LastActivity.IsZero}}never{{else}}{{date .LastActivity}}{{end}} |
+{{end}}{{end}}{{end}}{{end}}`))
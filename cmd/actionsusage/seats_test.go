@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+func TestContributedSince(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string
+			Variables map[string]any
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := strings.Count(req.Query, "user(login:"); got != 3 {
+			t.Errorf("query looks up %d users, want 3 in one query", got)
+		}
+		if req.Variables["l2"] != "gone" {
+			t.Errorf("l2 = %v, want gone", req.Variables["l2"])
+		}
+
+		fmt.Fprint(w, `{
+  "data": {
+    "u0": {"contributionsCollection": {"hasAnyContributions": true}},
+    "u1": {"contributionsCollection": {"hasAnyContributions": false}},
+    "u2": null
+  },
+  "errors": [{"message": "Could not resolve to a User with the login of 'gone'.", "path": ["u2"]}]
+}`)
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	got, err := contributedSince(context.Background(), client, "O_1", []string{"active", "idle", "gone"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]bool{"active": true, "idle": false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
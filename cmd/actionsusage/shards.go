@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var shardImbalance = flag.Float64("shard_imbalance", 1.25, "Report sharded jobs whose slowest shard takes, on average, at least this many times the mean shard duration.")
+
+// shardBalance describes how evenly a matrix job (e.g. "test (3, 8)") splits
+// its work across shards. The slowest shard sets the wall-clock time, while
+// every shard is billed.
+type shardBalance struct {
+	Repository string        `json:"repo"`
+	Workflow   string        `json:"workflow"`
+	Job        string        `json:"job"` // Without the matrix values.
+	Runs       int           `json:"runs"`
+	Shards     int           `json:"shards"`    // The most seen in a run.
+	Imbalance  float64       `json:"imbalance"` // Mean over runs of the slowest shard's duration over the mean shard duration.
+	Slowest    string        `json:"slowest"`   // The shard that was slowest most often.
+	Excess     time.Duration `json:"excess"`    // Mean wall-clock time lost per run to the slowest shard, over the mean.
+	ByShard    []shardStats  `json:"by_shard"`
+}
+
+type shardStats struct {
+	Shard    string        `json:"shard"` // The matrix values.
+	Runs     int           `json:"runs"`
+	Mean     time.Duration `json:"mean"`
+	Variance float64       `json:"variance"` // In seconds squared.
+}
+
+func (s shardBalance) String() string {
+	return fmt.Sprintf("%s: %s / %s: %d shards, slowest at %.2fx the mean over %d runs; %s is usually slowest, adding %v of wall-clock time per run",
+		s.Repository, s.Workflow, s.Job, s.Shards, s.Imbalance, s.Runs, s.Slowest, s.Excess.Round(time.Second))
+}
+
+// Suggestion returns how to rebalance the shards.
+func (s shardBalance) Suggestion() string {
+	var mean time.Duration
+	for _, b := range s.ByShard {
+		mean += b.Mean
+	}
+	mean /= time.Duration(len(s.ByShard))
+
+	var heavy []string
+	for _, b := range s.ByShard {
+		if float64(b.Mean) > *shardImbalance*float64(mean) {
+			heavy = append(heavy, fmt.Sprintf("%s (%v over)", b.Shard, (b.Mean-mean).Round(time.Second)))
+		}
+	}
+
+	if len(heavy) == 0 {
+		return "shard durations vary between runs rather than by shard; split by timing data instead of statically"
+	}
+
+	return "move work off " + strings.Join(heavy, ", ")
+}
+
+// splitMatrixJob splits a job name like "test (2, 4)" into its base name and
+// matrix values.
+func splitMatrixJob(name string) (string, string, bool) {
+	if !strings.HasSuffix(name, ")") {
+		return "", "", false
+	}
+
+	k := strings.LastIndex(name, " (")
+	if k < 0 {
+		return "", "", false
+	}
+
+	return name[:k], name[k+2 : len(name)-1], true
+}
+
+// analyzeShards returns the sharded jobs whose work is unevenly split, the
+// largest excess first.
+func analyzeShards(records []jobRecord) []shardBalance {
+	type key struct{ repo, workflow, job string }
+	type runKey struct {
+		key
+		run int64
+	}
+
+	runs := map[runKey][]jobRecord{}
+	for _, r := range records {
+		if r.Conclusion != "success" {
+			continue
+		}
+
+		if base, _, ok := splitMatrixJob(r.Job); ok {
+			k := runKey{key{r.Repository, r.Workflow, base}, r.RunID}
+			runs[k] = append(runs[k], r)
+		}
+	}
+
+	type accum struct {
+		shardBalance
+		slowest map[string]int
+		samples map[string][]time.Duration
+		excess  time.Duration
+	}
+
+	balances := map[key]*accum{}
+	for k, shards := range runs {
+		if len(shards) < 2 {
+			continue
+		}
+
+		b := balances[k.key]
+		if b == nil {
+			b = &accum{
+				shardBalance: shardBalance{Repository: k.repo, Workflow: k.workflow, Job: k.job},
+				slowest:      map[string]int{},
+				samples:      map[string][]time.Duration{},
+			}
+			balances[k.key] = b
+		}
+
+		var total, longest time.Duration
+		slowest := ""
+		for _, r := range shards {
+			_, shard, _ := splitMatrixJob(r.Job)
+			d := r.Duration()
+			total += d
+			b.samples[shard] = append(b.samples[shard], d)
+			if d > longest {
+				longest, slowest = d, shard
+			}
+		}
+
+		mean := total / time.Duration(len(shards))
+		if mean == 0 {
+			continue
+		}
+
+		b.Runs++
+		b.Shards = max(b.Shards, len(shards))
+		b.Imbalance += float64(longest) / float64(mean)
+		b.excess += longest - mean
+		b.slowest[slowest]++
+	}
+
+	var res []shardBalance
+	for _, b := range balances {
+		if b.Runs == 0 {
+			continue
+		}
+
+		b.Imbalance /= float64(b.Runs)
+		if b.Imbalance < *shardImbalance {
+			continue
+		}
+
+		b.Excess = b.excess / time.Duration(b.Runs)
+		for shard, n := range b.slowest {
+			if n > b.slowest[b.Slowest] || (n == b.slowest[b.Slowest] && shard < b.Slowest) {
+				b.Slowest = shard
+			}
+		}
+
+		for shard, samples := range b.samples {
+			var sum time.Duration
+			for _, d := range samples {
+				sum += d
+			}
+			mean := sum / time.Duration(len(samples))
+
+			var variance float64
+			for _, d := range samples {
+				variance += (d - mean).Seconds() * (d - mean).Seconds()
+			}
+
+			b.ByShard = append(b.ByShard, shardStats{Shard: shard, Runs: len(samples), Mean: mean, Variance: variance / float64(len(samples))})
+		}
+
+		sort.Slice(b.ByShard, func(i, j int) bool { return b.ByShard[i].Shard < b.ByShard[j].Shard })
+		res = append(res, b.shardBalance)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Excess != res[j].Excess {
+			return res[i].Excess > res[j].Excess
+		}
+		return res[i].Repository+res[i].Workflow+res[i].Job < res[j].Repository+res[j].Workflow+res[j].Job
+	})
+
+	return res
+}
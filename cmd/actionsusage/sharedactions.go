@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v58/github"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+var actionsFlags = flag.NewFlagSet("actions", flag.ExitOnError)
+
+// sharedAction is a local action (./.github/actions/...) or a composite action
+// maintained within the same owners as the scanned repositories, along with
+// who uses it and how long its steps took.
+type sharedAction struct {
+	Action      string // owner/repo/path.
+	Using       string // composite, docker, node20, ...
+	Local       bool
+	UsedBy      []string // "repo: workflow / job".
+	Invocations int
+	Minutes     float64
+}
+
+func (a *sharedAction) Repositories() int {
+	repos := map[string]bool{}
+	for _, u := range a.UsedBy {
+		repo, _, _ := strings.Cut(u, ":")
+		repos[repo] = true
+	}
+
+	return len(repos)
+}
+
+func runActions(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	owners := map[string]bool{}
+	for _, reponame := range repoList {
+		owner, _, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+		owners[strings.ToLower(owner)] = true
+	}
+
+	actions := map[string]*sharedAction{}
+	definitions := map[string]*workflows.Action{} // By owner/repo/path@ref.
+
+	for _, reponame := range repoList {
+		owner, name, _ := ghclient.SplitRepo(reponame)
+
+		files, err := workflows.Fetch(ctx, client, owner, name, "")
+		if err != nil {
+			return err
+		}
+
+		// Steps are matched to runs by workflow and step name.
+		byStep := map[string]*sharedAction{}
+
+		for _, w := range files {
+			for _, id := range w.SortedJobs() {
+				for _, step := range w.Jobs[id].Steps {
+					key, local, ok := sharedActionKey(reponame, step.Uses, owners)
+					if !ok {
+						continue
+					}
+
+					def, seen := definitions[key]
+					if !seen {
+						aowner, aname, dir, ref := splitActionKey(key)
+						if def, err = workflows.FetchAction(ctx, client, aowner, aname, dir, ref); err != nil {
+							return err
+						}
+						definitions[key] = def
+					}
+
+					// Remote actions are only of interest if they're composite.
+					if def == nil || (!local && def.Runs.Using != "composite") {
+						continue
+					}
+
+					action, _, _ := strings.Cut(key, "@")
+					a := actions[action]
+					if a == nil {
+						a = &sharedAction{Action: action, Using: def.Runs.Using, Local: local}
+						actions[action] = a
+					}
+
+					a.UsedBy = append(a.UsedBy, reponame+": "+w.DisplayName()+" / "+id)
+					byStep[w.DisplayName()+"|"+step.StepName()] = a
+				}
+			}
+		}
+
+		log.Printf("%s: scanned %d workflows", reponame, len(files))
+
+		if len(byStep) == 0 {
+			continue
+		}
+
+		runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{}, *runCount)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range runs {
+			jobs, _, err := fetchJobs(ctx, client, w, *maxJobs)
+			if err != nil {
+				return err
+			}
+
+			for _, job := range jobs {
+				for _, s := range newStepRecords(job.Steps) {
+					if a := byStep[w.GetName()+"|"+s.Name]; a != nil {
+						a.Invocations++
+						a.Minutes += s.End.Sub(s.Start).Minutes()
+					}
+				}
+			}
+		}
+	}
+
+	var res []*sharedAction
+	for _, a := range actions {
+		sort.Strings(a.UsedBy)
+		res = append(res, a)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Minutes != res[j].Minutes {
+			return res[i].Minutes > res[j].Minutes
+		}
+		return res[i].Action < res[j].Action
+	})
+
+	return actionsMarkdown.Execute(os.Stdout, res)
+}
+
+// sharedActionKey returns owner/repo/path@ref for a step's action, if it's a
+// local action or an action hosted by one of owners.
+func sharedActionKey(reponame, uses string, owners map[string]bool) (key string, local, ok bool) {
+	switch {
+	case uses == "" || strings.HasPrefix(uses, "docker://"):
+		return "", false, false
+
+	case strings.HasPrefix(uses, "./"):
+		return reponame + "/" + path.Clean(uses) + "@", true, true
+	}
+
+	action, ref, _ := strings.Cut(uses, "@")
+	owner, _, _ := strings.Cut(action, "/")
+	if !owners[strings.ToLower(owner)] {
+		return "", false, false
+	}
+
+	return action + "@" + ref, false, true
+}
+
+func splitActionKey(key string) (owner, name, dir, ref string) {
+	action, ref, _ := strings.Cut(key, "@")
+	parts := strings.SplitN(action, "/", 3)
+	if len(parts) == 3 {
+		dir = parts[2]
+	}
+
+	return parts[0], parts[1], dir, ref
+}
+
+var actionsMarkdown = template.Must(template.New("actions").Funcs(digestFuncs).Parse(`# Shared action usage
+
+| Action | Kind | Repositories | Jobs | Invocations | Minutes |
+|---|---|---:|---:|---:|---:|
+{{range .}}| {{.Action}} | {{if .Local}}local {{end}}{{.Using}} | {{.Repositories}} | {{len .UsedBy}} | {{.Invocations}} | {{minutes .Minutes}} |
+{{end}}
+{{range .}}## {{.Action}}
+{{range .UsedBy}}
+- {{.}}{{end}}
+
+{{end}}`))
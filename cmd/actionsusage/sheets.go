@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"namespacelabs.dev/githubtools/internal/jwt"
+)
+
+var (
+	sheetsID          = flag.String("sheets_id", "", "If set, append a summary row to this Google Sheet (the ID in its URL).")
+	sheetsRange       = flag.String("sheets_range", "Sheet1!A1", "The range whose table the summary row is appended to.")
+	sheetsCredentials = flag.String("sheets_credentials", "", "Service account key file used to access the sheet. Defaults to $GOOGLE_APPLICATION_CREDENTIALS.")
+)
+
+type serviceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// appendSheetRow appends the report's summary row to -sheets_id. The sheet
+// must be shared with the service account.
+func appendSheetRow(ctx context.Context, report *Report) error {
+	credentials := *sheetsCredentials
+	if credentials == "" {
+		credentials = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+
+	if credentials == "" {
+		return errors.New("-sheets_id requires -sheets_credentials or GOOGLE_APPLICATION_CREDENTIALS")
+	}
+
+	token, err := serviceAccountToken(ctx, credentials, "https://www.googleapis.com/auth/spreadsheets")
+	if err != nil {
+		return fmt.Errorf("google auth: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"values": [][]any{{
+			time.Now().UTC().Format(time.RFC3339),
+			strings.Join(report.Repos, ","),
+			report.Runs,
+			len(report.Jobs),
+			report.TotalMinutes,
+			report.MaxConcurrency,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("https://sheets.googleapis.com/v4/spreadsheets/%s/values/%s:append?valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS",
+		url.PathEscape(*sheetsID), url.PathEscape(*sheetsRange))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	if _, err := doJSON(req, nil); err != nil {
+		return fmt.Errorf("sheets append: %w", err)
+	}
+
+	log.Printf("Appended summary row to sheet %s", *sheetsID)
+	return nil
+}
+
+// serviceAccountToken exchanges a self-signed JWT for an OAuth access token.
+func serviceAccountToken(ctx context.Context, keyFile, scope string) (string, error) {
+	contents, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", err
+	}
+
+	var key serviceAccountKey
+	if err := json.Unmarshal(contents, &key); err != nil {
+		return "", err
+	}
+
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	rsaKey, err := jwt.ParseKey([]byte(key.PrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("service account private key: %w", err)
+	}
+
+	now := time.Now()
+	assertion, err := jwt.Sign(rsaKey, map[string]any{
+		"iss":   key.ClientEmail,
+		"scope": scope,
+		"aud":   key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, key.TokenURI, strings.NewReader(url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if _, err := doJSON(req, &res); err != nil {
+		return "", err
+	}
+
+	return res.AccessToken, nil
+}
+
+// doJSON sends a request and decodes a JSON response into v (if non-nil),
+// turning non-2xx responses into errors.
+func doJSON(req *http.Request, v any) (*http.Response, error) {
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return res, fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if v != nil {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
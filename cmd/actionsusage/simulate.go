@@ -0,0 +1,170 @@
+package main
+
+import (
+	"container/heap"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	simulateSizes = flag.String("simulate_sizes", "", "Runner pool sizes, separated by commas, to replay the jobs of each self-hosted label against, "+
+		"reporting the queue times they would have had.")
+	simulateCaps stringList
+)
+
+func init() {
+	flag.Var(&simulateCaps, "simulate_cap", "Concurrency cap of a runner label to replay its jobs against, as labels=n, e.g. self-hosted,linux=8 (repeatable).")
+}
+
+// poolSimulation is how long the jobs of a runner label would have queued
+// with a given number of runners.
+type poolSimulation struct {
+	Label       string
+	Size        int
+	Jobs        int
+	MedianQueue time.Duration
+	P95Queue    time.Duration
+	MaxQueue    time.Duration
+	Observed    time.Duration // Median queue time actually observed.
+}
+
+func (s poolSimulation) String() string {
+	return fmt.Sprintf("%s with %d runners: %d jobs, median queue %v (observed %v), p95 %v, max %v", s.Label, s.Size, s.Jobs,
+		s.MedianQueue.Round(time.Second), s.Observed.Round(time.Second), s.P95Queue.Round(time.Second), s.MaxQueue.Round(time.Second))
+}
+
+// parsePoolSizes parses -simulate_sizes and -simulate_cap.
+func parsePoolSizes() ([]int, map[string]int, error) {
+	var sizes []int
+	if *simulateSizes != "" {
+		for _, v := range strings.Split(*simulateSizes, ",") {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, nil, fmt.Errorf("bad -simulate_sizes %q", v)
+			}
+			sizes = append(sizes, n)
+		}
+	}
+
+	caps := map[string]int{}
+	for _, c := range simulateCaps {
+		k := strings.LastIndex(c, "=")
+		if k < 0 {
+			return nil, nil, fmt.Errorf("bad -simulate_cap %q: want labels=n", c)
+		}
+
+		n, err := strconv.Atoi(c[k+1:])
+		if err != nil || n < 1 {
+			return nil, nil, fmt.Errorf("bad -simulate_cap %q: want labels=n", c)
+		}
+		caps[c[:k]] = n
+	}
+
+	return sizes, caps, nil
+}
+
+// simulatePools replays the observed arrivals and durations of jobs against
+// each label's -simulate_cap, or each of -simulate_sizes for self-hosted
+// labels without a cap. Jobs are assigned to the first free runner in the
+// order they were created.
+func simulatePools(records []jobRecord, sizes []int, caps map[string]int) []poolSimulation {
+	byLabel := map[string][]jobRecord{}
+	for _, r := range records {
+		if _, ok := caps[r.Label()]; ok || (len(sizes) > 0 && selfHosted(r)) {
+			byLabel[r.Label()] = append(byLabel[r.Label()], r)
+		}
+	}
+
+	var labels []string
+	for l := range byLabel {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+
+	var res []poolSimulation
+	for _, label := range labels {
+		jobs := byLabel[label]
+		sort.Slice(jobs, func(i, j int) bool { return arrival(jobs[i]).Before(arrival(jobs[j])) })
+
+		var observed []time.Duration
+		for _, r := range jobs {
+			observed = append(observed, r.Queued)
+		}
+		sort.Slice(observed, func(i, j int) bool { return observed[i] < observed[j] })
+
+		labelSizes := sizes
+		if n, ok := caps[label]; ok {
+			labelSizes = []int{n}
+		}
+
+		for _, size := range labelSizes {
+			queued := replay(jobs, size)
+			res = append(res, poolSimulation{
+				Label:       label,
+				Size:        size,
+				Jobs:        len(jobs),
+				MedianQueue: percentile(queued, 0.5),
+				P95Queue:    percentile(queued, 0.95),
+				MaxQueue:    queued[len(queued)-1],
+				Observed:    percentile(observed, 0.5),
+			})
+		}
+	}
+
+	return res
+}
+
+func arrival(r jobRecord) time.Time {
+	return r.Start.Add(-r.Queued)
+}
+
+// replay returns the sorted queue times of jobs, sorted by arrival, on a pool
+// of size runners.
+func replay(jobs []jobRecord, size int) []time.Duration {
+	free := make(runnerHeap, size) // When each runner is next free.
+	var queued []time.Duration
+	for _, r := range jobs {
+		at := arrival(r)
+		start := free[0]
+		if start.Before(at) {
+			start = at
+		}
+
+		queued = append(queued, start.Sub(at))
+		free[0] = start.Add(r.Duration())
+		heap.Fix(&free, 0)
+	}
+
+	sort.Slice(queued, func(i, j int) bool { return queued[i] < queued[j] })
+	return queued
+}
+
+// runnerHeap is a min-heap of the times at which runners become free.
+type runnerHeap []time.Time
+
+func (h runnerHeap) Len() int {
+	return len(h)
+}
+
+func (h runnerHeap) Less(i, j int) bool {
+	return h[i].Before(h[j])
+}
+
+func (h runnerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *runnerHeap) Push(x any) {
+	*h = append(*h, x.(time.Time))
+}
+
+func (h *runnerHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
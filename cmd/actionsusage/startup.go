@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	startupFailureWindow = flag.Duration("startup_failure_window", time.Minute, "Jobs that fail within this long of starting count as startup failures.")
+	startupFailureMin    = flag.Int("startup_failure_min", 3, "Flag runner labels and workflows with at least this many startup failures.")
+)
+
+// startupFailures counts the jobs of a runner label or workflow that failed
+// right after starting, which usually means the runner never became healthy
+// (image pull failures, registration issues) rather than a failing build.
+type startupFailures struct {
+	Kind     string  `json:"kind"` // "label" or "workflow".
+	Key      string  `json:"key"`
+	Failures int     `json:"failures"`
+	Jobs     int     `json:"jobs"`
+	Rate     float64 `json:"rate"`
+}
+
+func (s startupFailures) String() string {
+	return fmt.Sprintf("%s %s: %d of %d jobs failed within %v of starting (%.0f%%)", s.Kind, s.Key, s.Failures, s.Jobs, *startupFailureWindow, 100*s.Rate)
+}
+
+func isStartupFailure(r jobRecord) bool {
+	return r.Conclusion == "failure" && r.Duration() < *startupFailureWindow
+}
+
+// detectStartupFailures returns the labels and workflows with crash-loop
+// patterns, the worst first.
+func detectStartupFailures(records []jobRecord) []startupFailures {
+	type key struct{ kind, key string }
+
+	counts := map[key]*startupFailures{}
+	count := func(k key, failed bool) {
+		s := counts[k]
+		if s == nil {
+			s = &startupFailures{Kind: k.kind, Key: k.key}
+			counts[k] = s
+		}
+
+		s.Jobs++
+		if failed {
+			s.Failures++
+		}
+	}
+
+	for _, r := range records {
+		failed := isStartupFailure(r)
+		count(key{"label", r.Repository + " " + r.Label()}, failed)
+		count(key{"workflow", r.Repository + ": " + r.Workflow}, failed)
+	}
+
+	var res []startupFailures
+	for _, s := range counts {
+		if s.Failures >= *startupFailureMin {
+			s.Rate = float64(s.Failures) / float64(s.Jobs)
+			res = append(res, *s)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Failures != res[j].Failures {
+			return res[i].Failures > res[j].Failures
+		}
+		return res[i].Kind+res[i].Key < res[j].Kind+res[j].Key
+	})
+
+	return res
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	minutePrice = flag.Float64("minute_price", 0.008, "Price in USD of a Linux runner minute; other OSes are priced with their multiplier, and self-hosted runners are free.")
+	topSteps    = flag.Int("top_steps", 10, "Number of most expensive steps to log.")
+)
+
+// jobCost returns what a job cost in USD.
+func jobCost(r jobRecord) float64 {
+	return r.Minutes * osMultipliers[billedOS(r.Labels)] * *minutePrice
+}
+
+// stepCost is the cost attributed to all steps with the same name.
+type stepCost struct {
+	Step    string  `json:"step"`
+	Runs    int     `json:"runs"`
+	Minutes float64 `json:"minutes"`
+	Cost    float64 `json:"cost"`
+	Monthly float64 `json:"monthly_cost"` // Cost extrapolated to 30 days.
+}
+
+func (s stepCost) String() string {
+	return fmt.Sprintf("%s: $%.2f/month ($%.2f over %d runs, %s minutes)", s.Step, s.Monthly, s.Cost, s.Runs, formatMinutes(s.Minutes))
+}
+
+// attributeStepCosts splits each job's cost across its steps in proportion to
+// their duration, and sums it up per step name, the most expensive first.
+func attributeStepCosts(records []jobRecord) []stepCost {
+	var first, last time.Time
+	byStep := map[string]*stepCost{}
+
+	for _, r := range records {
+		if first.IsZero() || r.Start.Before(first) {
+			first = r.Start
+		}
+		if r.End.After(last) {
+			last = r.End
+		}
+
+		var total time.Duration
+		for _, s := range r.Steps {
+			total += s.End.Sub(s.Start)
+		}
+
+		if total <= 0 {
+			continue
+		}
+
+		cost := jobCost(r)
+		for _, s := range r.Steps {
+			share := float64(s.End.Sub(s.Start)) / float64(total)
+
+			c := byStep[s.Name]
+			if c == nil {
+				c = &stepCost{Step: s.Name}
+				byStep[s.Name] = c
+			}
+
+			c.Runs++
+			c.Minutes += share * r.Minutes
+			c.Cost += share * cost
+		}
+	}
+
+	window := last.Sub(first)
+
+	var res []stepCost
+	for _, c := range byStep {
+		if window > 0 {
+			c.Monthly = c.Cost * float64(30*24*time.Hour) / float64(window)
+		}
+		res = append(res, *c)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Cost != res[j].Cost {
+			return res[i].Cost > res[j].Cost
+		}
+		return res[i].Step < res[j].Step
+	})
+
+	return res
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	storeDir = flag.String("store", "", "Directory of the local data store, or a postgres:// URL of a store shared by several collectors. "+
+		"Defaults to actionsusage in the user cache directory.")
+	storeJobs = flag.Bool("store_jobs", false, "Also upsert the counted jobs into the -store, keyed by run and job ID, "+
+		"so that several collectors, e.g. one per organization, can fill a single store.")
+)
+
+// store holds records by kind of data and day, keyed so that writing the
+// same record twice replaces it. Once pruned, older days are rolled up into
+// one per week, dated on its Monday.
+type store struct {
+	storeBackend
+}
+
+// storeBackend persists the records of each kind and day as a JSON array.
+type storeBackend interface {
+	// read returns the records of a day, or nil if there are none.
+	read(kind string, day time.Time) ([]byte, error)
+	// write replaces the records of a day.
+	write(kind string, day time.Time, contents []byte) error
+	// update replaces the records of a day with what f returns given them,
+	// while holding off other updates of that day.
+	update(kind string, day time.Time, f func(contents []byte) ([]byte, error)) error
+	// remove deletes the records of a day.
+	remove(kind string, day time.Time) error
+	// days returns the days a kind has records for, oldest first.
+	days(kind string) ([]time.Time, error)
+	// lock holds off other collectors and prunes of the store until the
+	// returned function is called.
+	lock() (func(), error)
+	// String describes where records are stored, for logging.
+	String() string
+}
+
+func openStore() (*store, error) {
+	if strings.HasPrefix(*storeDir, "postgres://") || strings.HasPrefix(*storeDir, "postgresql://") {
+		b, err := openPostgresStore(*storeDir)
+		if err != nil {
+			return nil, err
+		}
+
+		return &store{b}, nil
+	}
+
+	dir := *storeDir
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("no -store and no cache directory: %w", err)
+		}
+
+		dir = filepath.Join(cache, "actionsusage")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &store{fileStore{dir: dir}}, nil
+}
+
+// keyed is implemented by records that can be written to the store.
+type keyed interface {
+	storeKey() string
+	storeDay() time.Time
+}
+
+// put upserts records into the store, grouped by day. It holds the store's
+// lock, so that several collectors can write to the same store, and prunes
+// don't roll up days while they're written. Days of weeks that were rolled
+// up are refused, as their records would be counted in the week's twice.
+func put[T keyed](s *store, kind string, records []T) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	byDay := map[string][]T{}
+	for _, r := range records {
+		day := r.storeDay().UTC().Format(dateLayout)
+		byDay[day] = append(byDay[day], r)
+	}
+
+	for day, recs := range byDay {
+		week := weekOf(recs[0].storeDay())
+		rolled, err := s.read(rolledUpKind(kind), week)
+		if err != nil {
+			return err
+		}
+		if rolled != nil {
+			return fmt.Errorf("%s: %s: %s is in the week of %s, which was rolled up by prune", s, kind, day, week.Format(dateLayout))
+		}
+	}
+
+	for _, recs := range byDay {
+		day := recs[0].storeDay()
+
+		if err := s.update(kind, day, func(contents []byte) ([]byte, error) {
+			var existing []T
+			if contents != nil {
+				if err := json.Unmarshal(contents, &existing); err != nil {
+					return nil, fmt.Errorf("%s: %s: %s: %w", s, kind, day.UTC().Format(dateLayout), err)
+				}
+			}
+
+			merged := map[string]T{}
+			for _, r := range existing {
+				merged[r.storeKey()] = r
+			}
+
+			for _, r := range recs {
+				merged[r.storeKey()] = r
+			}
+
+			keys := make([]string, 0, len(merged))
+			for k := range merged {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			res := make([]T, 0, len(keys))
+			for _, k := range keys {
+				res = append(res, merged[k])
+			}
+
+			return json.MarshalIndent(res, "", "  ")
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// list returns the records of a kind stored for days within [since, until).
+func list[T keyed](s *store, kind string, since, until time.Time) ([]T, error) {
+	var res []T
+	for day := since.UTC().Truncate(24 * time.Hour); day.Before(until); day = day.AddDate(0, 0, 1) {
+		recs, err := readRecords[T](s, kind, day)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, recs...)
+	}
+
+	return res, nil
+}
+
+// rollup replaces the daily records of a kind, for weeks that ended by until,
+// with a record per key and week, as combined by merge, and marks the weeks as
+// rolled up so that put refuses their days. It returns the number of days
+// rolled up. Callers hold the store's lock.
+func rollup[T keyed](s *store, kind string, until time.Time, merge func(week time.Time, records []T) T) (int, error) {
+	days, err := s.days(kind)
+	if err != nil {
+		return 0, err
+	}
+
+	byWeek := map[time.Time][]time.Time{}
+	for _, day := range days {
+		if week := weekOf(day); !week.AddDate(0, 0, 7).After(until) {
+			byWeek[week] = append(byWeek[week], day)
+		}
+	}
+
+	n := 0
+	for week, weekDays := range byWeek {
+		// Already rolled up, or only ever had records on the Monday.
+		if len(weekDays) == 1 && weekDays[0].Equal(week) {
+			continue
+		}
+
+		byKey := map[string][]T{}
+		for _, day := range weekDays {
+			recs, err := readRecords[T](s, kind, day)
+			if err != nil {
+				return n, err
+			}
+
+			for _, r := range recs {
+				byKey[r.storeKey()] = append(byKey[r.storeKey()], r)
+			}
+		}
+
+		keys := make([]string, 0, len(byKey))
+		for k := range byKey {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		res := make([]T, 0, len(keys))
+		for _, k := range keys {
+			res = append(res, merge(week, byKey[k]))
+		}
+
+		if err := writeRecords(s, kind, week, res); err != nil {
+			return n, err
+		}
+
+		if err := s.write(rolledUpKind(kind), week, []byte("[]")); err != nil {
+			return n, err
+		}
+
+		for _, day := range weekDays {
+			if day.Equal(week) {
+				continue
+			}
+
+			if err := s.remove(kind, day); err != nil {
+				return n, err
+			}
+		}
+
+		n += len(weekDays)
+	}
+
+	return n, nil
+}
+
+// rolledUpKind is where rollup marks the weeks of a kind it rolled up.
+func rolledUpKind(kind string) string {
+	return kind + "-rolled-up"
+}
+
+// expire removes the records of a kind for days before until, returning how
+// many days it removed. Callers hold the store's lock.
+func (s *store) expire(kind string, until time.Time) (int, error) {
+	if _, err := s.removeBefore(rolledUpKind(kind), until); err != nil {
+		return 0, err
+	}
+
+	return s.removeBefore(kind, until)
+}
+
+func (s *store) removeBefore(kind string, until time.Time) (int, error) {
+	days, err := s.days(kind)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, day := range days {
+		if !day.Before(until) {
+			break
+		}
+
+		if err := s.remove(kind, day); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+func readRecords[T any](s *store, kind string, day time.Time) ([]T, error) {
+	contents, err := s.read(kind, day)
+	if err != nil || contents == nil {
+		return nil, err
+	}
+
+	var res []T
+	if err := json.Unmarshal(contents, &res); err != nil {
+		return nil, fmt.Errorf("%s: %s: %s: %w", s, kind, day.Format(dateLayout), err)
+	}
+
+	return res, nil
+}
+
+func writeRecords[T any](s *store, kind string, day time.Time, records []T) error {
+	contents, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return s.write(kind, day, contents)
+}
+
+// fileStore is a directory of JSON files, one per kind of data and day:
+//
+//	<dir>/<kind>/<YYYY-MM-DD>.json
+type fileStore struct {
+	dir string
+}
+
+func (s fileStore) String() string {
+	return s.dir
+}
+
+func (s fileStore) path(kind string, day time.Time) string {
+	return filepath.Join(s.dir, kind, day.UTC().Format(dateLayout)+".json")
+}
+
+func (s fileStore) read(kind string, day time.Time) ([]byte, error) {
+	contents, err := os.ReadFile(s.path(kind, day))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return contents, err
+}
+
+func (s fileStore) write(kind string, day time.Time, contents []byte) error {
+	p := s.path(kind, day)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that readers never see a partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+// update relies on the store's lock, which put holds, to hold off other
+// updates.
+func (s fileStore) update(kind string, day time.Time, f func(contents []byte) ([]byte, error)) error {
+	contents, err := s.read(kind, day)
+	if err != nil {
+		return err
+	}
+
+	updated, err := f(contents)
+	if err != nil {
+		return err
+	}
+
+	return s.write(kind, day, updated)
+}
+
+func (s fileStore) lock() (func(), error) {
+	return lockFile(filepath.Join(s.dir, "store.lock"), 10*time.Minute)
+}
+
+// staleLock is how long after its holder last refreshed it a lock file is
+// considered left behind by a collector that crashed.
+const staleLock = time.Minute
+
+// lockFile creates p with the PID of its holder, waiting for up to wait for
+// whoever created it first to remove it, and returns a function that removes
+// it. The holder refreshes its modification time until then, so that a lock
+// that wasn't refreshed for staleLock is broken.
+func lockFile(p string, wait time.Duration) (func(), error) {
+	deadline := time.Now().Add(wait)
+	for {
+		f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(p)
+				return nil, err
+			}
+
+			done := make(chan struct{})
+			go refreshLock(p, done)
+			return func() {
+				close(done)
+				os.Remove(p)
+			}, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		holder, _ := os.ReadFile(p)
+		if info, err := os.Stat(p); err == nil && time.Since(info.ModTime()) > staleLock {
+			log.Printf("%s: breaking the lock of pid %s, last refreshed %v", p, strings.TrimSpace(string(holder)), info.ModTime().Format(time.RFC3339))
+			if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s: still locked by pid %s after %v", p, strings.TrimSpace(string(holder)), wait)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func refreshLock(p string, done chan struct{}) {
+	t := time.NewTicker(staleLock / 4)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-t.C:
+			if err := os.Chtimes(p, now, now); err != nil {
+				log.Printf("%s: failed to refresh: %v", p, err)
+			}
+		}
+	}
+}
+
+func (s fileStore) remove(kind string, day time.Time) error {
+	return os.Remove(s.path(kind, day))
+}
+
+func (s fileStore) days(kind string) ([]time.Time, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, kind))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var res []time.Time
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+
+		day, err := time.Parse(dateLayout, name)
+		if err != nil {
+			continue
+		}
+
+		res = append(res, day)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDay(d int) time.Time {
+	// March 4th 2024 is a Monday.
+	return time.Date(2024, 3, 4+d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestStorePut(t *testing.T) {
+	s := &store{fileStore{dir: t.TempDir()}}
+
+	if err := put(s, "billing", []billingRow{
+		{Date: testDay(0), SKU: "UBUNTU", Quantity: 1},
+		{Date: testDay(0), SKU: "WINDOWS", Quantity: 2},
+		{Date: testDay(1), SKU: "UBUNTU", Quantity: 3},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A record with the same key and day replaces the stored one.
+	if err := put(s, "billing", []billingRow{{Date: testDay(0), SKU: "UBUNTU", Quantity: 5}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := list[billingRow](s, "billing", testDay(0), testDay(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []billingRow{
+		{Date: testDay(0), SKU: "UBUNTU", Quantity: 5},
+		{Date: testDay(0), SKU: "WINDOWS", Quantity: 2},
+		{Date: testDay(1), SKU: "UBUNTU", Quantity: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := os.Stat(filepath.Join(s.storeBackend.(fileStore).dir, "store.lock")); !os.IsNotExist(err) {
+		t.Errorf("lock left behind: %v", err)
+	}
+}
+
+func TestStoreRollupAndExpire(t *testing.T) {
+	s := &store{fileStore{dir: t.TempDir()}}
+
+	var rows []billingRow
+	for d := 0; d < 10; d++ {
+		rows = append(rows, billingRow{Date: testDay(d), SKU: "UBUNTU", Quantity: 1, NetAmount: 0.5})
+	}
+	rows = append(rows, billingRow{Date: testDay(2), SKU: "MACOS", Quantity: 4})
+	if err := put(s, "billing", rows); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first week ended by day 9.
+	n, err := rollup(s, "billing", testDay(9), mergeBillingRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 7 {
+		t.Errorf("rolled up %d days, want 7", n)
+	}
+
+	days, err := s.days("billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Time{testDay(0), testDay(7), testDay(8), testDay(9)}; !reflect.DeepEqual(days, want) {
+		t.Errorf("days %v, want %v", days, want)
+	}
+
+	week, err := readRecords[billingRow](s, "billing", testDay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []billingRow{
+		{Date: testDay(0), SKU: "MACOS", Quantity: 4},
+		{Date: testDay(0), SKU: "UBUNTU", Quantity: 7, NetAmount: 3.5},
+	}
+	if !reflect.DeepEqual(week, want) {
+		t.Errorf("week %+v\nwant %+v", week, want)
+	}
+
+	// Rolling up again changes nothing.
+	if n, err := rollup(s, "billing", testDay(9), mergeBillingRows); err != nil || n != 0 {
+		t.Errorf("rolled up %d days again (%v), want none", n, err)
+	}
+
+	// Re-importing a rolled-up day would count it twice once rolled up again,
+	// or replace the week with the Monday.
+	for _, d := range []int{0, 2} {
+		if err := put(s, "billing", []billingRow{{Date: testDay(d), SKU: "UBUNTU", Quantity: 1, NetAmount: 0.5}}); err == nil {
+			t.Errorf("re-imported day %d of a rolled-up week", d)
+		}
+	}
+	if got, _ := readRecords[billingRow](s, "billing", testDay(0)); !reflect.DeepEqual(got, want) {
+		t.Errorf("week %+v after re-importing\nwant %+v", got, want)
+	}
+	if err := put(s, "billing", []billingRow{{Date: testDay(8), SKU: "UBUNTU", Quantity: 1, NetAmount: 0.5}}); err != nil {
+		t.Errorf("re-importing a day of a week that's not rolled up: %v", err)
+	}
+
+	n, err = s.expire("billing", testDay(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expired %d days, want 2", n)
+	}
+
+	if days, _ := s.days("billing"); !reflect.DeepEqual(days, []time.Time{testDay(8), testDay(9)}) {
+		t.Errorf("days %v after expiry", days)
+	}
+	if weeks, _ := s.days(rolledUpKind("billing")); len(weeks) != 0 {
+		t.Errorf("rolled-up weeks %v after expiry", weeks)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "store.lock")
+
+	unlock, err := lockFile(p, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	holder, err := os.ReadFile(p)
+	if err != nil || strings.TrimSpace(string(holder)) == "" {
+		t.Errorf("lock file holds %q (%v), want a PID", holder, err)
+	}
+
+	if _, err := lockFile(p, 200*time.Millisecond); err == nil {
+		t.Error("locked twice")
+	}
+
+	unlock()
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("lock not removed: %v", err)
+	}
+
+	// A lock left behind by a collector that crashed is broken.
+	if err := os.WriteFile(p, []byte("12345\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleLock)
+	if err := os.Chtimes(p, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err = lockFile(p, time.Second)
+	if err != nil {
+		t.Fatalf("stale lock not broken: %v", err)
+	}
+	unlock()
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Layout of the timeline, in SVG user units; the SVG scales to the page width.
+const (
+	timelineWidth      = 1000
+	timelineLabelWidth = 200
+	timelineRowHeight  = 6
+	timelineLaneGap    = 4
+	timelineAxisHeight = 20
+	timelineConcHeight = 60
+	timelineTicks      = 6
+)
+
+var timelineColors = map[string]string{
+	"success":   "#2da44e",
+	"failure":   "#cf222e",
+	"cancelled": "#8c959f",
+	"skipped":   "#d0d7de",
+}
+
+// timelineBar is a job, drawn in its lane and in a row within it, so
+// overlapping jobs don't hide each other.
+type timelineBar struct {
+	X, Y, W float64
+	Fill    string
+	Title   string
+	URL     string
+}
+
+type timelineText struct {
+	X, Y  float64
+	Label string
+}
+
+type timelineSVG struct {
+	Width, Height int
+	BarHeight     int
+	Peak          int // Most jobs running at once.
+	Ticks         []timelineText
+	Lanes         []timelineText
+	Concurrency   timelineText // Where to label the concurrency line.
+	Points        string       // Of the concurrency line.
+	Bars          []timelineBar
+}
+
+// timelineDay zooms into a day of the timeline.
+type timelineDay struct {
+	Date string
+	Jobs int
+	SVG  timelineSVG
+}
+
+type timeline struct {
+	Overview timelineSVG
+	Days     []timelineDay // If the jobs span more than one day.
+}
+
+// renderTimeline returns a static SVG timeline of the jobs with one lane
+// per runner label, under a line of how many jobs ran at once. Hover over a
+// job for its name and duration, click it to open it. As the HTML has no
+// script to zoom with, jobs spanning several days are also drawn a day at a
+// time, each in a collapsed section below.
+func renderTimeline(records []jobRecord) (htmltemplate.HTML, error) {
+	sorted := append([]jobRecord(nil), records...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var tl timeline
+	if len(sorted) == 0 {
+		tl.Overview = drawTimeline(nil, time.Time{}, time.Time{})
+		return executeTimeline(tl)
+	}
+
+	from, to := sorted[0].Start, sorted[0].End
+	for _, r := range sorted {
+		if r.End.After(to) {
+			to = r.End
+		}
+	}
+	if !to.After(from) {
+		to = from.Add(time.Minute)
+	}
+
+	tl.Overview = drawTimeline(sorted, from, to)
+
+	firstDay := from.UTC().Truncate(24 * time.Hour)
+	if to.After(firstDay.AddDate(0, 0, 1)) {
+		for day := firstDay; day.Before(to); day = day.AddDate(0, 0, 1) {
+			end := day.AddDate(0, 0, 1)
+
+			var jobs []jobRecord
+			for _, r := range sorted {
+				if r.Start.Before(end) && r.End.After(day) {
+					jobs = append(jobs, r)
+				}
+			}
+			if len(jobs) == 0 {
+				continue
+			}
+
+			tl.Days = append(tl.Days, timelineDay{
+				Date: day.Format(dateLayout),
+				Jobs: len(jobs),
+				SVG:  drawTimeline(jobs, day, end),
+			})
+		}
+	}
+
+	return executeTimeline(tl)
+}
+
+// drawTimeline draws the jobs, sorted by start, from from until to; jobs
+// running past either end are cut there.
+func drawTimeline(sorted []jobRecord, from, to time.Time) timelineSVG {
+	clip := func(r jobRecord) (time.Time, time.Time) {
+		start, end := r.Start, r.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		return start, end
+	}
+
+	var labels []string
+	lanes := map[string]int{}
+	for _, r := range sorted {
+		if _, ok := lanes[r.Label()]; !ok {
+			lanes[r.Label()] = 0
+			labels = append(labels, r.Label())
+		}
+	}
+
+	sort.Strings(labels)
+	for k, l := range labels {
+		lanes[l] = k
+	}
+
+	// Assign each job the first row of its lane that's free by its start.
+	rows := make([]int, len(sorted))
+	rowEnds := make([][]time.Time, len(labels)) // Per lane, when the last job of each row ends.
+	for k, r := range sorted {
+		lane := lanes[r.Label()]
+		ends := rowEnds[lane]
+		start, end := clip(r)
+
+		rows[k] = len(ends)
+		for row, e := range ends {
+			if !e.After(start) {
+				rows[k] = row
+				break
+			}
+		}
+
+		if rows[k] == len(ends) {
+			rowEnds[lane] = append(ends, end)
+		} else {
+			ends[rows[k]] = end
+		}
+	}
+
+	svg := timelineSVG{
+		Width:       timelineWidth,
+		BarHeight:   timelineRowHeight - 1,
+		Concurrency: timelineText{Y: timelineAxisHeight + timelineConcHeight/2},
+	}
+
+	height := timelineAxisHeight + timelineConcHeight + timelineLaneGap
+	laneTop := make([]int, len(labels))
+	for k, l := range labels {
+		laneTop[k] = height
+		if len(l) > 32 {
+			l = l[:32]
+		}
+		svg.Lanes = append(svg.Lanes, timelineText{Y: float64(height + 10), Label: l})
+		height += max(len(rowEnds[k]), 1)*timelineRowHeight + timelineLaneGap
+	}
+	svg.Height = height
+
+	if len(sorted) == 0 {
+		return svg
+	}
+
+	x := func(t time.Time) float64 {
+		return timelineLabelWidth + float64(t.Sub(from))/float64(to.Sub(from))*(timelineWidth-timelineLabelWidth)
+	}
+
+	for k := 0; k <= timelineTicks; k++ {
+		at := from.Add(to.Sub(from) * time.Duration(k) / timelineTicks)
+		svg.Ticks = append(svg.Ticks, timelineText{X: x(at), Y: 12, Label: at.UTC().Format("01-02 15:04")})
+	}
+
+	for k, r := range sorted {
+		name := r.Job
+		if r.Attempt > 1 {
+			name = fmt.Sprintf("%s (attempt %d)", r.Job, r.Attempt)
+		}
+
+		fill, ok := timelineColors[r.Conclusion]
+		if !ok {
+			fill = "#bf8700"
+		}
+
+		start, end := clip(r)
+		svg.Bars = append(svg.Bars, timelineBar{
+			X:     x(start),
+			Y:     float64(laneTop[lanes[r.Label()]] + rows[k]*timelineRowHeight),
+			W:     max(x(end)-x(start), 1),
+			Fill:  fill,
+			Title: fmt.Sprintf("%s: %s / %s (%v)", r.Repository, r.Workflow, name, r.Duration()),
+			URL:   fmt.Sprintf("https://github.com/%s/actions/runs/%d/job/%d", r.Repository, r.RunID, r.JobID),
+		})
+	}
+
+	// Number of running jobs after each start or end, drawn as steps.
+	type event struct {
+		at    time.Time
+		delta int
+	}
+	var events []event
+	for _, r := range sorted {
+		start, end := clip(r)
+		events = append(events, event{start, 1}, event{end, -1})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].at.Equal(events[j].at) {
+			return events[i].at.Before(events[j].at)
+		}
+		return events[i].delta < events[j].delta
+	})
+
+	peak, running := 1, 0
+	for _, e := range events {
+		running += e.delta
+		peak = max(peak, running)
+	}
+
+	y := func(n int) float64 {
+		return timelineAxisHeight + timelineConcHeight - float64(n)/float64(peak)*timelineConcHeight
+	}
+
+	points := []string{fmt.Sprintf("%.1f,%.1f", x(from), y(0))}
+	running = 0
+	for _, e := range events {
+		px := x(e.at)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", px, y(running)))
+		running += e.delta
+		points = append(points, fmt.Sprintf("%.1f,%.1f", px, y(running)))
+	}
+	svg.Points = strings.Join(points, " ")
+	svg.Peak = peak
+	svg.Concurrency.Label = fmt.Sprintf("Concurrency (peak %d)", peak)
+
+	return svg
+}
+
+func executeTimeline(tl timeline) (htmltemplate.HTML, error) {
+	var b strings.Builder
+	if err := timelineHTML.Execute(&b, tl); err != nil {
+		return "", err
+	}
+
+	return htmltemplate.HTML(b.String()), nil
+}
+
+var timelineHTML = htmltemplate.Must(htmltemplate.New("timeline").Parse(`{{define "svg"}}<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 {{.Width}} {{.Height}}" width="100%" style="font:11px sans-serif">
+{{range .Ticks}}<text x="{{printf "%.1f" .X}}" y="{{.Y}}" fill="#333">{{.Label}}</text>
+{{end}}{{if .Points}}<text x="0" y="{{.Concurrency.Y}}" fill="#333">{{.Concurrency.Label}}</text>
+<polyline points="{{.Points}}" fill="none" stroke="#0969da"/>
+{{end}}{{range .Lanes}}<text x="0" y="{{.Y}}" fill="#333">{{.Label}}</text>
+{{end}}{{$h := .BarHeight}}{{range .Bars}}<a href="{{.URL}}"><rect x="{{printf "%.1f" .X}}" y="{{.Y}}" width="{{printf "%.1f" .W}}" height="{{$h}}" fill="{{.Fill}}"><title>{{.Title}}</title></rect></a>
+{{end}}</svg>{{end}}{{template "svg" .Overview}}
+{{range .Days}}<details><summary>{{.Date}}: {{.Jobs}} {{if eq .Jobs 1}}job{{else}}jobs{{end}}, peak {{.SVG.Peak}} at once</summary>
+{{template "svg" .SVG}}
+</details>
+{{end}}`))
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderTimeline(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []jobRecord{
+		{Repository: "acme/app", Workflow: "ci", Job: "build", RunID: 1, JobID: 10, Labels: []string{"ubuntu-latest"}, Conclusion: "success", Start: start, End: start.Add(10 * time.Minute)},
+		{Repository: "acme/app", Workflow: "ci", Job: "test", RunID: 1, JobID: 11, Labels: []string{"ubuntu-latest"}, Conclusion: "failure", Start: start.Add(time.Minute), End: start.Add(5 * time.Minute)},
+		{Repository: "acme/app", Workflow: "ci", Job: "mac", RunID: 1, JobID: 12, Labels: []string{"macos-14"}, Conclusion: "success", Start: start.Add(10 * time.Minute), End: start.Add(20 * time.Minute)},
+	}
+
+	html, err := renderTimeline(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := string(html)
+	if strings.Contains(s, "<script") {
+		t.Error("timeline has a script")
+	}
+
+	for _, want := range []string{
+		`<svg `,
+		`<text x="0" y="94" fill="#333">macos-14</text>`,
+		`<text x="0" y="104" fill="#333">ubuntu-latest</text>`,
+		`<a href="https://github.com/acme/app/actions/runs/1/job/11"><rect x="240.0" y="100" width="160.0" height="5" fill="#cf222e"><title>acme/app: ci / test (4m0s)</title></rect></a>`,
+		`<text x="0" y="50" fill="#333">Concurrency (peak 2)</text>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %s in:\n%s", want, s)
+		}
+	}
+
+	if strings.Contains(s, "<details>") {
+		t.Error("jobs of a single day are drilled into")
+	}
+
+	if _, err := renderTimeline(nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRenderTimelineDays(t *testing.T) {
+	start := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	records := []jobRecord{
+		// Runs past midnight, so it's cut at the end of the first day and the
+		// start of the second.
+		{Repository: "acme/app", Workflow: "ci", Job: "soak", RunID: 1, JobID: 10, Labels: []string{"ubuntu-latest"}, Conclusion: "success", Start: start, End: start.Add(12 * time.Hour)},
+		{Repository: "acme/app", Workflow: "ci", Job: "build", RunID: 2, JobID: 20, Labels: []string{"ubuntu-latest"}, Conclusion: "success", Start: start.Add(48 * time.Hour), End: start.Add(49 * time.Hour)},
+	}
+
+	html, err := renderTimeline(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := string(html)
+	for _, want := range []string{
+		`<summary>2024-03-01: 1 job, peak 1 at once</summary>`,
+		`<rect x="800.0" y="84" width="200.0" height="5" fill="#2da44e"><title>acme/app: ci / soak (12h0m0s)</title></rect>`,
+		`<summary>2024-03-02: 1 job, peak 1 at once</summary>`,
+		`<rect x="200.0" y="84" width="200.0" height="5" fill="#2da44e"><title>acme/app: ci / soak (12h0m0s)</title></rect>`,
+		`<summary>2024-03-03: 1 job, peak 1 at once</summary>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %s in:\n%s", want, s)
+		}
+	}
+}
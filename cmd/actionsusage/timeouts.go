@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+
+	"namespacelabs.dev/githubtools/internal/ghclient"
+	"namespacelabs.dev/githubtools/internal/workflows"
+)
+
+var (
+	timeoutsFlags  = flag.NewFlagSet("timeouts", flag.ExitOnError)
+	timeoutsMargin = timeoutsFlags.Duration("margin", time.Minute, "Jobs that ran within this long of their timeout count as having timed out.")
+	timeoutsAll    = timeoutsFlags.Bool("all", false, "List every job, not only those that timed out.")
+)
+
+// jobTimeout compares a job's configured timeout-minutes with how long its
+// runs actually take.
+type jobTimeout struct {
+	Repository string
+	Workflow   string
+	Job        string
+	Timeout    int  // In minutes.
+	Default    bool // The job doesn't set timeout-minutes.
+	Durations  []time.Duration
+	TimedOut   int
+	LostMin    float64 // Minutes of the runs that timed out.
+}
+
+func (j jobTimeout) Median() time.Duration {
+	return percentile(j.Durations, 0.5)
+}
+
+func (j jobTimeout) P95() time.Duration {
+	return percentile(j.Durations, 0.95)
+}
+
+func (j jobTimeout) Max() time.Duration {
+	if len(j.Durations) == 0 {
+		return 0
+	}
+
+	return j.Durations[len(j.Durations)-1]
+}
+
+// Suggested returns a timeout leaving twice the p95 duration of the runs that
+// didn't time out, rounded up to 5 minutes.
+func (j jobTimeout) Suggested() int {
+	var completed []time.Duration
+	limit := time.Duration(j.Timeout)*time.Minute - *timeoutsMargin
+	for _, d := range j.Durations {
+		if d < limit {
+			completed = append(completed, d)
+		}
+	}
+
+	if len(completed) == 0 {
+		return 0
+	}
+
+	m := int(2*percentile(completed, 0.95).Minutes()) + 1
+	return (m + 4) / 5 * 5
+}
+
+func runTimeouts(ctx context.Context) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	var res []*jobTimeout
+	for _, reponame := range repoList {
+		owner, name, err := ghclient.SplitRepo(reponame)
+		if err != nil {
+			return err
+		}
+
+		files, err := workflows.Fetch(ctx, client, owner, name, "")
+		if err != nil {
+			return err
+		}
+
+		// By workflow path, then job display name.
+		configured := map[string]map[string]*jobTimeout{}
+		for _, w := range files {
+			configured[w.Path] = map[string]*jobTimeout{}
+			for _, id := range w.SortedJobs() {
+				job := w.Jobs[id]
+				timeout, ok := job.Timeout()
+				if !ok || job.Uses != "" {
+					continue
+				}
+
+				configured[w.Path][job.DisplayName(id)] = &jobTimeout{
+					Repository: reponame,
+					Workflow:   w.DisplayName(),
+					Job:        job.DisplayName(id),
+					Timeout:    timeout,
+					Default:    job.TimeoutMinutes.Kind == 0,
+				}
+			}
+		}
+
+		paths, err := fetchWorkflowPaths(ctx, client, reponame)
+		if err != nil {
+			return err
+		}
+
+		runs, err := fetchRuns(ctx, client, reponame, github.ListWorkflowRunsOptions{Status: "completed"}, *runCount)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range runs {
+			byName := configured[paths[w.GetWorkflowID()]]
+			if byName == nil {
+				continue
+			}
+
+			jobs, _, err := fetchJobs(ctx, client, w, *maxJobs)
+			if err != nil {
+				return err
+			}
+
+			for _, job := range jobs {
+				if job.StartedAt == nil || job.CompletedAt == nil {
+					continue
+				}
+
+				jobName := job.GetName()
+				if base, _, ok := splitMatrixJob(jobName); ok && byName[jobName] == nil {
+					jobName = base
+				}
+
+				t := byName[jobName]
+				if t == nil {
+					continue
+				}
+
+				d := job.CompletedAt.Sub(job.StartedAt.Time)
+				t.Durations = append(t.Durations, d)
+				if d >= time.Duration(t.Timeout)*time.Minute-*timeoutsMargin && job.GetConclusion() != "success" {
+					t.TimedOut++
+					t.LostMin += jobMinutes(job)
+				}
+			}
+		}
+
+		for _, byName := range configured {
+			for _, t := range byName {
+				if len(t.Durations) == 0 || (t.TimedOut == 0 && !*timeoutsAll) {
+					continue
+				}
+
+				sort.Slice(t.Durations, func(i, j int) bool { return t.Durations[i] < t.Durations[j] })
+				res = append(res, t)
+			}
+		}
+
+		log.Printf("%s: checked the timeouts of %d workflows", reponame, len(files))
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].LostMin != res[j].LostMin {
+			return res[i].LostMin > res[j].LostMin
+		}
+		return fmt.Sprint(res[i].Repository, res[i].Workflow, res[i].Job) < fmt.Sprint(res[j].Repository, res[j].Workflow, res[j].Job)
+	})
+
+	return timeoutsMarkdown.Execute(os.Stdout, res)
+}
+
+var timeoutsMarkdown = template.Must(template.New("timeouts").Funcs(digestFuncs).Funcs(template.FuncMap{
+	"round": func(d time.Duration) time.Duration { return d.Round(time.Second) },
+}).Parse(`# Job timeouts
+
+| Repository | Workflow | Job | timeout-minutes | Runs | Median | p95 | Max | Timed out | Minutes lost | Suggested timeout |
+|---|---|---|---:|---:|---:|---:|---:|---:|---:|---:|
+{{range .}}| {{.Repository}} | {{.Workflow}} | {{.Job}} | {{.Timeout}}{{if .Default}} (default){{end}} | {{len .Durations}} | {{round .Median}} | {{round .P95}} | {{round .Max}} | {{.TimedOut}} | {{minutes .LostMin}} | {{with .Suggested}}{{.}}{{end}} |
+{{end}}`))
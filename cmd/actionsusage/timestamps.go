@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/google/go-github/v58/github"
+)
+
+var timestampPolicy = flag.String("timestamp_policy", "skip", "What to do with completed jobs whose timestamps are missing or that end before they start: "+
+	"skip them, clamp them (to no duration, starting when created if the start is missing), or estimate them from their steps, clamping if they have none.")
+
+// checkTimestamps applies -timestamp_policy to a job, returning whether its
+// timestamps were missing or inconsistent, and whether it should be counted.
+// Jobs that haven't completed are never counted.
+func checkTimestamps(job *github.WorkflowJob) (bool, bool) {
+	if job.GetStatus() != "completed" {
+		return false, job.StartedAt != nil && job.CompletedAt != nil
+	}
+
+	if job.StartedAt != nil && job.CompletedAt != nil && !job.CompletedAt.Before(job.StartedAt.Time) {
+		return false, true
+	}
+
+	switch *timestampPolicy {
+	case "estimate":
+		if start, end, ok := stepSpan(job.Steps); ok {
+			job.StartedAt, job.CompletedAt = start, end
+			return true, true
+		}
+		fallthrough
+
+	case "clamp":
+		if job.StartedAt == nil {
+			job.StartedAt = job.CreatedAt
+		}
+		if job.StartedAt == nil {
+			return true, false
+		}
+
+		if job.CompletedAt == nil || job.CompletedAt.Before(job.StartedAt.Time) {
+			job.CompletedAt = job.StartedAt
+		}
+		return true, true
+
+	default:
+		return true, false
+	}
+}
+
+// stepSpan returns when the first of a job's steps started and the last one
+// completed, if they are consistent.
+func stepSpan(steps []*github.TaskStep) (*github.Timestamp, *github.Timestamp, bool) {
+	var start, end *github.Timestamp
+	for _, s := range steps {
+		if s.StartedAt != nil && (start == nil || s.StartedAt.Before(start.Time)) {
+			start = s.StartedAt
+		}
+		if s.CompletedAt != nil && (end == nil || s.CompletedAt.After(end.Time)) {
+			end = s.CompletedAt
+		}
+	}
+
+	return start, end, start != nil && end != nil && !end.Before(start.Time)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+)
+
+var (
+	trendFlags      = flag.NewFlagSet("trend", flag.ExitOnError)
+	trendWindows    = trendFlags.String("windows", "", "Reporting windows as first..last days (YYYY-MM-DD, UTC), separated by commas. Defaults to the last -last windows of -window_days.")
+	trendLast       = trendFlags.Int("last", 12, "Number of consecutive windows to report, ending yesterday, when -windows isn't set.")
+	trendWindowDays = trendFlags.Int("window_days", 7, "Length in days of each of the -last windows.")
+)
+
+// trendWindow is the usage within a reporting window.
+type trendWindow struct {
+	Start, End time.Time // Inclusive days.
+	Runs       int
+	FailedRuns int
+	Jobs       int
+	Minutes    float64
+	Cost       float64
+	Waste      wasteSummary
+	ByRepo     map[string]float64
+}
+
+// runCache deduplicates the API calls of overlapping windows: each run is
+// listed, and has its jobs fetched, once.
+type runCache struct {
+	client *github.Client
+	runs   map[int64]*github.WorkflowRun
+	repos  map[int64]string // As given in -repos.
+	jobs   map[int64][]jobRecord
+}
+
+func (c *runCache) fetch(ctx context.Context, reponame string, w *trendWindow) ([]*github.WorkflowRun, error) {
+	runs, err := fetchRuns(ctx, c.client, reponame, github.ListWorkflowRunsOptions{
+		Created: w.Start.Format(dateLayout) + ".." + w.End.Format(dateLayout),
+	}, *runCount)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, run := range runs {
+		if cached, ok := c.runs[run.GetID()]; ok {
+			runs[k] = cached
+		} else {
+			c.runs[run.GetID()] = run
+			c.repos[run.GetID()] = reponame
+		}
+	}
+
+	return runs, nil
+}
+
+func (c *runCache) records(ctx context.Context, w *github.WorkflowRun) ([]jobRecord, error) {
+	if records, ok := c.jobs[w.GetID()]; ok {
+		return records, nil
+	}
+
+	jobs, _, err := fetchJobs(ctx, c.client, w, *maxJobs)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []jobRecord
+	for _, job := range jobs {
+		if job.StartedAt != nil && job.CompletedAt != nil {
+			records = append(records, newJobRecord(c.repos[w.GetID()], w, job))
+		}
+	}
+	roundRun(records)
+
+	c.jobs[w.GetID()] = records
+	return records, nil
+}
+
+func parseTrendWindows() ([]*trendWindow, error) {
+	var windows []*trendWindow
+	if *trendWindows == "" {
+		end := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+		for k := 0; k < *trendLast; k++ {
+			windows = append(windows, &trendWindow{Start: end.AddDate(0, 0, 1-*trendWindowDays), End: end})
+			end = end.AddDate(0, 0, -*trendWindowDays)
+		}
+	} else {
+		for _, spec := range strings.Split(*trendWindows, ",") {
+			first, last, ok := strings.Cut(spec, "..")
+			if !ok {
+				return nil, fmt.Errorf("bad window %q: want first..last", spec)
+			}
+
+			start, err := time.Parse(dateLayout, first)
+			if err != nil {
+				return nil, fmt.Errorf("bad window %q: %w", spec, err)
+			}
+
+			end, err := time.Parse(dateLayout, last)
+			if err != nil {
+				return nil, fmt.Errorf("bad window %q: %w", spec, err)
+			}
+
+			windows = append(windows, &trendWindow{Start: start, End: end})
+		}
+	}
+
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows, nil
+}
+
+func runTrend(ctx context.Context) error {
+	windows, err := parseTrendWindows()
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	repoList, err := targetRepos(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	cache := &runCache{client: client, runs: map[int64]*github.WorkflowRun{}, repos: map[int64]string{}, jobs: map[int64][]jobRecord{}}
+
+	byWindow := make([][]*github.WorkflowRun, len(windows))
+	for k, w := range windows {
+		for _, reponame := range repoList {
+			runs, err := cache.fetch(ctx, reponame, w)
+			if err != nil {
+				return err
+			}
+			byWindow[k] = append(byWindow[k], runs...)
+		}
+	}
+
+	var all []*github.WorkflowRun
+	for _, w := range cache.runs {
+		all = append(all, w)
+	}
+	superseded := supersededRuns(all)
+
+	for k, w := range windows {
+		w.ByRepo = map[string]float64{}
+
+		var records []jobRecord
+		for _, run := range byWindow[k] {
+			reponame := cache.repos[run.GetID()]
+			runRecords, err := cache.records(ctx, run)
+			if err != nil {
+				return err
+			}
+
+			w.Runs++
+			if run.GetConclusion() == "failure" {
+				w.FailedRuns++
+			}
+
+			for _, r := range runRecords {
+				r.Superseded = superseded[run.GetID()]
+				records = append(records, r)
+
+				w.Jobs++
+				w.Minutes += r.Minutes
+				w.Cost += jobCost(r)
+				w.ByRepo[reponame] += r.Minutes
+			}
+		}
+
+		w.Waste = summarizeWaste(records)
+		log.Printf("%s..%s: %d runs, %s minutes", w.Start.Format(dateLayout), w.End.Format(dateLayout), w.Runs, formatMinutes(w.Minutes))
+	}
+
+	log.Printf("trend: fetched %d distinct runs for %d windows", len(cache.runs), len(windows))
+
+	return trendMarkdown.Execute(os.Stdout, struct {
+		Repos   []string
+		Windows []*trendWindow
+	}{repoList, windows})
+}
+
+var trendMarkdown = template.Must(template.New("trend").Funcs(digestFuncs).Parse(`# CI usage trend
+
+| Window | Runs | Failed runs | Jobs | Minutes | Cost | Cancelled minutes | Superseded minutes |
+|---|---:|---:|---:|---:|---:|---:|---:|
+{{range .Windows}}| {{date .Start}} – {{date .End}} | {{.Runs}} | {{.FailedRuns}} | {{.Jobs}} | {{minutes .Minutes}} | ${{printf "%.2f" .Cost}} | {{minutes .Waste.CancelledMinutes}} | {{minutes .Waste.SupersededMinutes}} |
+{{end}}
+## Minutes by repository
+
+| Repository |{{range .Windows}} {{date .Start}} |{{end}}
+|---|{{range .Windows}}---:|{{end}}
+{{range $repo := .Repos}}| {{$repo}} |{{range $.Windows}} {{minutes (index .ByRepo $repo)}} |{{end}}
+{{end}}`))
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriteXLSX(t *testing.T) {
+	var b bytes.Buffer
+	err := writeXLSX(&b, []sheet{
+		{name: "Jobs & runs", rows: [][]any{{"repo", "minutes"}, {"acme/<app>", 12.5}, {"acme/lib", 3}}},
+		{name: "Empty"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(contents)
+	}
+
+	for _, tc := range []struct{ file, want string }{
+		{"[Content_Types].xml", `<Override PartName="/xl/worksheets/sheet2.xml"`},
+		{"_rels/.rels", `Target="xl/workbook.xml"`},
+		{"xl/workbook.xml", `<sheet name="Jobs &amp; runs" sheetId="1" r:id="rId1"/><sheet name="Empty" sheetId="2" r:id="rId2"/>`},
+		{"xl/_rels/workbook.xml.rels", `Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"`},
+		{"xl/worksheets/sheet1.xml", `<row r="2"><c r="A2" t="inlineStr"><is><t>acme/&lt;app&gt;</t></is></c><c r="B2"><v>12.5</v></c></row>`},
+		{"xl/worksheets/sheet1.xml", `<c r="B3"><v>3</v></c>`},
+		{"xl/worksheets/sheet2.xml", `<sheetData></sheetData>`},
+	} {
+		contents, ok := files[tc.file]
+		if !ok {
+			t.Errorf("missing %s", tc.file)
+		} else if !strings.Contains(contents, tc.want) {
+			t.Errorf("%s: missing %s in %s", tc.file, tc.want, contents)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for k, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(k); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", k, got, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package cli describes the subcommands and flags of the tools, for shell
+// completion and for wrapper scripts that introspect them.
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Flag describes a command-line flag.
+type Flag struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage"`
+	Default string `json:"default,omitempty"`
+	Bool    bool   `json:"bool,omitempty"` // Takes no value.
+}
+
+// Command describes a tool or one of its subcommands.
+type Command struct {
+	Name        string    `json:"name"`
+	Flags       []Flag    `json:"flags"` // Of a subcommand, only those it adds to the tool's.
+	Subcommands []Command `json:"subcommands,omitempty"`
+}
+
+// Describe describes a tool whose subcommands accept the top-level flags in
+// addition to their own.
+func Describe(name string, top *flag.FlagSet, subcommands map[string]*flag.FlagSet) Command {
+	c := Command{Name: name, Flags: describeFlags(top, nil)}
+
+	var names []string
+	for n := range subcommands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		c.Subcommands = append(c.Subcommands, Command{Name: n, Flags: describeFlags(subcommands[n], top)})
+	}
+
+	return c
+}
+
+// describeFlags describes the flags of fs, leaving out those it shares with
+// top.
+func describeFlags(fs, top *flag.FlagSet) []Flag {
+	res := []Flag{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if top != nil && top.Lookup(f.Name) != nil && top.Lookup(f.Name).Value == f.Value {
+			return
+		}
+
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		res = append(res, Flag{Name: f.Name, Usage: f.Usage, Default: f.DefValue, Bool: ok && b.IsBoolFlag()})
+	})
+
+	return res
+}
+
+// Run handles "completion <shell>" and -help-json, returning whether args
+// were either.
+func Run(w io.Writer, args []string, describe func() Command) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "-help-json", "--help-json":
+		return true, WriteJSON(w, describe())
+
+	case "completion":
+		if len(args) != 2 {
+			return true, fmt.Errorf("usage: %s completion bash|zsh|fish", describe().Name)
+		}
+		return true, WriteCompletion(w, args[1], describe())
+	}
+
+	return false, nil
+}
+
+// WriteJSON writes the description of a tool as indented JSON.
+func WriteJSON(w io.Writer, c Command) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// WriteCompletion writes a completion script for bash, zsh or fish.
+func WriteCompletion(w io.Writer, shell string, c Command) error {
+	switch shell {
+	case "bash":
+		return writeBash(w, c)
+	case "zsh":
+		return writeZsh(w, c)
+	case "fish":
+		return writeFish(w, c)
+	default:
+		return fmt.Errorf("unsupported shell %q: want bash, zsh or fish", shell)
+	}
+}
+
+func flagWords(flags ...[]Flag) string {
+	var words []string
+	for _, fs := range flags {
+		for _, f := range fs {
+			words = append(words, "-"+f.Name)
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+func subcommandWords(c Command) string {
+	var words []string
+	for _, s := range c.Subcommands {
+		words = append(words, s.Name)
+	}
+
+	return strings.Join(words, " ")
+}
+
+func funcName(c Command) string {
+	return "_" + strings.ReplaceAll(c.Name, "-", "_")
+}
+
+func writeBash(w io.Writer, c Command) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s() {\n\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\"\n", funcName(c))
+	fmt.Fprintf(&b, "\tif [ \"$COMP_CWORD\" -eq 1 ]; then\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\tfi\n",
+		strings.TrimSpace(subcommandWords(c)+" "+flagWords(c.Flags)))
+	fmt.Fprintf(&b, "\tcase \"${COMP_WORDS[1]}\" in\n")
+	for _, s := range c.Subcommands {
+		fmt.Fprintf(&b, "\t%s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", s.Name, flagWords(s.Flags, c.Flags))
+	}
+	fmt.Fprintf(&b, "\t*) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n\tesac\n}\n", flagWords(c.Flags))
+	fmt.Fprintf(&b, "complete -o default -F %s %s\n", funcName(c), c.Name)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeZsh(w io.Writer, c Command) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n\n%s() {\n", c.Name, funcName(c))
+	fmt.Fprintf(&b, "\tif (( CURRENT == 2 )); then\n\t\tcompadd -- %s\n\t\treturn\n\tfi\n", strings.TrimSpace(subcommandWords(c)+" "+flagWords(c.Flags)))
+	fmt.Fprintf(&b, "\tcase $words[2] in\n")
+	for _, s := range c.Subcommands {
+		fmt.Fprintf(&b, "\t%s) compadd -- %s ;;\n", s.Name, flagWords(s.Flags, c.Flags))
+	}
+	fmt.Fprintf(&b, "\t*) compadd -- %s ;;\n\tesac\n\t_files\n}\n\ncompdef %s %s\n", flagWords(c.Flags), funcName(c), c.Name)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFish(w io.Writer, c Command) error {
+	var b strings.Builder
+	if len(c.Subcommands) > 0 {
+		fmt.Fprintf(&b, "complete -c %s -f -n __fish_use_subcommand -a '%s'\n", c.Name, subcommandWords(c))
+	}
+
+	for _, f := range c.Flags {
+		fmt.Fprintf(&b, "complete -c %s -o %s%s -d %s\n", c.Name, f.Name, fishArg(f), fishQuote(summary(f.Usage)))
+	}
+
+	for _, s := range c.Subcommands {
+		for _, f := range s.Flags {
+			fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from %s' -o %s%s -d %s\n", c.Name, s.Name, f.Name, fishArg(f), fishQuote(summary(f.Usage)))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func fishArg(f Flag) string {
+	if f.Bool {
+		return ""
+	}
+	return " -r"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// summary returns the first sentence of a flag's usage.
+func summary(usage string) string {
+	s, _, _ := strings.Cut(usage, ". ")
+	return strings.TrimSuffix(s, ".")
+}
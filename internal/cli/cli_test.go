@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func testCommand() Command {
+	top := flag.NewFlagSet("tool", flag.ContinueOnError)
+	top.String("org", "", "Organization to act on. Defaults to all.")
+	top.Bool("dry_run", false, "Only print what would be done.")
+
+	sub := flag.NewFlagSet("rerun", flag.ContinueOnError)
+	sub.String("since", "-24h", "Only re-run recent runs.")
+	top.VisitAll(func(f *flag.Flag) { sub.Var(f.Value, f.Name, f.Usage) })
+
+	return Describe("my-tool", top, map[string]*flag.FlagSet{"rerun": sub})
+}
+
+func TestDescribe(t *testing.T) {
+	c := testCommand()
+
+	if len(c.Flags) != 2 || !c.Flags[0].Bool || c.Flags[1].Name != "org" {
+		t.Errorf("top-level flags: %+v", c.Flags)
+	}
+
+	if len(c.Subcommands) != 1 || len(c.Subcommands[0].Flags) != 1 || c.Subcommands[0].Flags[0] != (Flag{Name: "since", Usage: "Only re-run recent runs.", Default: "-24h"}) {
+		t.Errorf("subcommands leave out shared flags: %+v", c.Subcommands)
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	for _, tc := range []struct {
+		shell string
+		want  []string
+	}{
+		{"bash", []string{
+			`_my_tool() {`,
+			`COMPREPLY=($(compgen -W "rerun -dry_run -org" -- "$cur"))`,
+			`rerun) COMPREPLY=($(compgen -W "-since -dry_run -org" -- "$cur")) ;;`,
+			`complete -o default -F _my_tool my-tool`,
+		}},
+		{"zsh", []string{
+			`#compdef my-tool`,
+			`compadd -- rerun -dry_run -org`,
+			`rerun) compadd -- -since -dry_run -org ;;`,
+			`compdef _my_tool my-tool`,
+		}},
+		{"fish", []string{
+			`complete -c my-tool -f -n __fish_use_subcommand -a 'rerun'`,
+			`complete -c my-tool -o dry_run -d 'Only print what would be done'`,
+			`complete -c my-tool -o org -r -d 'Organization to act on'`,
+			`complete -c my-tool -n '__fish_seen_subcommand_from rerun' -o since -r -d 'Only re-run recent runs'`,
+		}},
+	} {
+		var b strings.Builder
+		if err := WriteCompletion(&b, tc.shell, testCommand()); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range tc.want {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s: missing %s in:\n%s", tc.shell, want, b.String())
+			}
+		}
+	}
+
+	if err := WriteCompletion(&strings.Builder{}, "powershell", testCommand()); err == nil {
+		t.Error("want an error for an unsupported shell")
+	}
+}
+
+func TestFishQuote(t *testing.T) {
+	if got, want := fishQuote(`it's a \ path`), `'it\'s a \\ path'`; got != want {
+		t.Errorf("fishQuote = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+// Package ghcli finds the token of the gh CLI, so that those who already
+// authenticated it don't need to set GITHUB_TOKEN.
+package ghcli
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const host = "github.com"
+
+// Token returns GITHUB_TOKEN or GH_TOKEN if set, and otherwise the github.com
+// token of the gh CLI, the same way gh extensions find it: from `gh auth
+// token`, which also reads the system keyring, or from gh's hosts.yml if gh
+// isn't installed. It returns "" if there's no token.
+func Token() string {
+	for _, env := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if t := os.Getenv(env); t != "" {
+			return t
+		}
+	}
+
+	if out, err := exec.Command("gh", "auth", "token", "--hostname", host).Output(); err == nil {
+		return string(bytes.TrimSpace(out))
+	}
+
+	return hostsToken()
+}
+
+// hostsToken returns the token that gh stored in plain text in hosts.yml.
+func hostsToken() string {
+	contents, err := os.ReadFile(filepath.Join(configDir(), "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(contents, &hosts); err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(hosts[host].OAuthToken)
+}
+
+// configDir returns where gh keeps its configuration.
+func configDir() string {
+	if d := os.Getenv("GH_CONFIG_DIR"); d != "" {
+		return d
+	}
+
+	if d := os.Getenv("XDG_CONFIG_HOME"); d != "" {
+		return filepath.Join(d, "gh")
+	}
+
+	if d := os.Getenv("AppData"); d != "" {
+		return filepath.Join(d, "GitHub CLI")
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gh")
+}
@@ -0,0 +1,114 @@
+// Package ghclient creates the GitHub clients that the tools share, as
+// configured by the flags that it registers, and helps them target
+// repositories.
+package ghclient
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"namespacelabs.dev/githubtools/internal/githubapp"
+	"namespacelabs.dev/githubtools/internal/httpconfig"
+	"namespacelabs.dev/githubtools/internal/tokenpool"
+)
+
+var (
+	appID          int64
+	installationID int64
+	appKey         string
+	tokens         string
+	proxy          string
+	caBundle       string
+	tlsMinVersion  string
+)
+
+// RegisterFlags registers the flags that configure how clients authenticate
+// and connect to GitHub.
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.Int64Var(&appID, "app-id", 0, "Authenticate as this GitHub App instead of with GITHUB_TOKEN. Defaults to GITHUB_APP_ID.")
+	fs.Int64Var(&installationID, "installation-id", 0, "Installation of -app-id to act as. Defaults to GITHUB_APP_INSTALLATION_ID.")
+	fs.StringVar(&appKey, "app-key", "", "Private key file of -app-id. Defaults to GITHUB_APP_PRIVATE_KEY_PATH, or the key itself in GITHUB_APP_PRIVATE_KEY.")
+	fs.StringVar(&tokens, "tokens", "", "Comma-separated tokens to rotate between as each approaches its rate limit. Defaults to GITHUB_TOKEN, which may list several the same way.")
+	fs.StringVar(&proxy, "proxy", "", "URL of the proxy to reach GitHub through, e.g. http://proxy.corp:3128. HTTPS_PROXY is honored without it.")
+	fs.StringVar(&caBundle, "ca_bundle", os.Getenv("SSL_CERT_FILE"), "PEM file of root certificates to trust in addition to the system's, e.g. those of an intercepting proxy. Defaults to SSL_CERT_FILE.")
+	fs.StringVar(&tlsMinVersion, "tls_min_version", "", "Minimum TLS version to accept: 1.2 or 1.3.")
+}
+
+// ConfigureHTTP configures the HTTP transport with -proxy, -ca_bundle and
+// -tls_min_version. It must be called once flags are parsed, before New.
+func ConfigureHTTP() error {
+	return httpconfig.Configure(proxy, caBundle, tlsMinVersion)
+}
+
+// ErrNoToken is returned by New when there's no way to authenticate.
+var ErrNoToken = errors.New("GITHUB_TOKEN, gh auth login or a GitHub App (-app-id) is required")
+
+// New authenticates as the GitHub App of -app-id if set, refreshing its
+// installation tokens as they expire, and returns its transport too.
+// Otherwise it authenticates with GITHUB_TOKEN or the token of the gh CLI,
+// rotating between tokens if there are several.
+func New() (*github.Client, *githubapp.Transport, error) {
+	app, err := githubapp.Configure(appID, installationID, appKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if app != nil {
+		return github.NewClient(&http.Client{Transport: app}), app, nil
+	}
+
+	list := tokenpool.Tokens(tokens)
+	switch len(list) {
+	case 0:
+		return nil, nil, ErrNoToken
+	case 1:
+		return github.NewClient(nil).WithAuthToken(list[0]), nil, nil
+	default:
+		return github.NewClient(&http.Client{Transport: tokenpool.New(list)}), nil, nil
+	}
+}
+
+// SplitRepo splits owner/name.
+func SplitRepo(reponame string) (string, string, error) {
+	parts := strings.Split(reponame, "/")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("bad repository format: %q", reponame)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// ListOrgRepos returns all repositories of an organization.
+func ListOrgRepos(ctx context.Context, client *github.Client, org string) ([]*github.Repository, error) {
+	var all []*github.Repository
+	opts := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		res, r, err := client.Repositories.ListByOrg(ctx, org, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, res...)
+		if r.NextPage == 0 {
+			return all, nil
+		}
+
+		opts.Page = r.NextPage
+	}
+}
+
+func IsNotFound(err error) bool {
+	var e *github.ErrorResponse
+	return errors.As(err, &e) && e.Response.StatusCode == http.StatusNotFound
+}
+
+func IsForbidden(err error) bool {
+	var e *github.ErrorResponse
+	return errors.As(err, &e) && e.Response.StatusCode == http.StatusForbidden
+}
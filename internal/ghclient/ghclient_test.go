@@ -0,0 +1,20 @@
+package ghclient
+
+import "testing"
+
+func TestSplitRepo(t *testing.T) {
+	for _, tc := range []struct {
+		in          string
+		owner, name string
+		ok          bool
+	}{
+		{"namespacelabs/foundation", "namespacelabs", "foundation", true},
+		{"foundation", "", "", false},
+		{"namespacelabs/foundation/extra", "", "", false},
+	} {
+		owner, name, err := SplitRepo(tc.in)
+		if (err == nil) != tc.ok || owner != tc.owner || name != tc.name {
+			t.Errorf("SplitRepo(%q) = %q, %q, %v", tc.in, owner, name, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package githubapp authenticates as an installation of a GitHub App, for
+// organizations that don't allow long-lived personal access tokens.
+package githubapp
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"namespacelabs.dev/githubtools/internal/jwt"
+)
+
+// apiURL is where installation tokens are minted.
+const apiURL = "https://api.github.com"
+
+// Transport authenticates requests with installation tokens, minting a new
+// one shortly before the current one expires (they last an hour), so that
+// long collections don't fail midway.
+type Transport struct {
+	AppID          int64
+	InstallationID int64
+
+	key  *rsa.PrivateKey
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Configure returns a Transport for the given app, installation and private
+// key file, each of which defaults to GITHUB_APP_ID,
+// GITHUB_APP_INSTALLATION_ID and GITHUB_APP_PRIVATE_KEY_PATH respectively; the
+// key may also be passed in GITHUB_APP_PRIVATE_KEY. It returns nil if no app
+// is configured.
+func Configure(appID, installationID int64, keyPath string) (*Transport, error) {
+	var err error
+	if appID == 0 && os.Getenv("GITHUB_APP_ID") != "" {
+		if appID, err = strconv.ParseInt(os.Getenv("GITHUB_APP_ID"), 10, 64); err != nil {
+			return nil, fmt.Errorf("bad GITHUB_APP_ID: %w", err)
+		}
+	}
+
+	if appID == 0 {
+		return nil, nil
+	}
+
+	if installationID == 0 && os.Getenv("GITHUB_APP_INSTALLATION_ID") != "" {
+		if installationID, err = strconv.ParseInt(os.Getenv("GITHUB_APP_INSTALLATION_ID"), 10, 64); err != nil {
+			return nil, fmt.Errorf("bad GITHUB_APP_INSTALLATION_ID: %w", err)
+		}
+	}
+
+	if installationID == 0 {
+		return nil, errors.New("GitHub App authentication requires an installation ID")
+	}
+
+	if keyPath == "" {
+		keyPath = os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH")
+	}
+
+	var keyPEM []byte
+	switch {
+	case keyPath != "":
+		if keyPEM, err = os.ReadFile(keyPath); err != nil {
+			return nil, err
+		}
+	case os.Getenv("GITHUB_APP_PRIVATE_KEY") != "":
+		keyPEM = []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY"))
+	default:
+		return nil, errors.New("GitHub App authentication requires a private key")
+	}
+
+	key, err := jwt.ParseKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub App private key: %w", err)
+	}
+
+	return &Transport{AppID: appID, InstallationID: installationID, key: key, base: http.DefaultTransport}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// Token returns the current installation token, minting a new one if it
+// expires within five minutes.
+func (t *Transport) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Until(t.expires) > 5*time.Minute {
+		return t.token, nil
+	}
+
+	token, err := t.jwt()
+	if err != nil {
+		return "", err
+	}
+
+	mint, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/app/installations/%d/access_tokens", apiURL, t.InstallationID), nil)
+	if err != nil {
+		return "", err
+	}
+	mint.Header.Set("Authorization", "Bearer "+token)
+	mint.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := t.base.RoundTrip(mint)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("minting a token for installation %d of app %d: %s", t.InstallationID, t.AppID, resp.Status)
+	}
+
+	var res struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+
+	t.token, t.expires = res.Token, res.ExpiresAt
+	return t.token, nil
+}
+
+// jwt returns a token that authenticates as the app itself, for ten minutes
+// (backdated by a minute for clock skew), which is as long as GitHub allows.
+func (t *Transport) jwt() (string, error) {
+	now := time.Now()
+	return jwt.Sign(t.key, map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(t.AppID, 10),
+	})
+}
+
+// Login returns the login the app acts as, e.g. in the actor of the runs it
+// triggers.
+func (t *Transport) Login(ctx context.Context) (string, error) {
+	token, err := t.jwt()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"/app", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting app %d: %s", t.AppID, resp.Status)
+	}
+
+	var app struct {
+		Slug string `json:"slug"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&app); err != nil {
+		return "", err
+	}
+
+	return app.Slug + "[bot]", nil
+}
@@ -0,0 +1,65 @@
+// Package httpconfig adapts the HTTP transport that all API clients use to
+// corporate networks, e.g. with an intercepting proxy.
+package httpconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Configure replaces http.DefaultTransport with one that goes through proxy
+// (or HTTPS_PROXY and friends, if empty), also trusts the PEM certificates of
+// caFile, and requires at least TLS minVersion (1.2 or 1.3, if set). It must
+// be called before any client is created.
+func Configure(proxy, caFile, minVersion string) error {
+	if proxy == "" && caFile == "" && minVersion == "" {
+		return nil
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return fmt.Errorf("bad proxy %q: %w", proxy, err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		contents, err := os.ReadFile(caFile)
+		if err != nil {
+			return err
+		}
+
+		if !pool.AppendCertsFromPEM(contents) {
+			return fmt.Errorf("%s: no PEM certificates", caFile)
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+
+	switch minVersion {
+	case "":
+	case "1.2":
+		t.TLSClientConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		t.TLSClientConfig.MinVersion = tls.VersionTLS13
+	default:
+		return fmt.Errorf("unsupported TLS version %q: want 1.2 or 1.3", minVersion)
+	}
+
+	http.DefaultTransport = t
+	return nil
+}
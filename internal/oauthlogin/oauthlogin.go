@@ -0,0 +1,153 @@
+// Package oauthlogin logs in to GitHub with the OAuth device flow, for those
+// who'd rather not create a personal access token, and stores the token for
+// later runs.
+package oauthlogin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Login runs the device flow of the OAuth app with clientID: prompt is called
+// with the URL to open and the code to enter there, and Login returns the
+// token once the user authorized the app.
+func Login(ctx context.Context, clientID, scopes string, prompt func(uri, code string)) (string, error) {
+	var device struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURI string `json:"verification_uri"`
+		ExpiresIn       int    `json:"expires_in"`
+		Interval        int    `json:"interval"`
+	}
+	if err := post(ctx, "https://github.com/login/device/code", url.Values{"client_id": {clientID}, "scope": {scopes}}, &device); err != nil {
+		return "", err
+	}
+
+	prompt(device.VerificationURI, device.UserCode)
+
+	interval := time.Duration(device.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+
+		var res struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if err := post(ctx, "https://github.com/login/oauth/access_token", url.Values{
+			"client_id":   {clientID},
+			"device_code": {device.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &res); err != nil {
+			return "", err
+		}
+
+		switch res.Error {
+		case "":
+			return res.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return "", fmt.Errorf("login: %s: %s", res.Error, res.Description)
+		}
+	}
+
+	return "", errors.New("login: the code expired before it was entered")
+}
+
+func post(ctx context.Context, endpoint string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// tokenPath is where the token is stored, readable only by the user.
+func tokenPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "githubtools", "token"), nil
+}
+
+// Store saves the token for later runs, returning where. The token is stored
+// in plain text, not in the OS keyring, so it's only protected by the
+// permissions of the file (0600) and its directory (0700), which Store sets
+// even if they already exist with broader ones.
+func Store(token string) (string, error) {
+	p, err := tokenPath()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	// MkdirAll and OpenFile only apply permissions to what they create.
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := f.Chmod(0o600); err != nil {
+		return "", err
+	}
+
+	if _, err := f.WriteString(token + "\n"); err != nil {
+		return "", err
+	}
+
+	return p, f.Close()
+}
+
+// Stored returns the token saved by Store, or "" if there's none.
+func Stored() string {
+	p, err := tokenPath()
+	if err != nil {
+		return ""
+	}
+
+	contents, err := os.ReadFile(p)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(contents))
+}
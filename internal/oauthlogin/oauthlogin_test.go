@@ -0,0 +1,44 @@
+package oauthlogin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStoreTightensPermissions(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("relies on XDG_CONFIG_HOME")
+	}
+
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	dir := filepath.Join(home, "githubtools")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Store("gho_new")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]os.FileMode{dir: 0o700, p: 0o600} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode().Perm(); got != want {
+			t.Errorf("%s: mode %v, want %v", path, got, want)
+		}
+	}
+
+	if got := Stored(); got != "gho_new" {
+		t.Errorf("Stored() = %q, want gho_new", got)
+	}
+}
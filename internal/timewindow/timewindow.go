@@ -0,0 +1,42 @@
+// Package timewindow parses the times that bound which runs the tools
+// consider, so that -since and -until mean the same everywhere.
+package timewindow
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats describes what Parse accepts, for flag usage strings.
+const Formats = "RFC3339, YYYY-MM-DD (UTC), or relative to now, e.g. -30d or -12h"
+
+// Parse parses an RFC3339 time, a YYYY-MM-DD date (UTC), or a time relative
+// to now: a negative number of days (-30d) or a negative duration (-12h).
+// It returns the zero time if v is empty.
+func Parse(v string, now time.Time) (time.Time, error) {
+	switch {
+	case v == "":
+		return time.Time{}, nil
+
+	case strings.HasPrefix(v, "-") && strings.HasSuffix(v, "d"):
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.AddDate(0, 0, days), nil
+
+	case strings.HasPrefix(v, "-"):
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+
+	case len(v) == len(time.DateOnly):
+		return time.Parse(time.DateOnly, v)
+
+	default:
+		return time.Parse(time.RFC3339, v)
+	}
+}
@@ -0,0 +1,35 @@
+package timewindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		v    string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"-30d", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"-12h", time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"-90m", time.Date(2024, 3, 31, 10, 30, 0, 0, time.UTC)},
+		{"2024-02-29", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"2024-03-01T08:00:00+02:00", time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC)},
+	} {
+		got, err := Parse(tc.v, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.v, err)
+		} else if !got.Equal(tc.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tc.v, got, tc.want)
+		}
+	}
+
+	for _, v := range []string{"-xd", "-1y", "24h", "2024-13-01", "yesterday"} {
+		if _, err := Parse(v, now); err == nil {
+			t.Errorf("Parse(%q): want an error", v)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package tokenpool spreads API requests over several tokens, so that large
+// collections aren't bound by a single token's hourly rate limit.
+package tokenpool
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"namespacelabs.dev/githubtools/internal/ghcli"
+	"namespacelabs.dev/githubtools/internal/oauthlogin"
+)
+
+// Transport authenticates each request with the token that has the most
+// requests left, as tracked from the rate limit headers of its responses.
+type Transport struct {
+	base http.RoundTripper
+
+	mu     sync.Mutex
+	tokens []*token
+}
+
+type token struct {
+	value     string
+	remaining int // Or -1 until a response tells.
+	reset     time.Time
+}
+
+// Tokens returns the tokens in list, separated by commas, if set. Otherwise
+// it returns those of GITHUB_TOKEN, which may list several the same way, or
+// the gh CLI's, or the one stored by -login.
+func Tokens(list string) []string {
+	if list == "" {
+		list = ghcli.Token()
+	}
+	if list == "" {
+		list = oauthlogin.Stored()
+	}
+
+	var res []string
+	for _, t := range strings.Split(list, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			res = append(res, t)
+		}
+	}
+
+	return res
+}
+
+// New returns a Transport that rotates between tokens.
+func New(tokens []string) *Transport {
+	t := &Transport{base: http.DefaultTransport}
+	for _, v := range tokens {
+		t.tokens = append(t.tokens, &token{value: v, remaining: -1})
+	}
+
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok := t.pick()
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+tok.value)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.update(tok, resp)
+	return resp, nil
+}
+
+// pick returns the token with the most requests left. Tokens that weren't
+// used yet, or whose limit was reset since, count as having all of them.
+func (t *Transport) pick() *token {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var best *token
+	bestRemaining := 0
+	for _, tok := range t.tokens {
+		remaining := tok.remaining
+		if remaining < 0 || time.Now().After(tok.reset) {
+			remaining = 1 << 30
+		}
+
+		if best == nil || remaining > bestRemaining {
+			best, bestRemaining = tok, remaining
+		}
+	}
+
+	return best
+}
+
+// update records the core rate limit of a token from a response. Other
+// resources, e.g. search, have limits of their own that aren't tracked.
+func (t *Transport) update(tok *token, resp *http.Response) {
+	if r := resp.Header.Get("X-RateLimit-Resource"); r != "" && r != "core" {
+		return
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tok.remaining, tok.reset = remaining, time.Unix(reset, 0)
+}
@@ -0,0 +1,26 @@
+package tokenpool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	for _, tc := range []struct {
+		list string
+		want []string
+	}{
+		{"ghp_a", []string{"ghp_a"}},
+		{"ghp_a,ghp_b", []string{"ghp_a", "ghp_b"}},
+		{" ghp_a , ,ghp_b,", []string{"ghp_a", "ghp_b"}},
+	} {
+		if got := Tokens(tc.list); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Tokens(%q) = %q, want %q", tc.list, got, tc.want)
+		}
+	}
+
+	t.Setenv("GITHUB_TOKEN", "ghp_c,ghp_d")
+	if got, want := Tokens(""), []string{"ghp_c", "ghp_d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens from GITHUB_TOKEN = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,411 @@
+// Package workflows parses GitHub Actions workflow and action definitions.
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+	"gopkg.in/yaml.v3"
+	"namespacelabs.dev/githubtools/internal/ghclient"
+)
+
+// File is the subset of a workflow definition that the tools look at.
+type File struct {
+	Path        string          `yaml:"-"`
+	Name        string          `yaml:"name"`
+	On          yaml.Node       `yaml:"on"`
+	Permissions yaml.Node       `yaml:"permissions"`
+	Jobs        map[string]*Job `yaml:"jobs"`
+}
+
+// Job is a job of a workflow.
+type Job struct {
+	Name           string    `yaml:"name"`
+	Uses           string    `yaml:"uses"` // For jobs that call a reusable workflow.
+	RunsOn         yaml.Node `yaml:"runs-on"`
+	Environment    yaml.Node `yaml:"environment"`
+	Permissions    yaml.Node `yaml:"permissions"`
+	TimeoutMinutes yaml.Node `yaml:"timeout-minutes"`
+	Steps          []Step    `yaml:"steps"`
+	Line           int       `yaml:"-"` // Where the job's definition starts in its file.
+}
+
+func (j *Job) UnmarshalYAML(n *yaml.Node) error {
+	type plain Job
+	if err := n.Decode((*plain)(j)); err != nil {
+		return err
+	}
+
+	j.Line = n.Line
+	return nil
+}
+
+// DefaultTimeoutMinutes is how long GitHub lets a job run when it doesn't set
+// timeout-minutes.
+const DefaultTimeoutMinutes = 360
+
+// Timeout returns the job's timeout-minutes, or the default if unset. ok is
+// false when it's an expression that can't be evaluated statically.
+func (j *Job) Timeout() (minutes int, ok bool) {
+	if j.TimeoutMinutes.Kind == 0 {
+		return DefaultTimeoutMinutes, true
+	}
+
+	if err := j.TimeoutMinutes.Decode(&minutes); err != nil {
+		return 0, false
+	}
+
+	return minutes, true
+}
+
+// DisplayName returns the name runs list the job with, before any matrix
+// values are appended.
+func (j *Job) DisplayName(id string) string {
+	if j.Name != "" {
+		return j.Name
+	}
+
+	return id
+}
+
+// Step is a step of a job or composite action.
+type Step struct {
+	Name string            `yaml:"name"`
+	Uses string            `yaml:"uses"`
+	With map[string]string `yaml:"with"`
+	Line int               `yaml:"-"` // Where the step starts in its file.
+}
+
+func (s *Step) UnmarshalYAML(n *yaml.Node) error {
+	type plain Step
+	if err := n.Decode((*plain)(s)); err != nil {
+		return err
+	}
+
+	s.Line = n.Line
+	return nil
+}
+
+// TriggerFilter holds the branch, tag and path filters of a trigger event.
+type TriggerFilter struct {
+	Branches    []string `yaml:"branches"`
+	Tags        []string `yaml:"tags"`
+	Paths       []string `yaml:"paths"`
+	PathsIgnore []string `yaml:"paths-ignore"`
+}
+
+// HasPathFilter reports whether the trigger is filtered by changed paths.
+func (f TriggerFilter) HasPathFilter() bool {
+	return len(f.Paths) > 0 || len(f.PathsIgnore) > 0
+}
+
+// MatchesPaths reports whether changes to files would trigger the workflow
+// given its path filters, following GitHub's semantics: with paths, some file
+// must match (patterns starting with ! exclude files matched earlier); with
+// paths-ignore, some file must not match.
+func (f TriggerFilter) MatchesPaths(files []string) bool {
+	switch {
+	case len(f.Paths) > 0:
+		for _, file := range files {
+			if matchPatterns(f.Paths, file) {
+				return true
+			}
+		}
+		return false
+
+	case len(f.PathsIgnore) > 0:
+		for _, file := range files {
+			if !matchPatterns(f.PathsIgnore, file) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// matchPatterns returns whether the last pattern matching file is positive.
+func matchPatterns(patterns []string, file string) bool {
+	matched := false
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		if MatchGlob(strings.TrimPrefix(p, "!"), file) {
+			matched = !negated
+		}
+	}
+
+	return matched
+}
+
+// MatchGlob reports whether file matches a workflow filter pattern, where *
+// matches within a path segment, ** across segments, and ? a single character.
+func MatchGlob(pattern, file string) bool {
+	var re strings.Builder
+	re.WriteString("^")
+	for k := 0; k < len(pattern); k++ {
+		switch c := pattern[k]; c {
+		case '*':
+			if strings.HasPrefix(pattern[k:], "**/") {
+				re.WriteString("(?:.*/)?")
+				k += 2
+			} else if strings.HasPrefix(pattern[k:], "**") {
+				re.WriteString(".*")
+				k++
+			} else {
+				re.WriteString("[^/]*")
+			}
+
+		case '?':
+			re.WriteString("[^/]")
+
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+
+	ok, _ := regexp.MatchString(re.String(), file)
+	return ok
+}
+
+// Triggers returns the events that trigger the workflow, with their filters.
+func (w *File) Triggers() map[string]TriggerFilter {
+	res := map[string]TriggerFilter{}
+	switch w.On.Kind {
+	case yaml.ScalarNode:
+		res[w.On.Value] = TriggerFilter{}
+
+	case yaml.SequenceNode:
+		for _, n := range w.On.Content {
+			res[n.Value] = TriggerFilter{}
+		}
+
+	case yaml.MappingNode:
+		for k := 0; k+1 < len(w.On.Content); k += 2 {
+			var f TriggerFilter
+			_ = w.On.Content[k+1].Decode(&f)
+			res[w.On.Content[k].Value] = f
+		}
+	}
+
+	return res
+}
+
+// Permissions are the GITHUB_TOKEN scopes a permissions: block grants, by
+// scope. read-all and write-all grant "read" or "write" to the scope "*".
+type Permissions map[string]string
+
+// ParsePermissions returns the scopes granted by a permissions: block, or nil
+// if there's none.
+func ParsePermissions(n yaml.Node) Permissions {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		level, _ := strings.CutSuffix(n.Value, "-all")
+		return Permissions{"*": level}
+
+	case yaml.MappingNode:
+		p := Permissions{}
+		_ = n.Decode(&p)
+		return p
+	}
+
+	return nil
+}
+
+// RunnerLabels returns the runs-on labels of the job, including its runner
+// group if it names one. Expressions are returned as is.
+func (j *Job) RunnerLabels() []string {
+	switch j.RunsOn.Kind {
+	case yaml.ScalarNode:
+		return []string{j.RunsOn.Value}
+
+	case yaml.SequenceNode:
+		var labels []string
+		_ = j.RunsOn.Decode(&labels)
+		return labels
+
+	case yaml.MappingNode:
+		var runsOn struct {
+			Group  string    `yaml:"group"`
+			Labels yaml.Node `yaml:"labels"`
+		}
+		_ = j.RunsOn.Decode(&runsOn)
+
+		var labels []string
+		if runsOn.Group != "" {
+			labels = append(labels, runsOn.Group)
+		}
+		if runsOn.Labels.Kind == yaml.ScalarNode {
+			return append(labels, runsOn.Labels.Value)
+		}
+		var more []string
+		_ = runsOn.Labels.Decode(&more)
+		return append(labels, more...)
+	}
+
+	return nil
+}
+
+// EnvironmentName returns the name of the deployment environment of the job.
+func (j *Job) EnvironmentName() string {
+	switch j.Environment.Kind {
+	case yaml.ScalarNode:
+		return j.Environment.Value
+
+	case yaml.MappingNode:
+		var env struct {
+			Name string `yaml:"name"`
+		}
+		_ = j.Environment.Decode(&env)
+		return env.Name
+	}
+
+	return ""
+}
+
+// SortedJobs returns the job IDs of a workflow, sorted.
+func (w *File) SortedJobs() []string {
+	var ids []string
+	for id := range w.Jobs {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+// Fetch returns the parsed workflows of a repository at ref (or
+// the default branch if empty). Workflows that don't parse are logged and
+// skipped, rather than failing the whole repository.
+func Fetch(ctx context.Context, client *github.Client, owner, name, ref string) ([]*File, error) {
+	opts := &github.RepositoryContentGetOptions{Ref: ref}
+
+	_, dir, _, err := client.Repositories.GetContents(ctx, owner, name, ".github/workflows", opts)
+	if err != nil {
+		if ghclient.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []*File
+	for _, entry := range dir {
+		if ext := path.Ext(entry.GetName()); entry.GetType() != "file" || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		contents, err := fetchContents(ctx, client, owner, name, entry.GetPath(), ref)
+		if err != nil {
+			return nil, err
+		}
+
+		w, err := parseFile(owner, name, entry.GetPath(), contents)
+		if err != nil {
+			log.Printf("skipping workflow: %v", err)
+			continue
+		}
+
+		files = append(files, w)
+	}
+
+	return files, nil
+}
+
+func FetchFile(ctx context.Context, client *github.Client, owner, name, filePath, ref string) (*File, error) {
+	contents, err := fetchContents(ctx, client, owner, name, filePath, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFile(owner, name, filePath, contents)
+}
+
+func fetchContents(ctx context.Context, client *github.Client, owner, name, filePath, ref string) (string, error) {
+	file, _, _, err := client.Repositories.GetContents(ctx, owner, name, filePath, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return "", err
+	}
+
+	return file.GetContent()
+}
+
+func parseFile(owner, name, filePath, contents string) (*File, error) {
+	w := &File{Path: filePath}
+	if err := yaml.Unmarshal([]byte(contents), w); err != nil {
+		return nil, fmt.Errorf("%s/%s: %s: %w", owner, name, filePath, err)
+	}
+
+	return w, nil
+}
+
+// ActionName returns the action of a `uses:` reference, without its version.
+func ActionName(uses string) string {
+	name, _, _ := strings.Cut(uses, "@")
+	return strings.ToLower(name)
+}
+
+// Action is the subset of an action definition (action.yml) that the tools
+// look at.
+type Action struct {
+	Name string `yaml:"name"`
+	Runs struct {
+		Using string `yaml:"using"` // composite, docker, or a node runtime.
+		Steps []Step `yaml:"steps"`
+	} `yaml:"runs"`
+}
+
+// FetchAction returns the definition of the action in dir of a repository at
+// ref, or nil if there's none.
+func FetchAction(ctx context.Context, client *github.Client, owner, name, dir, ref string) (*Action, error) {
+	for _, file := range []string{"action.yml", "action.yaml"} {
+		f, _, _, err := client.Repositories.GetContents(ctx, owner, name, path.Join(dir, file), &github.RepositoryContentGetOptions{Ref: ref})
+		if ghclient.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		contents, err := f.GetContent()
+		if err != nil {
+			return nil, err
+		}
+
+		var a Action
+		if err := yaml.Unmarshal([]byte(contents), &a); err != nil {
+			return nil, fmt.Errorf("%s/%s: %s: %w", owner, name, path.Join(dir, file), err)
+		}
+
+		return &a, nil
+	}
+
+	return nil, nil
+}
+
+// StepName returns the name GitHub displays for a step in a job's log.
+func (s Step) StepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	if s.Uses != "" {
+		return "Run " + s.Uses
+	}
+
+	return ""
+}
+
+// DisplayName returns the name runs of the workflow are listed with.
+func (w *File) DisplayName() string {
+	if w.Name != "" {
+		return w.Name
+	}
+
+	return w.Path
+}